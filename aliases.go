@@ -0,0 +1,174 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Aliases maps alias names to the arguments they expand to, e.g. "co" to "peer connect --tag fav".
+type Aliases map[string][]string
+
+// DefaultAliasesPath is the conventional location of the aliases file of an application,
+// e.g. "~/.config/<app>/aliases" on Linux.
+func DefaultAliasesPath(app string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, app, "aliases"), nil
+}
+
+// LoadAliases reads the aliases file at the given path, see ReadAliases.
+// A missing file results in no aliases.
+func LoadAliases(path string) (Aliases, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Aliases{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAliases(f)
+}
+
+// ReadAliases reads aliases, one per line, like `co = peer connect --tag fav`.
+// Arguments are separated by whitespace, and may be quoted with single or double quotes.
+// Empty lines and lines starting with "#" are ignored.
+func ReadAliases(r io.Reader) (Aliases, error) {
+	out := make(Aliases)
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d: expected alias like `name = args...`", i)
+		}
+		args, err := splitArgs(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("line %d: alias %q is empty", i, name)
+		}
+		out[name] = args
+	}
+	return out, scanner.Err()
+}
+
+// splitArgs splits a line into arguments, separated by whitespace, with optional quotes.
+func splitArgs(line string) ([]string, error) {
+	var out []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				out = append(out, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inArg {
+		out = append(out, arg.String())
+	}
+	return out, nil
+}
+
+// Expand replaces the first argument with the arguments of the alias, if it is an alias,
+// and repeats this for the alias that the expansion starts with, if any.
+// An error is returned if aliases expand to each other in a cycle.
+func (a Aliases) Expand(args []string) ([]string, error) {
+	var seen []string
+	for len(args) > 0 {
+		expansion, ok := a[args[0]]
+		if !ok {
+			break
+		}
+		for _, s := range seen {
+			if s == args[0] {
+				return nil, fmt.Errorf("alias cycle: %s -> %s", strings.Join(seen, " -> "), args[0])
+			}
+		}
+		seen = append(seen, args[0])
+		args = append(append(make([]string, 0, len(expansion)+len(args)-1), expansion...), args[1:]...)
+	}
+	return args, nil
+}
+
+// List renders the aliases, sorted by name, in the format of the aliases file.
+func (a Aliases) List() string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out strings.Builder
+	for _, name := range names {
+		out.WriteString(name)
+		out.WriteString(" =")
+		for _, arg := range a[name] {
+			out.WriteString(" ")
+			out.WriteString(quoteArg(arg))
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// quoteArg quotes the argument for splitArgs, if it is empty or contains whitespace or quotes.
+// The argument is single-quoted, and each single quote in it is written as a double-quoted part
+// of the same argument, like in a shell: it's becomes 'it'"'"'s'.
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t'\"") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// expandAliases expands the first argument if it is an alias of the aliases file.
+// The aliases are written to out, and done is true, if the arguments are `alias list`.
+func (opts *MainOptions) expandAliases(args []string, out io.Writer) (expanded []string, done bool, err error) {
+	if opts.AliasesFile == "" {
+		return args, false, nil
+	}
+	aliases, err := LoadAliases(opts.AliasesFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load aliases: %w", err)
+	}
+	if len(args) == 2 && args[0] == "alias" && args[1] == "list" {
+		_, err := io.WriteString(out, aliases.List())
+		return nil, true, err
+	}
+	expanded, err = aliases.Expand(args)
+	return expanded, false, err
+}
@@ -0,0 +1,83 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	aliases, err := ReadAliases(strings.NewReader(`
+# my aliases
+co = peer connect --tag fav
+fav = co "--peer.tag=my fav" x
+loop1 = loop2
+loop2 = loop1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := aliases.Expand([]string{"fav", "--port", "9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, "|") != "peer|connect|--tag|fav|--peer.tag=my fav|x|--port|9000" {
+		t.Fatalf("unexpected expansion: %q", args)
+	}
+	if args, err := aliases.Expand([]string{"peer", "co"}); err != nil || strings.Join(args, " ") != "peer co" {
+		t.Fatalf("only the first argument is expanded, got: %q, %v", args, err)
+	}
+	if _, err := aliases.Expand([]string{"loop1"}); err == nil || err.Error() != "alias cycle: loop1 -> loop2 -> loop1" {
+		t.Fatalf("expected cycle error, got: %v", err)
+	}
+	listed, err := ReadAliases(strings.NewReader(aliases.List()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(listed["fav"], "|") != "co|--peer.tag=my fav|x" || len(listed) != 4 {
+		t.Fatalf("expected list to round-trip, got: %s", aliases.List())
+	}
+	for _, bad := range []string{"no equals", "a b = c", "x =", `x = "open`} {
+		if _, err := ReadAliases(strings.NewReader(bad)); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestAliasesListQuotes(t *testing.T) {
+	args := []string{"plain", "", "it's", `say "hi"`, `a'b"c d`, `'"`, "tab\there"}
+	aliases := Aliases{"q": args}
+	if out := aliases.List(); out != `q = plain '' 'it'"'"'s' 'say "hi"' 'a'"'"'b"c d' ''"'"'"' 'tab	here'`+"\n" {
+		t.Fatalf("unexpected list: %s", out)
+	}
+	listed, err := ReadAliases(strings.NewReader(aliases.List()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(listed["q"], "|") != strings.Join(args, "|") || len(listed["q"]) != len(args) {
+		t.Fatalf("expected list to round-trip, got: %q", listed["q"])
+	}
+}
+
+func TestMainAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases")
+	if err := os.WriteFile(path, []byte("co = peer connect\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &MainOptions{AliasesFile: path}
+	var out strings.Builder
+	args, done, err := opts.expandAliases([]string{"co", "--port=1"}, &out)
+	if err != nil || done || strings.Join(args, " ") != "peer connect --port=1" {
+		t.Fatalf("unexpected expansion: %q, %v, %v", args, done, err)
+	}
+	if _, done, err := opts.expandAliases([]string{"alias", "list"}, &out); err != nil || !done || out.String() != "co = peer connect\n" {
+		t.Fatalf("unexpected alias list: %q, %v, %v", out.String(), done, err)
+	}
+	opts.AliasesFile = filepath.Join(t.TempDir(), "missing")
+	if args, _, err := opts.expandAliases([]string{"co"}, &out); err != nil || args[0] != "co" {
+		t.Fatalf("expected missing file to have no aliases, got: %q, %v", args, err)
+	}
+}
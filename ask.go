@@ -2,15 +2,19 @@ package ask
 
 import (
 	"context"
+	"encoding"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net"
 	"os"
-	"os/signal"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unsafe"
@@ -20,6 +24,28 @@ var HelpErr = errors.New("ask: help asked with flag")
 
 var UnrecognizedErr = errors.New("command was not recognized")
 
+// UsageErr wraps an error caused by invalid usage of a command,
+// such as an unknown flag, a bad flag value or a missing argument.
+// Errors returned by a running command are not wrapped,
+// so the caller can decide to only print usage information for usage errors.
+type UsageErr struct {
+	Err error
+}
+
+func (e *UsageErr) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageErr) Unwrap() error {
+	return e.Err
+}
+
+// IsUsageErr checks if the error, or any error it wraps, is a UsageErr.
+func IsUsageErr(err error) bool {
+	var usageErr *UsageErr
+	return errors.As(err, &usageErr)
+}
+
 // TypedValue is the interface to the dynamic value stored in a flag.
 // (The default value is represented as a string.)
 // Extension of flag.Value with Type information.
@@ -34,6 +60,32 @@ type ImplicitValue interface {
 	Implicit() string
 }
 
+// CanonicalValue is implemented by values that render differently from String to be parsed by Set, see Flag.Canonical.
+type CanonicalValue interface {
+	flag.Value
+	// Canonical renders the value in a syntax that Set accepts, or returns an error if it cannot be rendered that way.
+	Canonical() (string, error)
+}
+
+// SecretValue is implemented by values that hold secrets, like SecretListValue.
+// Flags with a SecretValue are secret, as if tagged with `secret`,
+// and their current value is rendered with Redacted where values are shown, e.g. in DumpConfig.
+type SecretValue interface {
+	flag.Value
+	// Redacted renders the value without the secrets.
+	Redacted() string
+}
+
+// CtxValue is implemented by values that need the context of the command to be set,
+// e.g. to resolve a name over the network, or to read from a keystore.
+// During Execute, SetCtx is called with the context of the execution instead of Set.
+// Set is still used to apply defaults. SetFromMap sets the value with a background context.
+type CtxValue interface {
+	flag.Value
+	// SetCtx parses and sets the value, with the context of the command.
+	SetCtx(ctx context.Context, value string) error
+}
+
 type Command interface {
 	// Run the command, with context and remaining unrecognized args
 	Run(ctx context.Context, args ...string) error
@@ -41,6 +93,29 @@ type Command interface {
 
 var commandType = reflect.TypeOf((*Command)(nil)).Elem()
 
+// ArgSpec may be implemented by a Command to declare how many remaining arguments it accepts in Run.
+// Execute checks the count before running the command, so the command does not have to validate len(args).
+type ArgSpec interface {
+	// ArgSpec returns the minimum and maximum number of remaining arguments.
+	// A negative max means there is no upper bound.
+	ArgSpec() (min int, max int)
+}
+
+// Epilogue can be implemented by a command to add text at the end of its usage, e.g. examples or references.
+// The text may contain simple markdown, see RenderHelp.
+type Epilogue interface {
+	Epilogue() string
+}
+
+var epilogueType = reflect.TypeOf((*Epilogue)(nil)).Elem()
+
+// RawArgs is a marker interface for a Command that receives all remaining arguments unparsed in Run,
+// e.g. a wrapper command like `exec -- cmd --flag`: flags, including `--help`, are not interpreted at its level.
+// Declared positional arguments are still bound, before the remaining arguments are passed to Run.
+type RawArgs interface {
+	RawArgs()
+}
+
 type CommandRoute interface {
 	// Cmd gets a sub-command, which can be a Command or CommandRoute
 	// The command that is returned will be loaded with `Load` before it runs or its subcommand is retrieved.
@@ -57,6 +132,39 @@ type CommandKnownRoutes interface {
 
 var commandRouteType = reflect.TypeOf((*CommandRoute)(nil)).Elem()
 
+// RoutePolicy decides what Execute does when the first argument matches a sub-command route,
+// but the command itself also accepts positional arguments.
+type RoutePolicy uint8
+
+const (
+	// PreferRoute runs the sub-command. Arguments that are not a route are passed to the command. This is the default.
+	PreferRoute RoutePolicy = iota
+	// PreferCommand passes the arguments to the command. Routes are only used if the command does not accept arguments.
+	PreferCommand
+	// RouteAmbiguityError returns a UsageErr if an argument matches a route.
+	// The argument can be passed to the command after the "--" terminator.
+	RouteAmbiguityError
+)
+
+func (p RoutePolicy) String() string {
+	switch p {
+	case PreferRoute:
+		return "prefer-route"
+	case PreferCommand:
+		return "prefer-command"
+	case RouteAmbiguityError:
+		return "error-on-ambiguity"
+	default:
+		return fmt.Sprintf("RoutePolicy(%d)", uint8(p))
+	}
+}
+
+// CommandRoutePolicy may be implemented by a command that is both a Command and CommandRoute,
+// to choose what happens when an argument matches a sub-command. The default is PreferRoute.
+type CommandRoutePolicy interface {
+	RoutePolicy() RoutePolicy
+}
+
 type Help interface {
 	// Help explains how a command or group of flags is used.
 	Help() string
@@ -74,6 +182,24 @@ type InitDefault interface {
 
 var initDefaultType = reflect.TypeOf((*InitDefault)(nil)).Elem()
 
+// ExtraFlags can be implemented by a command or group of flags to declare flags that are not struct fields,
+// e.g. flags bridged from another CLI library. The flags are added after the flags of the struct fields.
+type ExtraFlags interface {
+	ExtraFlags() []*Flag
+}
+
+var extraFlagsType = reflect.TypeOf((*ExtraFlags)(nil)).Elem()
+
+// ArgParser can be implemented by a command to fully customize how its arguments are tokenized,
+// e.g. to support a legacy syntax. The returned flag values, by flag path, are bound like regular flags,
+// and the remaining arguments are handled as positional arguments.
+// A HelpErr can be returned to show the usage of the command.
+type ArgParser interface {
+	ParseArgs(args []string) (flags map[string]string, remaining []string, err error)
+}
+
+var argParserType = reflect.TypeOf((*ArgParser)(nil)).Elem()
+
 type Flag struct {
 	Value flag.Value
 	Name  string
@@ -86,6 +212,149 @@ type Flag struct {
 	// Reason for deprecation. Empty if not deprecated.
 	Deprecated string
 	Hidden     bool
+	// Secret flags never have their value included in error messages, and their default is redacted in usage.
+	Secret bool
+	// ReplacedBy is the flag that replaces this deprecated positional arg, in the same group.
+	// The arg shares its value with the replacement. Nil if not replaced.
+	ReplacedBy *Flag
+	// Transforms change the raw value before it is set, in order.
+	Transforms []Transform
+	// HideDefault omits the default value from usage information.
+	HideDefault bool
+	// Requires lists the names of the flags that must be set if this flag is set, relative to the group of this flag.
+	Requires []string
+	// Conflicts lists the names of the flags that must not be set if this flag is set, relative to the group of this flag.
+	Conflicts []string
+	// DefaultFrom is the name of the flag to take the value of if this flag is not set, relative to the group of this flag.
+	DefaultFrom string
+	// OnlyIf is the name of the boolean flag that must be true for this flag to be set, relative to the group of this flag.
+	OnlyIf string
+	// Early flags are set in a first pass over the arguments, before routing to a sub-command and before other sources,
+	// so their value can configure the rest of the execution, e.g. a config file path or the log level.
+	// Early flags are declared with `early:"true"`, and only recognized in the long format.
+	Early bool
+	// dest is the field the flag is bound to, and initial a copy of its value after loading, to restore with Reset.
+	// Invalid for flags that are not bound to a field.
+	dest, initial reflect.Value
+	// bind creates a flag value like Value for a scratch value of the field type, to parse or render without changing dest.
+	bind func(dest reflect.Value) (flag.Value, error)
+	// zeroDefault is the zero value of the field, as rendered by the flag, see isZeroDefault.
+	zeroDefault string
+	// Together lists the names of the sets of flags that this flag is in, shared by the whole command.
+	// The flags of a set must be set together, or not at all.
+	Together []string
+	// Unit of the value, e.g. "ms", for documentation only.
+	Unit string
+	// Example value, e.g. "1.2.3.4:9000", for documentation only.
+	Example string
+	// Link to more documentation about the flag.
+	Link string
+	// Env lists the environment variables to take the value from, in order, if the flag is not set by an argument.
+	Env []string
+	// DefaultOrigin tells where the default came from when the flag was loaded, see DefaultsReport.
+	// Empty for flags that are not struct fields.
+	DefaultOrigin DefaultOrigin
+	// Attached allows the value to be attached to the shorthand, like `-p9000`, when parsing with StrictShorthand.
+	Attached bool
+}
+
+// redacted renders the current value of a secret flag without the secrets.
+func (f *Flag) redacted() string {
+	if sv, ok := f.Value.(SecretValue); ok {
+		return sv.Redacted()
+	}
+	return RedactedValue
+}
+
+// usageDefault is the default to render in usage information, redacted for secret flags.
+func (f *Flag) usageDefault() string {
+	if f.Secret {
+		return RedactedValue
+	}
+	return f.Default
+}
+
+// isZeroDefault checks if the default is the zero value of the field the flag is bound to, like the standard flag package.
+// The zero value is rendered when loading, by a flag of the same field type bound to a zero value.
+// Only an empty default is zero for values that are not bound to a field.
+func (f *Flag) isZeroDefault() bool {
+	return f.Default == "" || (f.dest.IsValid() && f.Default == f.zeroDefault)
+}
+
+// isNegatable checks if the flag is a boolean flag, that can be set to false with the `--no-` prefix.
+func (f *Flag) isNegatable() bool {
+	v, ok := f.Value.(ImplicitValue)
+	return ok && !f.IsArg && v.Implicit() == "true"
+}
+
+// meta lists the documentation metadata of the flag, e.g. "unit: ms".
+func (f *Flag) meta() []string {
+	var out []string
+	if f.Unit != "" {
+		out = append(out, "unit: "+f.Unit)
+	}
+	if f.Example != "" {
+		out = append(out, "example: "+f.Example)
+	}
+	if f.Link != "" {
+		out = append(out, "see: "+f.Link)
+	}
+	if len(f.Env) > 0 {
+		out = append(out, "env: "+strings.Join(f.Env, ", "))
+	}
+	return out
+}
+
+// Canonical renders the current value in a syntax that Set accepts, e.g. to write the value back to a config file.
+// Values implement CanonicalValue if String does not render them that way, e.g. if String rounds,
+// and return an error if the value cannot be rendered that way, like an unset port.
+func (f *Flag) Canonical() (string, error) {
+	if c, ok := f.Value.(CanonicalValue); ok {
+		return c.Canonical()
+	}
+	return f.Value.String(), nil
+}
+
+// Set applies the transforms to the value, and then sets it.
+func (f *Flag) Set(value string) error {
+	value, err := f.transform(value)
+	if err != nil {
+		return err
+	}
+	return f.Value.Set(value)
+}
+
+// SetCtx is the same as Set, but sets a CtxValue with the context.
+func (f *Flag) SetCtx(ctx context.Context, value string) error {
+	value, err := f.transform(value)
+	if err != nil {
+		return err
+	}
+	if cv, ok := f.Value.(CtxValue); ok {
+		return cv.SetCtx(ctx, value)
+	}
+	return f.Value.Set(value)
+}
+
+// reset restores the value the flag had after loading, in place: the value of a pointer field is bound to its pointee.
+// Flags that are not bound to a field are set to their default.
+func (f *Flag) reset() error {
+	if f.dest.IsValid() {
+		restoreCopy(f.dest, f.initial)
+		return nil
+	}
+	return f.Value.Set(f.Default)
+}
+
+func (f *Flag) transform(value string) (string, error) {
+	for _, t := range f.Transforms {
+		v, err := t(value)
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	return value, nil
 }
 
 type PrefixedFlag struct {
@@ -108,83 +377,21 @@ type FlagGroup struct {
 	Entries []*FlagGroup
 	// flags in this group (does not include sub-groups)
 	Flags []*Flag
+	// Toggle is the boolean flag, in the parent group, that enables the flags of this group.
+	// Nil if the group is always enabled.
+	Toggle *Flag
+
+	// slice of structs, if the group is indexed: each element is a sub-group, named by its index.
+	indexed *indexedGroup
 }
 
-func (g *FlagGroup) Usage(prefix string, showHidden bool, out *strings.Builder) {
-	path := g.path(prefix)
-	if g.GroupName != "" {
-		out.WriteString("# ")
-		out.WriteString(path)
-		out.WriteString("\n")
-	}
-	if g.Help != nil {
-		out.WriteString(g.Help.Help())
-		out.WriteString("\n\n")
-	}
-	for _, f := range g.Flags {
-		if f.Hidden && !showHidden {
-			continue
-		}
-		out.WriteString("  ")
-		indent := 2
-		if f.Shorthand != 0 {
-			out.WriteString("-")
-			out.WriteByte(f.Shorthand)
-			out.WriteString(" ")
-			// e.g. "-c "
-			indent += 1 + 1 + 1
-		}
-		if f.Name != string(f.Shorthand) {
-			var prefix, suffix string
-			if f.IsArg {
-				if f.Required {
-					prefix = "<"
-					suffix = ">"
-				} else {
-					prefix = "["
-					suffix = "]"
-				}
-			} else {
-				prefix = "--"
-			}
-			out.WriteString(prefix)
-			if path != "" {
-				out.WriteString(path)
-				out.WriteString(".")
-				indent += len(path) + 1
-			}
-			out.WriteString(f.Name)
-			out.WriteString(suffix)
-			out.WriteString(" ")
-			indent += len(prefix) + len(f.Name) + len(suffix) + 1
-		}
-		if indent < 30 {
-			out.WriteString(strings.Repeat(" ", 30-indent))
-		}
-		out.WriteString(f.Help)
-		if f.Default != "" {
-			out.WriteString(" (default: ")
-			out.WriteString(f.Default)
-			out.WriteString(")")
-		}
-		if tv, ok := f.Value.(TypedValue); ok {
-			typ := tv.Type()
-			if typ != "" {
-				out.WriteString(" (type: ")
-				out.WriteString(typ)
-				out.WriteString(")")
-			}
-		}
-		if f.Deprecated != "" {
-			out.WriteString(" DEPRECATED: ")
-			out.WriteString(f.Deprecated)
-		}
-		out.WriteString("\n")
-	}
-	out.WriteString("\n")
-	for _, e := range g.Entries {
-		e.Usage(path, showHidden, out)
+// Enabled checks if the flags of the group are enabled, see Toggle.
+func (g *FlagGroup) Enabled() bool {
+	if g.Toggle == nil {
+		return true
 	}
+	v, _ := strconv.ParseBool(g.Toggle.Value.String())
+	return v
 }
 
 func (g *FlagGroup) path(prefix string) string {
@@ -199,12 +406,65 @@ func (g *FlagGroup) path(prefix string) string {
 	return path
 }
 
+// togglePath returns the path of the Toggle flag, which is in the parent group with the given path.
+func (g *FlagGroup) togglePath(prefix string) string {
+	if prefix == "" {
+		return g.Toggle.Name
+	}
+	return prefix + "." + g.Toggle.Name
+}
+
+// All lists the flags of the group and its sub-groups, in a stable order:
+// the flags of a group come first, in declaration order, followed by the sub-groups, depth-first, in declaration order.
+// Flags of inline groups are part of the group they are inlined in, in declaration order.
+// A deprecated positional arg follows the flag that replaces it, and created toggle flags come last in their group.
+// Elements of indexed groups are ordered by index. See SortFlags to order flags differently.
 func (g *FlagGroup) All(prefix string) []PrefixedFlag {
 	out := make([]PrefixedFlag, 0, len(g.Flags))
 	g.all(&out, prefix)
 	return out
 }
 
+// Walk calls fn for each of the flags of the group and its sub-groups, in the same order as All.
+// Walk stops at the first error, and returns it.
+func (g *FlagGroup) Walk(prefix string, fn func(pf PrefixedFlag) error) error {
+	for _, pf := range g.All(prefix) {
+		if err := fn(pf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlagOrder reports whether flag a sorts before flag b.
+type FlagOrder func(a, b PrefixedFlag) bool
+
+// OrderByPath orders flags by path.
+func OrderByPath(a, b PrefixedFlag) bool {
+	return a.Path < b.Path
+}
+
+// OrderArgsFirst orders required positional args first, then optional positional args, and then flags.
+func OrderArgsFirst(a, b PrefixedFlag) bool {
+	rank := func(pf PrefixedFlag) int {
+		if pf.IsArg && pf.Required {
+			return 0
+		}
+		if pf.IsArg {
+			return 1
+		}
+		return 2
+	}
+	return rank(a) < rank(b)
+}
+
+// SortFlags sorts the flags in the given order. The sort is stable: equal flags keep their order.
+func SortFlags(flags []PrefixedFlag, order FlagOrder) {
+	sort.SliceStable(flags, func(i, j int) bool {
+		return order(flags[i], flags[j])
+	})
+}
+
 func (g *FlagGroup) all(out *[]PrefixedFlag, prefix string) {
 	path := g.path(prefix)
 	for _, f := range g.Flags {
@@ -222,16 +482,115 @@ func (g *FlagGroup) all(out *[]PrefixedFlag, prefix string) {
 // ChangedMarkers tracks which flags are changed.
 type ChangedMarkers map[string][]*bool
 
+// NewChangedMarkers creates empty markers, e.g. for LoadGroup.
+func NewChangedMarkers() ChangedMarkers {
+	return make(ChangedMarkers)
+}
+
+// add registers a marker of the flag with the given path, if it is not registered already,
+// so loading the same value again does not grow the markers.
+func (c ChangedMarkers) add(path string, ptr *bool) {
+	for _, p := range c[path] {
+		if p == ptr {
+			return
+		}
+	}
+	c[path] = append(c[path], ptr)
+}
+
+// removeWithin removes the markers that point into the memory range [start, end), e.g. of values that moved.
+func (c ChangedMarkers) removeWithin(start, end uintptr) {
+	for path, ptrs := range c {
+		kept := ptrs[:0]
+		for _, p := range ptrs {
+			if addr := uintptr(unsafe.Pointer(p)); addr < start || addr >= end {
+				kept = append(kept, p)
+			}
+		}
+		// clear the tail, to not retain the removed pointers
+		for i := len(kept); i < len(ptrs); i++ {
+			ptrs[i] = nil
+		}
+		if len(kept) == 0 {
+			delete(c, path)
+		} else {
+			c[path] = kept
+		}
+	}
+}
+
 // An interface{} can be loaded as a command-description to execute it. See Load()
 type CommandDescription struct {
 	FlagGroup
 	// Define a field as 'MySettingChanged bool `changed:"my-setting"`' to e.g. track '--my-setting' being changed.
 	// The same flag may be tracked with multiple fields
 	ChangedMarkers ChangedMarkers
+	// Locked flags cannot be changed during Execute, see Lock. Keyed by flag path.
+	Locked map[string]struct{}
+	// Sources describes where the current value of each flag came from, see Source. Keyed by flag path.
+	Sources map[string]string
+	// Shadowed lists the sources of values that were overridden or ignored by precedence, by flag path, see Explain.
+	Shadowed map[string][]string
+	// Precedence ranks the kinds of sources, highest first, see DefaultPrecedence if nil.
+	// A value is ignored if the current value of the flag is from a source of higher precedence.
+	// Sub-commands inherit the precedence.
+	Precedence []string
+	// LoadOptions the command was loaded with, sub-commands are loaded with the same options.
+	// Nil for the default options.
+	LoadOptions *LoadOptions
+	// Route is the sequence of sub-command names that Execute routed through to reach this command.
+	// Empty for the root command.
+	Route []string
+	// Epilogue to add at the end of the usage, may be nil.
+	Epilogue Epilogue
+	// Remaining are the arguments that Execute passed to Run, after flags and positional args were parsed.
+	Remaining []string
+	// Unknown are the unrecognized long flags by name, if collected with ExecutionOptions.CollectUnknown.
+	Unknown map[string]string
+	// ArgParser tokenizes the arguments instead of the default flag syntax, may be nil.
+	// Sub-commands inherit the parser, unless they implement ArgParser themselves.
+	ArgParser ArgParser
 	// Command to run, may be nil if nothing has to run
 	Command
 	// Sub-command routing, can create commands (or other sub-commands) to access, may be nil if no sub-commands
 	CommandRoute
+
+	target interface{}
+	// module sets of the command, see ModuleSet
+	modules []*ModuleSet
+	// parent is the command that Execute routed through to reach this command, nil for the root command.
+	parent *CommandDescription
+}
+
+// LoadOptions configures how commands are loaded.
+type LoadOptions struct {
+	// TagName is the struct tag that declares flags, args and groups. Defaults to "ask".
+	TagName string
+	// LegacyTagNames are read, in order, for fields that are not tagged with TagName.
+	// This enables a gradual migration from the tags of another CLI library, e.g. `cli:"--name"`.
+	LegacyTagNames []string
+	// Kong enables compatibility with kong-style struct tags, for fields without an ask or legacy tag:
+	// every exported field is a flag, named after the field in kebab-case or by the `name` tag,
+	// with `short`, `arg`, `optional`, `enum`, `default`, `embed` and `kong:"-"` tags.
+	Kong bool
+	// Unexported allows flags, groups and changed markers to be declared with unexported struct fields,
+	// so CLI-configurable fields do not have to be part of the public API of a type.
+	// The fields are accessed through unsafe reflection.
+	Unexported bool
+	// Copy loads a deep copy of the target, so defaults and flags are never applied through pointers
+	// that the target shares with the application, e.g. state that a parent command passes to its sub-commands.
+	// The copy is returned by Target, sub-commands are copied too.
+	// Unexported fields of other types are opaque, and copied shallowly, e.g. the buffer of a bytes.Buffer.
+	Copy bool
+	// HelpCatalog to look up the `helpkey` tags with. Defaults to the catalog registered with RegisterHelpCatalog.
+	HelpCatalog HelpCatalog
+}
+
+func (opts *LoadOptions) tagName() string {
+	if opts == nil || opts.TagName == "" {
+		return "ask"
+	}
+	return opts.TagName
 }
 
 // Load takes a structure instance that defines a command through its type,
@@ -240,10 +599,27 @@ func Load(val interface{}) (*CommandDescription, error) {
 	return LoadReflect(reflect.ValueOf(val))
 }
 
+// Target returns the first value that was loaded, or the copy of it if the command was loaded with LoadOptions.Copy.
+func (descr *CommandDescription) Target() interface{} {
+	return descr.target
+}
+
 // LoadReflect is the same as Load, but directly using reflection to handle the value.
 func LoadReflect(val reflect.Value) (*CommandDescription, error) {
+	return LoadReflectWithOptions(val, nil)
+}
+
+// LoadWithOptions is the same as Load, with options to change how the command is loaded.
+// Nil options are the same as the default options.
+func LoadWithOptions(val interface{}, opts *LoadOptions) (*CommandDescription, error) {
+	return LoadReflectWithOptions(reflect.ValueOf(val), opts)
+}
+
+// LoadReflectWithOptions is the same as LoadWithOptions, but directly using reflection to handle the value.
+func LoadReflectWithOptions(val reflect.Value, opts *LoadOptions) (*CommandDescription, error) {
 	descr := &CommandDescription{
-		ChangedMarkers: make(map[string][]*bool),
+		ChangedMarkers: NewChangedMarkers(),
+		LoadOptions:    opts,
 	}
 	return descr, descr.LoadReflect(val)
 }
@@ -260,6 +636,12 @@ func (descr *CommandDescription) Load(val interface{}) error {
 
 // LoadReflect is the same as Load, but directly using reflection to handle the value.
 func (descr *CommandDescription) LoadReflect(val reflect.Value) error {
+	if descr.LoadOptions != nil && descr.LoadOptions.Copy {
+		val = descr.LoadOptions.copyTarget(val)
+	}
+	if descr.target == nil {
+		descr.target = val.Interface()
+	}
 	typ := val.Type()
 	if descr.Command == nil && typ.Implements(commandType) {
 		descr.Command = val.Interface().(Command)
@@ -267,63 +649,207 @@ func (descr *CommandDescription) LoadReflect(val reflect.Value) error {
 	if descr.CommandRoute == nil && typ.Implements(commandRouteType) {
 		descr.CommandRoute = val.Interface().(CommandRoute)
 	}
-	grp, err := LoadGroup("", val, descr.ChangedMarkers)
+	if descr.Epilogue == nil && typ.Implements(epilogueType) {
+		descr.Epilogue = val.Interface().(Epilogue)
+	}
+	if descr.ArgParser == nil && typ.Implements(argParserType) {
+		descr.ArgParser = val.Interface().(ArgParser)
+	}
+	l := &loader{changes: descr.ChangedMarkers, opts: descr.LoadOptions}
+	grp, err := l.loadGroup("", val, true)
 	if err != nil {
 		return err
 	}
 	descr.FlagGroup = *grp
+	descr.modules = append(descr.modules, l.modules...)
+	if err := descr.checkDuplicates(); err != nil {
+		return err
+	}
+	if err := descr.checkRelations(); err != nil {
+		return err
+	}
+	union, err := l.loadUnion(val)
+	if err != nil {
+		return err
+	}
+	if union != nil {
+		if typ.Implements(commandType) {
+			return fmt.Errorf("type %s cannot be both a Command and a union of commands", typ)
+		}
+		if descr.Command == nil {
+			descr.Command = union
+		}
+	}
+	return nil
+}
+
+// Lookup finds the flag (or positional arg) with the given path.
+func (descr *CommandDescription) Lookup(path string) (PrefixedFlag, bool) {
+	for _, pf := range descr.All("") {
+		if pf.Path == path {
+			return pf, true
+		}
+	}
+	return PrefixedFlag{}, false
+}
+
+// Lock marks the flags with the given paths as locked:
+// Execute returns an error if the arguments try to change a locked flag.
+// This enables an application to fix flags it set itself, e.g. a data directory.
+func (descr *CommandDescription) Lock(paths ...string) error {
+	for _, path := range paths {
+		if _, ok := descr.Lookup(path); !ok {
+			return fmt.Errorf("cannot lock unknown flag %q", path)
+		}
+	}
+	if descr.Locked == nil {
+		descr.Locked = make(map[string]struct{})
+	}
+	for _, path := range paths {
+		descr.Locked[path] = struct{}{}
+	}
 	return nil
 }
 
+// Pin sets the flag with the given path to the value, and locks it. See Lock.
+func (descr *CommandDescription) Pin(path string, value string) error {
+	pf, ok := descr.Lookup(path)
+	if !ok {
+		return fmt.Errorf("cannot pin unknown flag %q", path)
+	}
+	if err := pf.Set(value); err != nil {
+		return FlagValueErr(pf, value, err)
+	}
+	return descr.Lock(path)
+}
+
 func LoadGroup(name string, val reflect.Value, changes ChangedMarkers) (*FlagGroup, error) {
+	l := &loader{changes: changes}
+	return l.loadGroup(name, val, true)
+}
+
+// loader loads groups of flags, following the load options.
+type loader struct {
+	changes ChangedMarkers
+	opts    *LoadOptions
+	// copies of structs from before InitDefault, to tell which defaults it changed
+	preDefaults map[structKey]reflect.Value
+	// module sets that were loaded, to set up and close around the command
+	modules []*ModuleSet
+}
+
+type structKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// field returns the i-th field of the struct.
+// Unexported fields are made accessible if LoadOptions.Unexported is enabled and the struct is addressable.
+func (l *loader) field(val reflect.Value, f *reflect.StructField, i int) reflect.Value {
+	v := val.Field(i)
+	if f.IsExported() || l.opts == nil || !l.opts.Unexported || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// preDefault copies the struct before InitDefault runs, unless a parent group copied it before its InitDefault.
+func (l *loader) preDefault(key structKey, v reflect.Value) {
+	if l.preDefaults == nil {
+		l.preDefaults = make(map[structKey]reflect.Value)
+	}
+	if _, ok := l.preDefaults[key]; ok {
+		return
+	}
+	cp := reflect.New(v.Type()).Elem()
+	cp.Set(v)
+	l.preDefaults[key] = cp
+}
+
+// getAsk gets the flag declaration of a field, from the configured tag or the legacy tags.
+func (l *loader) getAsk(f *reflect.StructField) (v string, ok bool) {
+	if v, ok := f.Tag.Lookup(l.opts.tagName()); ok {
+		return v, true
+	}
+	if l.opts != nil {
+		for _, name := range l.opts.LegacyTagNames {
+			if v, ok := f.Tag.Lookup(name); ok {
+				return v, true
+			}
+		}
+		if l.opts.Kong {
+			return kongDecl(f)
+		}
+	}
+	return "", false
+}
+
+// loadGroup loads a group, and optionally initializes the defaults (see InitDefault) of the group.
+func (l *loader) loadGroup(name string, val reflect.Value, initDefaults bool) (*FlagGroup, error) {
 	typ := val.Type()
 	var grp FlagGroup
 	grp.GroupName = name
 	if typ.Implements(helpType) {
 		grp.Help = val.Interface().(Help)
 	}
-	if err := fillGroup(&grp, val, changes); err != nil {
+	if err := l.fillGroup(&grp, val, initDefaults); err != nil {
 		return nil, err
 	}
 	return &grp, nil
 }
 
-func fillGroup(grp *FlagGroup, val reflect.Value, changes ChangedMarkers) error {
+func (l *loader) fillGroup(grp *FlagGroup, val reflect.Value, initDefaults bool) error {
 	typ := val.Type()
 	if grp.Help == nil && typ.Implements(helpType) {
 		grp.Help = val.Interface().(Help)
 	}
-	if typ.Implements(initDefaultType) {
+	if initDefaults && typ.Implements(initDefaultType) {
+		if val.Kind() == reflect.Ptr && !val.IsNil() && val.Elem().Kind() == reflect.Struct {
+			l.preDefault(structKey{val.Pointer(), val.Elem().Type()}, val.Elem())
+		}
 		val.Interface().(InitDefault).Default()
 	}
+	if typ == moduleSetType {
+		return l.fillModules(grp, val.Interface().(*ModuleSet), initDefaults)
+	}
 	switch val.Kind() {
 	case reflect.Struct:
+		// sub-groups that are enabled by a toggle flag, resolved after all flags are loaded
+		toggles := make(map[*FlagGroup]string)
+		var pre reflect.Value
+		if val.CanAddr() {
+			pre = l.preDefaults[structKey{val.Addr().Pointer(), typ}]
+		}
 		fieldCount := val.NumField()
 		for i := 0; i < fieldCount; i++ {
 			f := typ.Field(i)
 			if changed, ok := getChanged(&f); ok {
-				v := val.Field(i)
+				v := l.field(val, &f, i)
 				if !v.CanAddr() {
 					return fmt.Errorf("cannot get address of changed flag boolean field '%s'", f.Name)
 				}
 				if ptr, ok := v.Addr().Interface().(*bool); ok {
-					changes[changed] = append(changes[changed], ptr)
+					l.changes.add(changed, ptr)
 				} else {
 					return fmt.Errorf("changed flag field '%s' is not a bool", f.Name)
 				}
 				continue
 			}
 
-			tag, ok := getAsk(&f)
+			tag, ok := l.getAsk(&f)
 			// skip ignored fields
 			if !ok || tag == "-" {
 				continue
 			}
-			v := val.Field(i)
+			v := l.field(val, &f, i)
+			// sub-groups compare their defaults with the copy from before the InitDefault of this group
+			if pre.IsValid() && v.Kind() == reflect.Struct && strings.HasPrefix(tag, ".") {
+				l.preDefault(structKey{v.Addr().Pointer(), v.Type()}, l.field(pre, &f, i))
+			}
 
 			// recurse into explicitly inline-squashed fields
 			if tag == "." {
-				if err := fillGroup(grp, v.Addr(), changes); err != nil {
+				if err := l.fillGroup(grp, v.Addr(), initDefaults); err != nil {
 					return fmt.Errorf("failed to load squashed flag group into group %q: %v", grp.GroupName, err)
 				}
 				continue
@@ -331,118 +857,175 @@ func fillGroup(grp *FlagGroup, val reflect.Value, changes ChangedMarkers) error
 
 			// recurse into sub-groups
 			if strings.HasPrefix(tag, ".") {
-				subGrp, err := LoadGroup(tag[1:], v.Addr(), changes)
+				var subGrp *FlagGroup
+				var err error
+				if isIndexedGroup(v.Type()) {
+					subGrp, err = l.loadIndexedGroup(tag[1:], v, initDefaults)
+				} else {
+					subGrp, err = l.loadGroup(tag[1:], v.Addr(), initDefaults)
+				}
 				if err != nil {
 					return err
 				}
-				if h, ok := f.Tag.Lookup("help"); ok {
+				if h, ok, err := l.getHelp(&f); err != nil {
+					return err
+				} else if ok {
 					subGrp.Help = InlineHelp(h)
 				}
+				if t, ok := f.Tag.Lookup("toggle"); ok {
+					if !strings.HasPrefix(t, "--") || len(t) < 3 {
+						return fmt.Errorf("field %q toggle must be a long flag, like --name", f.Name)
+					}
+					toggles[subGrp] = t[2:]
+				}
 				grp.Entries = append(grp.Entries, subGrp)
 				continue
 			}
 
 			// handle individual fields
-			fl, err := LoadField(typ.Field(i), v)
+			fl, err := l.loadField(typ.Field(i), v)
 			if err != nil {
 				return err
 			}
+			fl.DefaultOrigin = DefaultValue
+			if v.IsZero() {
+				fl.DefaultOrigin = DefaultZero
+			} else if pre.IsValid() {
+				if p := l.field(pre, &f, i); v.CanInterface() && p.CanInterface() && !reflect.DeepEqual(p.Interface(), v.Interface()) {
+					fl.DefaultOrigin = DefaultInit
+				}
+			}
+			if initDefaults {
+				if err := defaultTag(&f, v, fl); err != nil {
+					return err
+				}
+			}
+			if v.CanSet() {
+				fl.dest, fl.initial = v, deepCopy(v)
+				fl.bind = func(dest reflect.Value) (flag.Value, error) {
+					return fieldValue(&f, dest)
+				}
+				zero, err := fl.bind(reflect.New(v.Type()).Elem())
+				if err != nil {
+					return err
+				}
+				fl.zeroDefault = zero.String()
+			}
 			grp.Flags = append(grp.Flags, fl)
+
+			// deprecated positional form of the flag
+			if a, ok := f.Tag.Lookup("deprecated-arg"); ok {
+				argFl, err := loadDeprecatedArg(f.Name, a, fl)
+				if err != nil {
+					return err
+				}
+				grp.Flags = append(grp.Flags, argFl)
+			}
 			continue
 		}
+		// the flags declared by methods, of the struct itself or behind a pointer
+		if val.CanAddr() && val.Addr().Type().Implements(extraFlagsType) {
+			grp.Flags = append(grp.Flags, val.Addr().Interface().(ExtraFlags).ExtraFlags()...)
+		}
+		for _, sub := range grp.Entries {
+			if name, ok := toggles[sub]; ok {
+				toggle, err := toggleFlag(grp, sub, name)
+				if err != nil {
+					return err
+				}
+				sub.Toggle = toggle
+			}
+		}
 		return nil
 	case reflect.Ptr:
 		if val.IsNil() {
 			val.Set(reflect.New(val.Type().Elem()))
 		}
-		return fillGroup(grp, val.Elem(), changes)
+		return l.fillGroup(grp, val.Elem(), initDefaults)
 	default:
 		return fmt.Errorf("type %T, is not a valid group of flags", typ)
 	}
 }
 
-// Usage prints the help information and the usage of all flags.
-func (descr *CommandDescription) Usage(showHidden bool) string {
-	var out strings.Builder
-	out.WriteString("(command)")
-	all := descr.All("")
+type ExecutionOptions struct {
+	OnDeprecated func(fl PrefixedFlag) error
+	// RouteIgnoreCase matches sub-command routes case-insensitively.
+	// Only routes listed by CommandKnownRoutes are matched this way.
+	RouteIgnoreCase bool
+	// RoutePrefix matches sub-command routes by unique prefix, e.g. "con" for "connect".
+	// Only routes listed by CommandKnownRoutes are matched this way.
+	RoutePrefix bool
+	// ParseOptions changes the accepted flag syntax.
+	ParseOptions
+	// ParseOnly parses and binds the arguments, and checks the argument count, but does not run the command.
+	// Values are not resolved, see ResolvableValue.
+	ParseOnly bool
+	// LookupEnv looks up the environment variables of flags with an `env` tag. Defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// ResolveTimeout limits the time to resolve the flags that implement ResolvableValue, zero for no limit.
+	// See CommandDescription.Resolve.
+	ResolveTimeout time.Duration
+	// CollectUnknown collects unrecognized long flags into CommandDescription.Unknown, instead of returning an error,
+	// e.g. for a gateway command that forwards them to an embedded engine. The value of `--x.y=z` is "z",
+	// a flag without `=` is collected as "true": the next argument is never consumed as its value.
+	// Unrecognized shorthand flags are still an error.
+	CollectUnknown bool
+	// Close makes Execute clean up the command it ran, see CommandDescription.Close.
+	// A command that implements io.Closer is long-running: if Run succeeds, it is closed once the context is canceled,
+	// and Execute blocks until then. Other commands are closed as soon as Run returns.
+	// Errors of closing are joined with the error of Run.
+	Close bool
+	// Configure is called with the final command after routing, before the arguments are parsed,
+	// e.g. to apply a config file with SetFromMap, see UserConfig. Nil to disable.
+	Configure func(cmd *CommandDescription) error
+	// Record writes an ExecutionRecord of every execution, as a line of JSON, to replay later with Replay.
+	// Nil to disable.
+	Record io.Writer
+}
 
-	for _, a := range all {
-		if a.IsArg && a.Required {
-			out.WriteString(" <")
-			out.WriteString(a.Path)
-			out.WriteString(">")
-		}
+// resolveRoute maps the given route name to a known route, following the route matching options.
+// The name is returned as-is if the route does not declare known routes, or if none of them match.
+// An error is returned if the name matches multiple known routes.
+func (opts *ExecutionOptions) resolveRoute(route CommandRoute, name string) (string, error) {
+	if !opts.RouteIgnoreCase && !opts.RoutePrefix {
+		return name, nil
 	}
-	for _, a := range all {
-		if a.IsArg && !a.Required {
-			out.WriteString(" [")
-			out.WriteString(a.Path)
-			out.WriteString("]")
-		}
+	knownRoutes, ok := route.(CommandKnownRoutes)
+	if !ok {
+		return name, nil
 	}
-	flagCount := 0
-	for _, a := range all {
-		if !a.IsArg && (!a.Hidden || showHidden) {
-			flagCount += 1
+	routes := knownRoutes.Routes()
+	for _, r := range routes {
+		if r == name {
+			return r, nil
 		}
 	}
-	if flagCount > 0 {
-		out.WriteString(fmt.Sprintf(" # %d flags (see below)", flagCount))
-	}
-
-	out.WriteString("\n\n")
-
-	if len(all) > 0 {
-		descr.FlagGroup.Usage("", showHidden, &out)
-		out.WriteString("\n")
+	var candidates []string
+	if opts.RouteIgnoreCase {
+		for _, r := range routes {
+			if strings.EqualFold(r, name) {
+				candidates = append(candidates, r)
+			}
+		}
 	}
-
-	if descr.CommandRoute != nil {
-		knownRoutes, ok := descr.CommandRoute.(CommandKnownRoutes)
-		if ok {
-			out.WriteString("Sub commands:\n")
-			routes := knownRoutes.Routes()
-			maxRouteLen := 0
-			for _, r := range routes {
-				if len(r) > maxRouteLen {
-					maxRouteLen = len(r)
-				}
+	if len(candidates) == 0 && opts.RoutePrefix {
+		for _, r := range routes {
+			if len(r) < len(name) {
+				continue
 			}
-			for _, k := range routes {
-				out.WriteString("  ")
-				out.WriteString(k)
-				if len(k) < maxRouteLen {
-					out.WriteString(strings.Repeat(" ", maxRouteLen-len(k)))
-				}
-				out.WriteString("  ")
-				subCmd, err := descr.CommandRoute.Cmd(k)
-				if err != nil {
-					out.WriteString(err.Error())
-				} else if subCmd == nil {
-					out.WriteString("Command route not available")
-				} else {
-					subDescr, err := Load(subCmd)
-					if err != nil {
-						out.WriteString("[error] command is invalid\n")
-						out.WriteString(err.Error())
-					} else {
-						if subDescr.Help != nil {
-							out.WriteString(subDescr.Help.Help())
-						}
-						// no info in no help available but valid otherwise
-					}
-				}
-				out.WriteString("\n")
+			if r[:len(name)] == name || (opts.RouteIgnoreCase && strings.EqualFold(r[:len(name)], name)) {
+				candidates = append(candidates, r)
 			}
 		}
 	}
-
-	return out.String()
-}
-
-type ExecutionOptions struct {
-	OnDeprecated func(fl PrefixedFlag) error
+	switch len(candidates) {
+	case 0:
+		return name, nil
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous command %q, candidates: %s", name, strings.Join(candidates, ", "))
+	}
 }
 
 // Execute runs the command, with given context and arguments.
@@ -451,6 +1034,7 @@ type ExecutionOptions struct {
 //
 // A HelpErr is returned when help information was requested for the command (through `help`, `--help` or `-h`)
 // A UnrecognizedErr is returned when a sub-command was expected but not found.
+// A UsageErr is returned when the arguments are invalid, e.g. when a flag is unknown or a required argument is missing.
 //
 // To add inputs/outputs such as STDOUT to a command, add the readers/writers as field in the command struct definition,
 // and the command can pass them on to sub-commands. Similarly logging and other misc. data can be passed around.
@@ -458,86 +1042,177 @@ type ExecutionOptions struct {
 //
 // opts.OnDeprecated is called for each deprecated flag,
 // and command execution exits immediately if this callback returns an error.
+//
+// opts.RouteIgnoreCase and opts.RoutePrefix relax sub-command matching,
+// an error listing the candidates is returned if a route name is ambiguous.
+//
+// Early flags (see Flag.Early) of each command are set before it routes to a sub-command.
+//
+// If the command has an ArgParser, it tokenizes the arguments instead of the default flag syntax.
+// If the command is RawArgs, the arguments are not parsed as flags.
+// Values that implement ResolvableValue are resolved before the command runs.
 func (descr *CommandDescription) Execute(ctx context.Context, opts *ExecutionOptions, args ...string) (final *CommandDescription, err error) {
-	if len(args) > 0 && (args[0] == "--help" || args[0] == "-h" || args[0] == "help") {
-		return descr, HelpErr
+	if opts == nil || opts.Record == nil {
+		return descr.execute(ctx, opts, args)
 	}
-	if opts == nil {
+	start := time.Now()
+	final, err = descr.execute(ctx, opts, args)
+	if recErr := recordExecution(opts.Record, &opts.ParseOptions, start, descr, final, args, err); recErr != nil && err == nil {
+		err = recErr
+	}
+	return final, err
+}
+
+func (descr *CommandDescription) execute(ctx context.Context, opts *ExecutionOptions, args []string) (final *CommandDescription, err error) {
+	if opts == nil {
 		opts = &ExecutionOptions{}
 	}
+	_, raw := descr.Command.(RawArgs)
+	if !raw {
+		// early flags are set before routing, to configure the rest of the execution
+		if args, err = descr.parseEarly(ctx, opts, args); err != nil {
+			return descr, &UsageErr{err}
+		}
+	}
+	if !raw && len(args) > 0 && (args[0] == "--help" || args[0] == "-h" || args[0] == "help") {
+		return descr, HelpErr
+	}
 
-	if descr.CommandRoute != nil && len(args) > 0 {
-		sub, err := descr.CommandRoute.Cmd(args[0])
+	policy := descr.routePolicy()
+	if descr.CommandRoute != nil && len(args) > 0 && !(policy == PreferCommand && descr.acceptsArgs()) &&
+		!(policy == RouteAmbiguityError && args[0] == "--") {
+		name, err := opts.resolveRoute(descr.CommandRoute, args[0])
+		if err != nil {
+			return nil, &UsageErr{err}
+		}
+		sub, err := descr.CommandRoute.Cmd(name)
 		if err != nil {
 			return nil, err
 		}
+		if sub != nil && policy == RouteAmbiguityError && descr.acceptsArgs() {
+			return descr, &UsageErr{fmt.Errorf("%q is both a sub-command and an argument, use \"-- %s\" to pass it as argument",
+				args[0], args[0])}
+		}
 		if sub != nil {
-			subCmd, err := Load(sub)
+			subCmd, err := LoadWithOptions(sub, descr.LoadOptions)
 			if err != nil {
 				return nil, err
 			}
-			return subCmd.Execute(ctx, opts, args[1:]...)
+			if subCmd.ArgParser == nil {
+				subCmd.ArgParser = descr.ArgParser
+			}
+			subCmd.Precedence = descr.Precedence
+			subCmd.Route = append(append(make([]string, 0, len(descr.Route)+1), descr.Route...), name)
+			subCmd.parent = descr
+			return subCmd.execute(ctx, opts, args[1:])
 		}
 		// deal with it as regular command if it is not recognized as sub-command
 	}
 
-	var long []PrefixedFlag
-	var short []PrefixedFlag
-	var positionalRequired []PrefixedFlag
-	var positionalOptional []PrefixedFlag
-	for _, pf := range descr.FlagGroup.All("") {
-		if pf.IsArg {
-			if pf.Required {
-				positionalRequired = append(positionalRequired, pf)
-			} else {
-				positionalOptional = append(positionalOptional, pf)
-			}
-		} else {
-			if pf.Shorthand != 0 {
-				short = append(short, pf)
-			}
-			if string(pf.Shorthand) != pf.Name {
-				long = append(long, pf)
-			}
+	if opts.Configure != nil {
+		if err := opts.Configure(descr); err != nil {
+			return descr, err
 		}
 	}
-	sort.SliceStable(long, func(i, j int) bool {
-		return long[i].Path < long[j].Path
-	})
-	sort.SliceStable(short, func(i, j int) bool {
-		return short[i].Path < short[j].Path
-	})
+
+	var paths []string
+	if !raw {
+		paths = argPaths(args)
+	}
+	var custom map[string]string
+	var customRemaining []string
+	if descr.ArgParser != nil && !raw {
+		custom, customRemaining, err = descr.ArgParser.ParseArgs(args)
+		if err == HelpErr {
+			return descr, err
+		} else if err != nil {
+			return descr, &UsageErr{err}
+		}
+		paths = make([]string, 0, len(custom))
+		for p := range custom {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+	}
+
+	// add the elements of indexed groups that flags refer to
+	if err := descr.FlagGroup.expandIndexed("", paths); err != nil {
+		return descr, err
+	}
+
+	short, long, positionalRequired, positionalOptional := descr.sortedFlags()
 
 	seen := make(map[string]struct{})
+	isSeen := func(fl PrefixedFlag) bool {
+		if _, ok := seen[fl.Path]; ok {
+			return true
+		}
+		if fl.ReplacedBy != nil {
+			_, ok := seen[replacementPath(fl)]
+			return ok
+		}
+		return false
+	}
 	set := func(fl PrefixedFlag, value string) error {
+		_, repeated := seen[fl.Path]
 		seen[fl.Path] = struct{}{}
-		for _, ptr := range descr.ChangedMarkers[fl.Path] {
-			*ptr = true
-		}
-
-		if fl.Deprecated != "" && opts.OnDeprecated != nil {
+		if fl.ReplacedBy != nil {
+			path := replacementPath(fl)
+			seen[path] = struct{}{}
+			if opts.OnDeprecated != nil {
+				replaced := *fl.Flag
+				replaced.Deprecated = fmt.Sprintf("positional argument is replaced by flag --%s", path)
+				if err := opts.OnDeprecated(PrefixedFlag{Path: fl.Path, Flag: &replaced}); err != nil {
+					return err
+				}
+			}
+		} else if fl.Deprecated != "" && opts.OnDeprecated != nil {
 			if err := opts.OnDeprecated(fl); err != nil {
 				return err
 			}
 		}
 
-		return fl.Flag.Value.Set(value)
+		if av, ok := fl.Value.(*appendValue); ok {
+			av.arg, av.appending = true, repeated
+			defer func() { av.arg, av.appending = false, false }()
+		}
+		return descr.setFlag(ctx, fl, opts.ParseOptions.normalizeNumber(fl, value), SourceFlag)
 	}
-	remaining, err := ParseArgs(short, long, args, set)
-	if err != nil {
-		// can be a HelpErr to indicate a help-flag was detected
+	var remaining []string
+	if raw {
+		remaining = args
+	} else if descr.ArgParser != nil {
+		remaining = customRemaining
+		err = descr.bindFlags(paths, custom, set)
+	} else {
+		parseOpts := opts.ParseOptions
+		if opts.CollectUnknown {
+			descr.Unknown = make(map[string]string)
+			parseOpts.unknown = func(name string, value string) {
+				descr.Unknown[name] = value
+			}
+		}
+		remaining, err = parseOpts.ParseArgs(short, long, args, set)
+	}
+	if err == HelpErr {
 		return descr, err
+	} else if err != nil {
+		return descr, &UsageErr{err}
+	}
+
+	if err := descr.setFromEnv(ctx, opts.LookupEnv, isSeen, seen); err != nil {
+		return descr, &UsageErr{err}
 	}
 
 	var remainingPositionalRequiredFlags []PrefixedFlag
 	for _, v := range positionalRequired {
-		if _, ok := seen[v.Path]; !ok {
+		if !isSeen(v) {
 			remainingPositionalRequiredFlags = append(remainingPositionalRequiredFlags, v)
 		}
 	}
 	var remainingPositionalOptionalFlags []PrefixedFlag
 	for _, v := range positionalOptional {
-		if _, ok := seen[v.Path]; !ok {
+		if !isSeen(v) {
 			remainingPositionalOptionalFlags = append(remainingPositionalOptionalFlags, v)
 		}
 	}
@@ -548,12 +1223,12 @@ func (descr *CommandDescription) Execute(ctx context.Context, opts *ExecutionOpt
 		for _, pf := range remainingPositionalRequiredFlags {
 			remainingPaths = append(remainingPaths, pf.Path)
 		}
-		return descr, fmt.Errorf("got %d arguments, but expected %d, missing required arguments: %s",
-			len(remaining), len(remainingPositionalRequiredFlags), strings.Join(remainingPaths, ", "))
+		return descr, &UsageErr{fmt.Errorf("got %d arguments, but expected %d, missing required arguments: %s",
+			len(remaining), len(remainingPositionalRequiredFlags), strings.Join(remainingPaths, ", "))}
 	}
 	for i := range remainingPositionalRequiredFlags {
 		if err := set(remainingPositionalRequiredFlags[i], remaining[i]); err != nil {
-			return descr, err
+			return descr, &UsageErr{FlagValueErr(remainingPositionalRequiredFlags[i], remaining[i], err)}
 		}
 	}
 	remaining = remaining[len(remainingPositionalRequiredFlags):]
@@ -566,23 +1241,251 @@ func (descr *CommandDescription) Execute(ctx context.Context, opts *ExecutionOpt
 				break
 			}
 			if err := set(remainingPositionalOptionalFlags[i], remaining[i]); err != nil {
-				return descr, err
+				return descr, &UsageErr{FlagValueErr(remainingPositionalOptionalFlags[i], remaining[i], err)}
 			}
 			count += 1
 		}
 		remaining = remaining[count:]
 	}
 
+	if err := descr.checkToggles(seen); err != nil {
+		return descr, &UsageErr{err}
+	}
+	if u, ok := descr.Command.(*unionCommand); ok {
+		if err := u.check(seen); err != nil {
+			return descr, &UsageErr{err}
+		}
+	}
+	if err := descr.applyRelations(); err != nil {
+		return descr, &UsageErr{err}
+	}
+
+	descr.Remaining = remaining
 	if descr.Command != nil {
+		if spec, ok := descr.Command.(ArgSpec); ok {
+			if err := checkArgCount(spec, len(remaining)); err != nil {
+				return descr, &UsageErr{err}
+			}
+		}
+		if opts.ParseOnly {
+			return descr, nil
+		}
+		if err := descr.Resolve(ctx, opts.ResolveTimeout); err != nil {
+			return descr, err
+		}
+		if err := descr.setupModules(ctx); err != nil {
+			return descr, err
+		}
 		err := descr.Command.Run(ctx, remaining...)
+		// the modules of a long-running command are closed with the command, see Close
+		if _, ok := descr.Command.(io.Closer); !ok || err != nil {
+			if closeErr := descr.closeModules(ctx); closeErr != nil {
+				if err == nil {
+					err = closeErr
+				} else {
+					err = errors.Join(err, closeErr)
+				}
+			}
+		}
+		if opts.Close {
+			if _, ok := descr.Command.(io.Closer); ok && err == nil {
+				<-ctx.Done()
+			}
+			if closeErr := descr.Close(); closeErr != nil {
+				if err == nil {
+					return descr, closeErr
+				}
+				return descr, errors.Join(err, closeErr)
+			}
+		}
 		return descr, err
 	}
 
 	return descr, UnrecognizedErr
 }
 
-func getAsk(f *reflect.StructField) (v string, ok bool) {
-	return f.Tag.Lookup("ask")
+// setFromEnv sets the flags that were not seen in the arguments from their environment variables, if any are set.
+// The source of the value is recorded as "env:" and the name of the variable, e.g. "env:APP_PORT".
+// The errors of all invalid values are returned together.
+func (descr *CommandDescription) setFromEnv(ctx context.Context, lookupEnv func(key string) (string, bool),
+	isSeen func(fl PrefixedFlag) bool, seen map[string]struct{}) error {
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	var errs []error
+	for _, pf := range descr.All("") {
+		if len(pf.Env) == 0 || isSeen(pf) {
+			continue
+		}
+		for _, key := range pf.Env {
+			value, ok := lookupEnv(key)
+			if !ok {
+				continue
+			}
+			source := "env:" + key
+			if err := descr.setFlag(ctx, pf, value, source); err != nil {
+				errs = append(errs, fmt.Errorf("%w (from %s)", FlagValueErr(pf, value, err), source))
+			} else {
+				seen[pf.Path] = struct{}{}
+			}
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routePolicy returns the RoutePolicy of the command, see CommandRoutePolicy.
+func (descr *CommandDescription) routePolicy() RoutePolicy {
+	if p, ok := descr.CommandRoute.(CommandRoutePolicy); ok {
+		return p.RoutePolicy()
+	}
+	if p, ok := descr.Command.(CommandRoutePolicy); ok {
+		return p.RoutePolicy()
+	}
+	return PreferRoute
+}
+
+// acceptsArgs checks if the command takes positional arguments, which may conflict with sub-command routes.
+// Commands receive the remaining arguments in Run, unless their ArgSpec does not allow any.
+func (descr *CommandDescription) acceptsArgs() bool {
+	if descr.Command == nil {
+		return false
+	}
+	for _, pf := range descr.FlagGroup.All("") {
+		if pf.IsArg {
+			return true
+		}
+	}
+	if spec, ok := descr.Command.(ArgSpec); ok {
+		_, max := spec.ArgSpec()
+		return max != 0
+	}
+	return true
+}
+
+// checkDuplicates returns an error if flags or positional args have the same path, e.g. two `<id>` args in
+// groups that are squashed into the same group. Only the first would be usable, and usage would be confusing.
+// Flags with the same shorthand, and a single-character long flag like --v next to another flag with -v, are rejected too.
+func (descr *CommandDescription) checkDuplicates() error {
+	seen := make(map[string]PrefixedFlag)
+	for _, pf := range descr.All("") {
+		// deprecated positional forms of flags share the name with the flag
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		if prev, ok := seen[pf.Path]; ok {
+			return fmt.Errorf("%s and %s have the same name, declare one of them in a named group or rename it",
+				flagDecl(prev), flagDecl(pf))
+		}
+		seen[pf.Path] = pf
+	}
+	// shorthands are shared by all groups, and a single-character long flag like --v is easily confused with -v
+	shorthands := make(map[byte]PrefixedFlag)
+	for _, pf := range descr.All("") {
+		if pf.IsArg || pf.Shorthand == 0 {
+			continue
+		}
+		if prev, ok := shorthands[pf.Shorthand]; ok {
+			return fmt.Errorf("%s and %s have the same shorthand -%c, remove or change one of them",
+				flagDecl(prev), flagDecl(pf), pf.Shorthand)
+		}
+		shorthands[pf.Shorthand] = pf
+	}
+	for _, pf := range descr.All("") {
+		if pf.IsArg || len(pf.Path) != 1 || pf.Shorthand == pf.Path[0] {
+			continue
+		}
+		if other, ok := shorthands[pf.Path[0]]; ok {
+			return fmt.Errorf("%s shadows the shorthand of %s, rename the flag or change the shorthand",
+				flagDecl(pf), flagDecl(other))
+		}
+	}
+	return nil
+}
+
+// sortedFlags splits the flags into shorthand flags, sorted by shorthand, long flags, sorted by path,
+// and required and optional positional args, in order of declaration.
+func (descr *CommandDescription) sortedFlags() (short, long, positionalRequired, positionalOptional []PrefixedFlag) {
+	for _, pf := range descr.FlagGroup.All("") {
+		if pf.IsArg {
+			if pf.Required {
+				positionalRequired = append(positionalRequired, pf)
+			} else {
+				positionalOptional = append(positionalOptional, pf)
+			}
+		} else {
+			if pf.Shorthand != 0 {
+				short = append(short, pf)
+			}
+			if string(pf.Shorthand) != pf.Name {
+				long = append(long, pf)
+			}
+		}
+	}
+	sort.SliceStable(long, func(i, j int) bool {
+		return long[i].Path < long[j].Path
+	})
+	sort.SliceStable(short, func(i, j int) bool {
+		return short[i].Shorthand < short[j].Shorthand
+	})
+	return
+}
+
+// bindFlags sets the flags of the given paths, with the values parsed by a custom ArgParser.
+func (descr *CommandDescription) bindFlags(paths []string, values map[string]string, set ApplyArg) error {
+	for _, p := range paths {
+		fl, ok := descr.Lookup(p)
+		if !ok {
+			return fmt.Errorf("unrecognized flag: %s", p)
+		}
+		if err := set(fl, values[p]); err != nil {
+			return FlagValueErr(fl, values[p], err)
+		}
+	}
+	return nil
+}
+
+// checkToggles returns an error if any of the seen flags is in a group that is not enabled.
+func (descr *CommandDescription) checkToggles(seen map[string]struct{}) error {
+	var check func(g *FlagGroup, prefix string) error
+	check = func(g *FlagGroup, prefix string) error {
+		if !g.Enabled() {
+			for _, pf := range g.All(prefix) {
+				if _, ok := seen[pf.Path]; ok {
+					return fmt.Errorf("flag %s is disabled, enable it with --%s", pf.Path, g.togglePath(prefix))
+				}
+			}
+			return nil
+		}
+		path := g.path(prefix)
+		for _, e := range g.Entries {
+			if err := check(e, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(&descr.FlagGroup, "")
+}
+
+// replacementPath returns the path of the flag that replaces the deprecated positional arg.
+func replacementPath(fl PrefixedFlag) string {
+	return strings.TrimSuffix(fl.Path, fl.Name) + fl.ReplacedBy.Name
+}
+
+func checkArgCount(spec ArgSpec, count int) error {
+	min, max := spec.ArgSpec()
+	if count >= min && (max < 0 || count <= max) {
+		return nil
+	}
+	switch {
+	case max < 0:
+		return fmt.Errorf("expected at least %d arguments, got %d", min, count)
+	case min == max:
+		return fmt.Errorf("expected %d arguments, got %d", min, count)
+	default:
+		return fmt.Errorf("expected %d-%d arguments, got %d", min, max, count)
+	}
 }
 
 func getChanged(f *reflect.StructField) (v string, ok bool) {
@@ -596,13 +1499,26 @@ var durationType = reflect.TypeOf(time.Second)
 var ipType = reflect.TypeOf(net.IP{})
 var ipmaskType = reflect.TypeOf(net.IPMask{})
 var ipNetType = reflect.TypeOf(net.IPNet{})
+var macType = reflect.TypeOf(net.HardwareAddr{})
+var regexpType = reflect.TypeOf((*regexp.Regexp)(nil))
+var tcpAddrType = reflect.TypeOf(net.TCPAddr{})
+var udpAddrType = reflect.TypeOf(net.UDPAddr{})
+var levelType = reflect.TypeOf(slog.Level(0))
+
+var fileModeType = reflect.TypeOf(fs.FileMode(0))
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 
 // LoadField loads a struct field as flag
 func LoadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
+	l := &loader{}
+	return l.loadField(f, val)
+}
+
+func (l *loader) loadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
 	if !val.CanAddr() {
 		return
 	}
-	v, ok := getAsk(&f)
+	v, ok := l.getAsk(&f)
 	if !ok {
 		return
 	}
@@ -611,10 +1527,15 @@ func LoadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
 	deprecated := ""
 	help := ""
 	hidden := false
+	secret := false
+	hideDefault := false
+	attached := false
 	isArg := false
 	required := false
 
-	if h, ok := f.Tag.Lookup("help"); ok {
+	if h, ok, err := l.getHelp(&f); err != nil {
+		return nil, err
+	} else if ok {
 		help = h
 	}
 
@@ -625,20 +1546,49 @@ func LoadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
 	if _, ok := f.Tag.Lookup("hidden"); ok {
 		hidden = true
 	}
+	if _, ok := f.Tag.Lookup("secret"); ok {
+		secret = true
+	}
+	if d, ok := f.Tag.Lookup("showdefault"); ok && d == "false" {
+		hideDefault = true
+	}
+	if a, ok := f.Tag.Lookup("attached"); ok && a == "true" {
+		attached = true
+	}
+	requires := splitList(f.Tag.Get("requires"))
+	conflicts := splitList(f.Tag.Get("conflicts"))
+	defaultFrom := f.Tag.Get("default-from")
+	unit := f.Tag.Get("unit")
+	example := f.Tag.Get("example")
+	link := f.Tag.Get("link")
+	env := splitList(f.Tag.Get("env"))
+	onlyIf := f.Tag.Get("onlyif")
+	together := splitList(f.Tag.Get("and"))
+	early := f.Tag.Get("early") == "true"
+	var transforms []Transform
+	if t, ok := f.Tag.Lookup("transform"); ok {
+		transforms, err = loadTransforms(t)
+		if err != nil {
+			return nil, fmt.Errorf("field %q has invalid transform: %v", f.Name, err)
+		}
+	}
+	if fileOk, ok := f.Tag.Lookup("file-ok"); ok && fileOk == "true" {
+		transforms = append([]Transform{ReadFileRef}, transforms...)
+	}
 
-	value, err := FlagValue(f.Type, val)
+	value, err := fieldValue(&f, val)
 	if err != nil {
-		return nil, fmt.Errorf("failed to handle value type of field %s as flag/arg: %v", f.Name, err)
+		return nil, err
 	}
 
 	for _, k := range strings.Split(v, " ") {
 		if k == "" {
 			continue
 		}
-		if name != "" {
-			return nil, fmt.Errorf("field %q cannot have different flag/arg declarations", f.Name)
-		}
 		if strings.HasPrefix(k, "--") {
+			if name != "" {
+				return nil, fmt.Errorf("field %q cannot have different flag/arg declarations", f.Name)
+			}
 			if len(k) < 3 {
 				return nil, fmt.Errorf("field %q long flag must have at least 1 char name", f.Name)
 			}
@@ -649,28 +1599,38 @@ func LoadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
 			if shorthand != 0 {
 				return nil, fmt.Errorf("field %q cannot have two different short-flag style declarations", f.Name)
 			}
-			if len(k) == 2 {
+			if len(k) != 2 {
 				return nil, fmt.Errorf("field %q short flag must have a 1 char short name", f.Name)
 			}
 			shorthand = k[1]
 			continue
 		}
-		if len(v) < 3 {
+		if name != "" {
+			return nil, fmt.Errorf("field %q cannot have different flag/arg declarations", f.Name)
+		}
+		if len(k) < 3 {
 			return nil, fmt.Errorf("field %q positional arg must have at least 1 char name", f.Name)
 		}
-		if strings.HasPrefix(v, "<") && strings.HasSuffix(v, ">") {
-			name = v[1 : len(v)-1]
+		if strings.HasPrefix(k, "<") && strings.HasSuffix(k, ">") {
+			name = k[1 : len(k)-1]
 			isArg = true
 			required = true
 			continue
 		}
-		if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
-			name = v[1 : len(v)-1]
+		if strings.HasPrefix(k, "[") && strings.HasSuffix(k, "]") {
+			name = k[1 : len(k)-1]
 			isArg = true
 			continue
 		}
 		return nil, fmt.Errorf("struct field %q has invalid Ask arg/flag declaration", f.Name)
 	}
+	if isArg && shorthand != 0 {
+		return nil, fmt.Errorf("field %q positional arg cannot have a short flag", f.Name)
+	}
+
+	if _, ok := value.(SecretValue); ok {
+		secret = true
+	}
 
 	// use shorthand as name if name is missing
 	if shorthand != 0 && name == "" {
@@ -678,18 +1638,378 @@ func LoadField(f reflect.StructField, val reflect.Value) (fl *Flag, err error) {
 	}
 
 	return &Flag{
-		Value:      value,
-		Name:       name,
-		Shorthand:  shorthand,
-		IsArg:      isArg,
-		Help:       help,
-		Default:    value.String(),
-		Required:   required,
-		Deprecated: deprecated,
-		Hidden:     hidden,
+		Value:       value,
+		Name:        name,
+		Shorthand:   shorthand,
+		IsArg:       isArg,
+		Help:        help,
+		Default:     value.String(),
+		Required:    required,
+		Deprecated:  deprecated,
+		Hidden:      hidden,
+		Secret:      secret,
+		Transforms:  transforms,
+		HideDefault: hideDefault,
+		Attached:    attached,
+		Requires:    requires,
+		Conflicts:   conflicts,
+		DefaultFrom: defaultFrom,
+		OnlyIf:      onlyIf,
+		Together:    together,
+		Early:       early,
+		Unit:        unit,
+		Example:     example,
+		Link:        link,
+		Env:         env,
 	}, nil
 }
 
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(v string) []string {
+	var out []string
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// toggleFlag finds the boolean flag with the given name in the group, to toggle the sub-group with.
+// The flag is created if it is not declared by a field. A declared flag that is not boolean is an error.
+func toggleFlag(grp *FlagGroup, sub *FlagGroup, name string) (*Flag, error) {
+	for _, fl := range grp.Flags {
+		if fl.Name == name && !fl.IsArg {
+			if v, ok := fl.Value.(ImplicitValue); !ok || v.Implicit() != "true" {
+				return nil, fmt.Errorf("flag --%s toggles group %s, but is not a bool flag", name, sub.GroupName)
+			}
+			return fl, nil
+		}
+	}
+	fl := &Flag{
+		Value:   new(BoolValue),
+		Name:    name,
+		Help:    fmt.Sprintf("Enable the %s flags", sub.GroupName),
+		Default: "false",
+	}
+	grp.Flags = append(grp.Flags, fl)
+	return fl, nil
+}
+
+// fieldValue creates the flag value of a field, bound to the value of the field, with the tags of the field that change its format.
+func fieldValue(f *reflect.StructField, val reflect.Value) (value flag.Value, err error) {
+	format, ok := f.Tag.Lookup("format")
+	if !ok {
+		if format, ok, err = formatAlias(f); err != nil {
+			return nil, err
+		}
+	}
+	if ok {
+		value, err = formatValue(format, val)
+		if err != nil {
+			return nil, fmt.Errorf("field %q has invalid format: %v", f.Name, err)
+		}
+	} else {
+		value, err = FlagValue(f.Type, val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to handle value type of field %s as flag/arg: %v", f.Name, err)
+		}
+	}
+	if bounds, err := durationBounds(f, val); err != nil {
+		return nil, fmt.Errorf("field %q has invalid bounds: %v", f.Name, err)
+	} else if bounds != nil {
+		value = bounds
+	}
+	if e, ok := f.Tag.Lookup("enum"); ok {
+		value, err = enumValue(f.Type, value, e)
+		if err != nil {
+			return nil, fmt.Errorf("field %q has invalid enum: %v", f.Name, err)
+		}
+	}
+	if sep, ok := f.Tag.Lookup("sep"); ok {
+		if f.Type.Kind() != reflect.Slice || sep == "" {
+			return nil, fmt.Errorf("field %q must be a slice with a non-empty separator", f.Name)
+		}
+		value = &sepValue{Value: value, dest: val, sep: sep}
+	}
+	if m, ok := f.Tag.Lookup("mode"); ok {
+		if m != "append" {
+			return nil, fmt.Errorf("field %q has unknown mode %q", f.Name, m)
+		}
+		if k := f.Type.Kind(); k != reflect.Slice && k != reflect.Map {
+			return nil, fmt.Errorf("field %q must be a slice or map to append to", f.Name)
+		}
+		value = &appendValue{Value: value, dest: val}
+	}
+	return value, nil
+}
+
+// formatAlias returns the format of the tags that came before the format tag:
+// `count:"true"`, `rune:"true"`, and `percent:"fraction"` or `percent:"whole"`.
+func formatAlias(f *reflect.StructField) (format string, ok bool, err error) {
+	if c, ok := f.Tag.Lookup("count"); ok && c == "true" {
+		return "count", true, nil
+	}
+	if r, ok := f.Tag.Lookup("rune"); ok && r == "true" {
+		return "rune", true, nil
+	}
+	if p, ok := f.Tag.Lookup("percent"); ok {
+		switch p {
+		case "fraction":
+			return "percent", true, nil
+		case "whole":
+			return "percent-whole", true, nil
+		}
+		return "", false, fmt.Errorf("field %q has invalid percent tag %q, expected fraction or whole", f.Name, p)
+	}
+	return "", false, nil
+}
+
+// defaultTag applies the `default` tag to a loaded flag, if the field is still zero after initialization.
+// The value is parsed like a flag argument, e.g. `default:"5s"` for a duration.
+func defaultTag(f *reflect.StructField, v reflect.Value, fl *Flag) error {
+	d, ok := f.Tag.Lookup("default")
+	if !ok || !v.IsZero() {
+		return nil
+	}
+	if err := fl.Set(d); err != nil {
+		return fmt.Errorf("field %q has invalid default: %v", f.Name, err)
+	}
+	fl.Default = fl.Value.String()
+	fl.DefaultOrigin = DefaultTag
+	return nil
+}
+
+// loadDeprecatedArg creates a deprecated optional positional arg, declared like `deprecated-arg:"[name]"`,
+// that shares the value of the given flag which replaces it.
+func loadDeprecatedArg(fieldName string, decl string, fl *Flag) (*Flag, error) {
+	if fl.IsArg {
+		return nil, fmt.Errorf("field %q is a positional arg, cannot be replacement of deprecated arg", fieldName)
+	}
+	if len(decl) < 3 || !strings.HasPrefix(decl, "[") || !strings.HasSuffix(decl, "]") {
+		return nil, fmt.Errorf("field %q deprecated arg must be an optional positional arg, like [name]", fieldName)
+	}
+	return &Flag{
+		Value:      fl.Value,
+		Name:       decl[1 : len(decl)-1],
+		IsArg:      true,
+		Help:       fl.Help,
+		Default:    fl.Default,
+		Deprecated: fmt.Sprintf("replaced by flag --%s", fl.Name),
+		Hidden:     fl.Hidden,
+		Secret:     fl.Secret,
+		ReplacedBy: fl,
+		Transforms: fl.Transforms,
+	}, nil
+}
+
+// enumValue restricts the value of a string or integer field to the comma-separated list of allowed values.
+func enumValue(typ reflect.Type, value flag.Value, list string) (flag.Value, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, fmt.Errorf("only string and integer values can be an enum, not %s", typ)
+	}
+	allowed := splitList(list)
+	if len(allowed) == 0 {
+		return nil, errors.New("no allowed values")
+	}
+	// check that the allowed values are valid, without changing the value
+	tmp := reflect.New(typ)
+	tmpValue, err := FlagValue(typ, tmp.Elem())
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allowed {
+		if err := tmpValue.Set(a); err != nil {
+			return nil, fmt.Errorf("allowed value %q is invalid: %v", a, err)
+		}
+	}
+	return &EnumValue{Value: value, Allowed: allowed}, nil
+}
+
+// durationBounds loads the `min`, `max` and `multipleof` tags of a duration field, or nil if the field has none.
+func durationBounds(f *reflect.StructField, val reflect.Value) (flag.Value, error) {
+	var out DurationBounds
+	for _, tag := range []string{"min", "max", "multipleof"} {
+		v, ok := f.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		if f.Type != durationType {
+			return nil, fmt.Errorf("%s tag requires a time.Duration type, got %s", tag, f.Type)
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s tag: %v", tag, err)
+		}
+		switch tag {
+		case "min":
+			out.Min = &d
+		case "max":
+			out.Max = &d
+		case "multipleof":
+			if d <= 0 {
+				return nil, fmt.Errorf("multipleof tag must be positive, got %s", d)
+			}
+			out.MultipleOf = d
+		}
+	}
+	if out.Min == nil && out.Max == nil && out.MultipleOf == 0 {
+		return nil, nil
+	}
+	if out.Min != nil && out.Max != nil && *out.Min > *out.Max {
+		return nil, fmt.Errorf("min %s is more than max %s", *out.Min, *out.Max)
+	}
+	out.DurationValue = (*DurationValue)(unsafe.Pointer(val.Addr().Pointer()))
+	return &out, nil
+}
+
+// appendValue appends the values of a repeated flag to a slice or map, see the `mode:"append"` struct tag.
+// The first occurrence of the flag replaces the default, like other flags.
+// Every occurrence of a map flag is a single key=value pair, the value may contain commas.
+type appendValue struct {
+	flag.Value
+	dest reflect.Value
+	// arg is enabled by Execute when the value is an occurrence of the flag in the arguments
+	arg bool
+	// appending is enabled by Execute when the flag is repeated
+	appending bool
+}
+
+func (a *appendValue) Set(s string) error {
+	if a.dest.Kind() == reflect.Map && a.arg {
+		return a.setPair(s)
+	}
+	if !a.appending {
+		return a.Value.Set(s)
+	}
+	prev := reflect.AppendSlice(reflect.MakeSlice(a.dest.Type(), 0, a.dest.Len()), a.dest)
+	if err := a.Value.Set(s); err != nil {
+		return err
+	}
+	a.dest.Set(reflect.AppendSlice(prev, a.dest))
+	return nil
+}
+
+// setPair adds a single key=value pair to the map, or replaces the map with it if it is not appending.
+func (a *appendValue) setPair(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value pair, got %q", s)
+	}
+	typ := a.dest.Type()
+	key, elem := reflect.New(typ.Key()).Elem(), reflect.New(typ.Elem()).Elem()
+	for _, x := range []struct {
+		dest reflect.Value
+		v    string
+	}{{key, k}, {elem, v}} {
+		fl, err := FlagValue(x.dest.Type(), x.dest)
+		if err != nil {
+			return err
+		}
+		if err := fl.Set(x.v); err != nil {
+			return err
+		}
+	}
+	if !a.appending || a.dest.IsNil() {
+		a.dest.Set(reflect.MakeMap(typ))
+	}
+	a.dest.SetMapIndex(key, elem)
+	return nil
+}
+
+func (a *appendValue) Type() string {
+	if typed, ok := a.Value.(TypedValue); ok {
+		return typed.Type()
+	}
+	return ""
+}
+
+// sepValue splits a slice flag by a custom separator, see the `sep` struct tag.
+// Each element is parsed on its own, e.g. a string element may contain commas.
+type sepValue struct {
+	flag.Value
+	dest reflect.Value
+	sep  string
+}
+
+func (s *sepValue) Set(v string) error {
+	var parts []string
+	if v != "" {
+		parts = strings.Split(v, s.sep)
+	}
+	out := reflect.MakeSlice(s.dest.Type(), len(parts), len(parts))
+	for i, part := range parts {
+		elem, err := FlagValue(out.Type().Elem(), out.Index(i))
+		if err != nil {
+			return err
+		}
+		if err := elem.Set(part); err != nil {
+			return err
+		}
+	}
+	s.dest.Set(out)
+	return nil
+}
+
+func (s *sepValue) String() string {
+	if !s.dest.IsValid() {
+		return ""
+	}
+	parts := make([]string, s.dest.Len())
+	for i := range parts {
+		elem, err := FlagValue(s.dest.Type().Elem(), s.dest.Index(i))
+		if err != nil {
+			return ""
+		}
+		parts[i] = elem.String()
+	}
+	return strings.Join(parts, s.sep)
+}
+
+func (s *sepValue) Type() string {
+	if typed, ok := s.Value.(TypedValue); ok {
+		return typed.Type()
+	}
+	return ""
+}
+
+// formatValue loads a value that is decoded in the given format, regardless of the type of the value,
+// or a value of a type that is ambiguous on its own, like a rune that is an int32, or an int that counts.
+func formatValue(format string, val reflect.Value) (flag.Value, error) {
+	switch format {
+	case "json":
+		return jsonValue(val)
+	case "count":
+		if typ := val.Type(); typ.Kind() != reflect.Int {
+			return nil, fmt.Errorf("count format requires an int type, got %s", typ)
+		}
+		return (*CountValue)(unsafe.Pointer(val.Addr().Pointer())), nil
+	case "rune":
+		if typ := val.Type(); typ.Kind() != reflect.Int32 {
+			return nil, fmt.Errorf("rune format requires a rune type, got %s", typ)
+		}
+		return (*RuneValue)(unsafe.Pointer(val.Addr().Pointer())), nil
+	case "percent", "percent-whole":
+		if typ := val.Type(); typ.Kind() != reflect.Float64 {
+			return nil, fmt.Errorf("%s format requires a float64 type, got %s", format, typ)
+		}
+		return &PercentValue{Dest: (*float64)(unsafe.Pointer(val.Addr().Pointer())), Whole: format == "percent-whole"}, nil
+	case "uuid":
+		if typ := val.Type(); typ.Kind() != reflect.Array || typ.Len() != 16 || typ.Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("uuid format requires a [16]byte type, got %s", typ)
+		}
+		return (*UUIDValue)(unsafe.Pointer(val.Addr().Pointer())), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
 func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 	// Get the pointer to the destination struct, to route pflags to
 	ptr := unsafe.Pointer(val.Addr().Pointer())
@@ -704,6 +2024,8 @@ func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 		fl = val.Interface().(flag.Value)
 	} else if reflect.PtrTo(typ).Implements(flagValueType) {
 		fl = val.Addr().Interface().(flag.Value)
+	} else if registered, ok := registeredValues[typ]; ok {
+		fl = registered(val)
 	} else if typ == durationType {
 		fl = (*DurationValue)(ptr)
 	} else if typ == ipType {
@@ -712,6 +2034,20 @@ func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 		fl = (*IPNetValue)(ptr)
 	} else if typ == ipmaskType {
 		fl = (*IPMaskValue)(ptr)
+	} else if typ == macType {
+		fl = (*MACValue)(ptr)
+	} else if typ == tcpAddrType {
+		fl = (*TCPAddrValue)(ptr)
+	} else if typ == udpAddrType {
+		fl = (*UDPAddrValue)(ptr)
+	} else if typ == levelType {
+		fl = (*LevelValue)(ptr)
+	} else if typ == fileModeType {
+		fl = (*FileModeValue)(ptr)
+	} else if typ == regexpType {
+		fl = &RegexpValue{Dest: (**regexp.Regexp)(ptr)}
+	} else if typ.Kind() != reflect.Ptr && reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		fl = &TextValue{Dest: val.Addr().Interface().(encoding.TextUnmarshaler)}
 	} else {
 		switch typ.Kind() {
 		// unsigned integers
@@ -752,6 +2088,10 @@ func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 				fl = (*DurationSliceValue)(ptr)
 			} else if elemTyp == ipType {
 				fl = (*IPSliceValue)(ptr)
+			} else if elemTyp == ipNetType {
+				fl = (*IPNetSliceValue)(ptr)
+			} else if elemTyp == macType {
+				fl = (*MACSliceValue)(ptr)
 			} else {
 				switch elemTyp.Kind() {
 				case reflect.Array:
@@ -807,6 +2147,27 @@ func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 			default:
 				return nil, fmt.Errorf("unrecognized array element type: %v", elemTyp.String())
 			}
+		case reflect.Map:
+			if typ.Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("unrecognized map key type: %v", typ.Key().String())
+			}
+			switch typ.Elem().Kind() {
+			case reflect.String:
+				fl = (*MapStringValue)(ptr)
+			case reflect.Int:
+				fl = (*MapIntValue)(ptr)
+			case reflect.Uint64:
+				fl = (*MapUint64Value)(ptr)
+			case reflect.Bool:
+				fl = (*MapBoolValue)(ptr)
+			case reflect.Int64:
+				if typ.Elem() != durationType {
+					return nil, fmt.Errorf("unrecognized map value type: %v", typ.Elem().String())
+				}
+				fl = (*MapDurationValue)(ptr)
+			default:
+				return nil, fmt.Errorf("unrecognized map value type: %v", typ.Elem().String())
+			}
 		case reflect.Ptr:
 			contentTyp := typ.Elem()
 			// allocate a destination value if it doesn't exist yet
@@ -821,67 +2182,3 @@ func FlagValue(typ reflect.Type, val reflect.Value) (flag.Value, error) {
 	}
 	return fl, nil
 }
-
-type start struct {
-	cmd *CommandDescription
-	err error
-}
-
-// Run is a convenience-method to run a command: it handles long-running commands and shuts down on os.Interrupt signal.
-// Arguments are read from os.Args[1:] (i.e. program name is skipped).
-// Set the "HIDDEN_OPTIONS" env var to show hidden CLI options.
-func Run(cmd interface{}) {
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	descr, err := Load(cmd)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "failed to load main command: %v", err.Error())
-		os.Exit(1)
-	}
-	onDeprecated := func(fl PrefixedFlag) error {
-		fmt.Fprintf(os.Stderr, "warning: flag %q is deprecated: %s", fl.Path, fl.Deprecated)
-		return nil
-	}
-
-	starter := make(chan start)
-
-	// run command in the background, so we can stop it at any time
-	go func() {
-		cmd, err := descr.Execute(ctx, &ExecutionOptions{OnDeprecated: onDeprecated}, os.Args[1:]...)
-		starter <- start{cmd, err}
-	}()
-
-	for {
-		select {
-		case start := <-starter:
-			if cmd, err := start.cmd, start.err; err == nil {
-				// if the command is long-running and closeable later on, then have the interrupt close it.
-				if cl, ok := cmd.Command.(io.Closer); ok {
-					<-interrupt
-					err := cl.Close()
-					cancel()
-					if err != nil {
-						_, _ = fmt.Fprintf(os.Stderr, "failed to close gracefully. Exiting in 5 seconds. %v", err.Error())
-						<-time.After(time.Second * 5)
-						os.Exit(1)
-					}
-				}
-				os.Exit(0)
-			} else if err == UnrecognizedErr {
-				_, _ = fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			} else if err == HelpErr {
-				_, _ = fmt.Fprintln(os.Stderr, cmd.Usage(os.Getenv("HIDDEN_OPTIONS") != ""))
-				os.Exit(0)
-			} else {
-				_, _ = fmt.Fprintln(os.Stderr, err.Error())
-				os.Exit(1)
-			}
-		case <-interrupt: // if interrupted during start, then we try to cancel
-			cancel()
-		}
-	}
-}
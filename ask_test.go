@@ -1,13 +1,17 @@
 package ask
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 type ActorState struct {
@@ -132,6 +136,13 @@ func TestPeerConnect(t *testing.T) {
 		if !strings.Contains(usage, "default: 4,5,6") {
 			t.Fatalf("expected embedded default to be included in help details, got: %s", usage)
 		}
+		filtered := cmd.Usage(false, WithFilter("PEER"))
+		if !strings.Contains(filtered, "--peer.tag") || !strings.Contains(filtered, "# peer") || !strings.Contains(filtered, "# 1 flags") {
+			t.Fatalf("expected peer group in filtered usage, got: %s", filtered)
+		}
+		if strings.Contains(filtered, "--port") || strings.Contains(filtered, "# fork") {
+			t.Fatalf("expected other flags to be filtered out, got: %s", filtered)
+		}
 	}
 
 	// Execute returns the final command that is executed,
@@ -145,3 +156,1172 @@ func TestPeerConnect(t *testing.T) {
 		t.Errorf("got unexpected host data value: %s", state.HostData)
 	}
 }
+
+type Routes struct {
+	Ran *string
+}
+
+func (c *Routes) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "connect", "config", "Status":
+		return &Leaf{Name: route, Ran: c.Ran}, nil
+	default:
+		return nil, UnrecognizedErr
+	}
+}
+
+func (c *Routes) Routes() []string {
+	return []string{"connect", "config", "Status"}
+}
+
+type Leaf struct {
+	Name string
+	Ran  *string
+}
+
+func (c *Leaf) Run(ctx context.Context, args ...string) error {
+	*c.Ran = c.Name
+	return nil
+}
+
+func TestRouteMatching(t *testing.T) {
+	var ran string
+	cmd, err := Load(&Routes{Ran: &ran})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "status"); err != UnrecognizedErr {
+		t.Fatalf("expected case-sensitive match by default, got: %v", err)
+	}
+	opts := &ExecutionOptions{RouteIgnoreCase: true, RoutePrefix: true}
+	for input, expected := range map[string]string{"status": "Status", "conn": "connect", "CONF": "config", "s": "Status"} {
+		if _, err := cmd.Execute(context.Background(), opts, input); err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		if ran != expected {
+			t.Fatalf("%s: expected %s to run, got %s", input, expected, ran)
+		}
+	}
+	if _, err := cmd.Execute(context.Background(), opts, "con"); err == nil || !strings.Contains(err.Error(), "connect, config") {
+		t.Fatalf("expected ambiguity error listing candidates, got: %v", err)
+	}
+}
+
+type PolicyRoot struct {
+	Policy RoutePolicy
+	Target string `ask:"[target]"`
+	Ran    *string
+}
+
+func (c *PolicyRoot) Cmd(route string) (cmd interface{}, err error) {
+	if route == "status" {
+		return &Leaf{Name: route, Ran: c.Ran}, nil
+	}
+	return nil, nil
+}
+
+func (c *PolicyRoot) Routes() []string {
+	return []string{"status"}
+}
+
+func (c *PolicyRoot) RoutePolicy() RoutePolicy {
+	return c.Policy
+}
+
+func (c *PolicyRoot) Run(ctx context.Context, args ...string) error {
+	*c.Ran = "root " + c.Target
+	return nil
+}
+
+func TestRoutePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy   RoutePolicy
+		args     []string
+		expected string
+	}{
+		{PreferRoute, []string{"status"}, "status"},
+		{PreferRoute, []string{"other"}, "root other"},
+		{PreferCommand, []string{"status"}, "root status"},
+		{RouteAmbiguityError, []string{"other"}, "root other"},
+		{RouteAmbiguityError, []string{"--", "status"}, "root status"},
+	} {
+		var ran string
+		cmd, err := Load(&PolicyRoot{Policy: tc.policy, Ran: &ran})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmd.Execute(context.Background(), nil, tc.args...); err != nil {
+			t.Fatalf("%s %q: %v", tc.policy, tc.args, err)
+		}
+		if ran != tc.expected {
+			t.Fatalf("%s %q: expected %q, got %q", tc.policy, tc.args, tc.expected, ran)
+		}
+	}
+	var ran string
+	cmd, err := Load(&PolicyRoot{Policy: RouteAmbiguityError, Ran: &ran})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "status"); !IsUsageErr(err) || !strings.Contains(err.Error(), "-- status") {
+		t.Fatalf("expected ambiguity error, got: %v", err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "Arguments that match a sub command are rejected") {
+		t.Fatalf("expected policy in usage, got: %s", usage)
+	}
+}
+
+type SpecCmd struct{}
+
+func (c *SpecCmd) ArgSpec() (min int, max int) {
+	return 1, 2
+}
+
+func (c *SpecCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestArgSpec(t *testing.T) {
+	cmd, err := Load(&SpecCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "a", "b", "c", "d", "e"); err == nil || err.Error() != "expected 1-2 arguments, got 5" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type DeprecatedArgCmd struct {
+	Peer struct {
+		ID string `ask:"--id" deprecated-arg:"[id]" help:"ID of the peer"`
+	} `ask:".peer"`
+	IDChanged bool `changed:"peer.id"`
+}
+
+func (c *DeprecatedArgCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDeprecatedArg(t *testing.T) {
+	var c DeprecatedArgCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deprecated []string
+	opts := &ExecutionOptions{OnDeprecated: func(fl PrefixedFlag) error {
+		deprecated = append(deprecated, fl.Path+": "+fl.Deprecated)
+		return nil
+	}}
+	if _, err := cmd.Execute(context.Background(), opts, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Peer.ID != "abc" || !c.IDChanged {
+		t.Fatalf("expected positional arg to set replacement flag, got %q (changed: %v)", c.Peer.ID, c.IDChanged)
+	}
+	if len(deprecated) != 1 || deprecated[0] != "peer.id: positional argument is replaced by flag --peer.id" {
+		t.Fatalf("unexpected deprecation warnings: %v", deprecated)
+	}
+	deprecated = nil
+	if _, err := cmd.Execute(context.Background(), opts, "--peer.id=def", "extra"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Peer.ID != "def" || len(deprecated) != 0 {
+		t.Fatalf("expected flag to take precedence, got %q, warnings: %v", c.Peer.ID, deprecated)
+	}
+}
+
+func TestFlagDeclarations(t *testing.T) {
+	load := func(decl string) (*CommandDescription, error) {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "X", Type: reflect.TypeOf(""), Tag: reflect.StructTag(`ask:` + strconv.Quote(decl))}})
+		return Load(reflect.New(typ).Interface())
+	}
+	for decl, expected := range map[string]string{
+		"--verbose -v": "verbose -v",
+		"-v --verbose": "verbose -v",
+		"-v":           "v -v",
+		"--verbose":    "verbose",
+		"<name>":       "name",
+		"[name]":       "name",
+	} {
+		cmd, err := load(decl)
+		if err != nil {
+			t.Fatalf("%q: %v", decl, err)
+		}
+		fl := cmd.Flags[0]
+		got := fl.Name
+		if fl.Shorthand != 0 {
+			got += " -" + string(fl.Shorthand)
+		}
+		if got != expected {
+			t.Fatalf("%q: unexpected flag %q", decl, got)
+		}
+	}
+	for _, decl := range []string{"--a --b", "-a -b", "-ab", "--", "<a> [b]", "<a> -a", "[a] --a", "<>", "name"} {
+		if _, err := load(decl); err == nil {
+			t.Fatalf("%q: expected declaration error", decl)
+		}
+	}
+}
+
+type RenamedArgCmd struct {
+	Addr           string `ask:"--addr" deprecated-arg:"[address]"`
+	AddrChanged    bool   `changed:"addr"`
+	AddressChanged bool   `changed:"address"`
+}
+
+func (c *RenamedArgCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDeprecatedArgChanged(t *testing.T) {
+	var c RenamedArgCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != "localhost" || !c.AddrChanged || !c.AddressChanged {
+		t.Fatalf("expected both paths to be changed: %+v", c)
+	}
+}
+
+type FailingCmd struct {
+	Count uint8 `ask:"--count"`
+}
+
+func (c *FailingCmd) Run(ctx context.Context, args ...string) error {
+	return errors.New("runtime failure")
+}
+
+func TestUsageErr(t *testing.T) {
+	cmd, err := Load(&FailingCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--count=300"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--unknown"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--count=3"); err == nil || IsUsageErr(err) {
+		t.Fatalf("expected runtime error, got: %v", err)
+	}
+}
+
+type LockCmd struct {
+	DataDir string `ask:"--datadir"`
+	Port    uint16 `ask:"--port"`
+}
+
+func (c *LockCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestPin(t *testing.T) {
+	var c LockCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Pin("datadir", "/data"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port=123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--datadir=/tmp"); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("expected locked flag error, got: %v", err)
+	}
+	if c.DataDir != "/data" || c.Port != 123 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if err := cmd.Lock("unknown"); err == nil {
+		t.Fatal("expected error for unknown flag")
+	}
+}
+
+func TestSources(t *testing.T) {
+	var c LockCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.SetFromMap(map[string]string{"port": "8000", "datadir": "/data"}, "config:app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port=123"); err != nil {
+		t.Fatal(err)
+	}
+	if src := cmd.Source("datadir"); src != "config:app.json" {
+		t.Fatalf("unexpected datadir source: %s", src)
+	}
+	usage := cmd.Usage(false, WithSources())
+	if !strings.Contains(usage, "(source: flag)") || !strings.Contains(usage, "(source: config:app.json)") {
+		t.Fatalf("expected sources in usage, got: %s", usage)
+	}
+	if err := cmd.SetFromMap(map[string]string{"unknown": "x"}, "env"); err == nil {
+		t.Fatal("expected unknown flag error")
+	}
+	// the port flag argument has precedence over config files until reset
+	if err := cmd.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.SetFromMap(map[string]string{"a": "1", "port": "x", "datadir": "/other", "z": "2"}, "config:bad.json")
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `unknown flag "a" (from config:bad.json)`) ||
+		!strings.Contains(lines[1], "failed to apply flag port") || !strings.Contains(lines[2], `unknown flag "z"`) {
+		t.Fatalf("expected all errors, got: %v", err)
+	}
+	if c.DataDir != "/other" {
+		t.Fatalf("expected valid values to be applied, got: %+v", c)
+	}
+}
+
+type EnvCmd struct {
+	Port  uint16 `ask:"--port" env:"APP_PORT,PORT" help:"Port to listen on"`
+	Token string `ask:"--token" env:"APP_TOKEN" secret:"true"`
+	Peer  string `ask:"<peer>" env:"APP_PEER"`
+}
+
+func (c *EnvCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestEnvTag(t *testing.T) {
+	var c EnvCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "Port to listen on (default: 0) (type: uint16) (env: APP_PORT, PORT)") {
+		t.Fatalf("expected env names in usage, got: %s", usage)
+	}
+	env := map[string]string{"PORT": "8000", "APP_TOKEN": "secret", "APP_PEER": "bob"}
+	opts := &ExecutionOptions{LookupEnv: func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}}
+	if _, err := cmd.Execute(context.Background(), opts, "--token=abc"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8000 || c.Token != "abc" || c.Peer != "bob" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if cmd.Source("port") != "env:PORT" || cmd.Source("token") != SourceFlag || cmd.Source("peer") != "env:APP_PEER" {
+		t.Fatalf("unexpected sources: %v", cmd.Sources)
+	}
+	env["APP_PORT"] = "x"
+	if _, err := cmd.Execute(context.Background(), opts); !IsUsageErr(err) || !strings.Contains(err.Error(), "(from env:APP_PORT)") {
+		t.Fatalf("expected env error, got: %v", err)
+	}
+}
+
+type EnvErrorsCmd struct {
+	Port    uint16 `ask:"--port" env:"APP_PORT"`
+	Workers uint8  `ask:"--workers" env:"APP_WORKERS"`
+}
+
+func (c *EnvErrorsCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestEnvErrors(t *testing.T) {
+	cmd, err := Load(&EnvErrorsCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"APP_PORT": "x", "APP_WORKERS": "1000"}
+	opts := &ExecutionOptions{LookupEnv: func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}}
+	_, err = cmd.Execute(context.Background(), opts)
+	if !IsUsageErr(err) || !strings.Contains(err.Error(), "(from env:APP_PORT)") || !strings.Contains(err.Error(), "(from env:APP_WORKERS)") {
+		t.Fatalf("expected all env errors, got: %v", err)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	var c EnvCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"APP_PORT": "8000", "APP_TOKEN": "secret"}
+	opts := &ExecutionOptions{LookupEnv: func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}}
+	if err := cmd.SetFromMap(map[string]string{"port": "7000", "token": "old", "peer": "alice"}, "config:app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), opts, "--token=abc", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8000 || c.Token != "abc" || c.Peer != "bob" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	expected := "port = 8000 (env:APP_PORT, over config:app.json)\n" +
+		"token = *** (flag, over config:app.json)\n" +
+		"peer = bob (flag, over config:app.json)\n"
+	if got := cmd.Explain(); got != expected {
+		t.Fatalf("unexpected explanation:\n%s", got)
+	}
+
+	// config files can be ranked above the environment
+	if err := cmd.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Precedence = []string{SourceFlag, SourceConfig, SourceEnv}
+	if err := cmd.SetFromMap(map[string]string{"port": "7000"}, "config:app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), opts, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 7000 || cmd.Source("port") != "config:app.json" || cmd.Shadowed["port"][0] != "env:APP_PORT" {
+		t.Fatalf("unexpected port: %d from %s", c.Port, cmd.Source("port"))
+	}
+}
+
+type MetricsOptions struct {
+	Port uint16 `ask:"--port" help:"Metrics port"`
+}
+
+type ToggleCmd struct {
+	Metrics MetricsOptions `ask:".metrics" toggle:"--metrics"`
+	Tracing struct {
+		Enabled bool `ask:"--enabled" help:"Enable tracing"`
+		Opts    struct {
+			Addr string `ask:"--addr"`
+		} `ask:".opts" toggle:"--enabled"`
+	} `ask:".tracing"`
+}
+
+func (c *ToggleCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestGroupToggle(t *testing.T) {
+	var c ToggleCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "# metrics\n(disabled, enable with --metrics)") ||
+		strings.Contains(usage, "--metrics.port") {
+		t.Fatalf("expected collapsed metrics group, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--metrics.port=9000"); err == nil || err.Error() != "flag metrics.port is disabled, enable it with --metrics" {
+		t.Fatalf("expected disabled error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--tracing.opts.addr=foo"); err == nil || !strings.Contains(err.Error(), "--tracing.enabled") {
+		t.Fatalf("expected disabled error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--metrics.port=9000", "--metrics", "--tracing.enabled", "--tracing.opts.addr=foo"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Metrics.Port != 9000 || !c.Tracing.Enabled || c.Tracing.Opts.Addr != "foo" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "--metrics.port") {
+		t.Fatalf("expected enabled metrics group, got: %s", usage)
+	}
+	if _, err := Load(&struct {
+		Metrics string         `ask:"--metrics"`
+		Opts    MetricsOptions `ask:".opts" toggle:"--metrics"`
+	}{}); err == nil || !strings.Contains(err.Error(), "not a bool flag") {
+		t.Fatalf("expected error for a toggle that is not a bool flag, got: %v", err)
+	}
+}
+
+type UpstreamOptions struct {
+	Addr   string `ask:"--addr" help:"Upstream address"`
+	Weight uint8  `ask:"--weight" help:"Upstream weight"`
+}
+
+func (o *UpstreamOptions) Default() {
+	o.Weight = 1
+}
+
+type IndexedCmd struct {
+	Upstreams []UpstreamOptions `ask:".upstream" help:"Upstream servers"`
+}
+
+func (c *IndexedCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestIndexedGroup(t *testing.T) {
+	var c IndexedCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "--upstream.<index>.addr") {
+		t.Fatalf("expected element template in usage, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"--upstream.1.addr=b", "--upstream.0.addr=a", "--upstream.0.weight=3"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Upstreams) != 2 || c.Upstreams[0] != (UpstreamOptions{"a", 3}) || c.Upstreams[1] != (UpstreamOptions{"b", 1}) {
+		t.Fatalf("unexpected upstreams: %+v", c.Upstreams)
+	}
+	if err := cmd.SetFromMap(map[string]string{"upstream.2.addr": "c"}, "config"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Upstreams) != 3 || c.Upstreams[0] != (UpstreamOptions{"a", 3}) || c.Upstreams[2] != (UpstreamOptions{"c", 1}) {
+		t.Fatalf("unexpected upstreams: %+v", c.Upstreams)
+	}
+}
+
+type ServerMode struct {
+	Port uint16 `ask:"--port"`
+	Ran  bool
+}
+
+func (c *ServerMode) Run(ctx context.Context, args ...string) error {
+	c.Ran = true
+	return nil
+}
+
+type ClientMode struct {
+	Addr string `ask:"--addr"`
+	Ran  bool
+}
+
+func (c *ClientMode) Run(ctx context.Context, args ...string) error {
+	c.Ran = true
+	return nil
+}
+
+type UnionCmd struct {
+	Mode   string     `ask:"--mode" discriminator:"true" help:"Mode to run in"`
+	Server ServerMode `ask:".server" union:"server"`
+	Client ClientMode `ask:".client" union:"client"`
+}
+
+func TestUnion(t *testing.T) {
+	var c UnionCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--mode=client", "--client.addr=foo"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Client.Ran || c.Server.Ran || c.Client.Addr != "foo" {
+		t.Fatalf("expected client to run: %+v", c)
+	}
+	for _, args := range [][]string{{"--mode=other"}, {"--client.addr=foo"}} {
+		if err := cmd.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmd.Execute(context.Background(), &ExecutionOptions{ParseOnly: true}, args...); !IsUsageErr(err) || !strings.Contains(err.Error(), "--mode must be one of: server, client") {
+			t.Fatalf("%v: expected usage error at parse time, got: %v", args, err)
+		}
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--mode=server", "--client.addr=bar"); !IsUsageErr(err) || err.Error() != "flag client.addr is of client, but --mode selects server" {
+		t.Fatalf("expected usage error for flag of inactive member, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		Server ServerMode `ask:".server" union:"server"`
+	}{}); err == nil {
+		t.Fatal("expected error for missing discriminator")
+	}
+}
+
+func TestFlagOrder(t *testing.T) {
+	cmd, err := Load(&Connect{ActorState: &ActorState{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := func(flags []PrefixedFlag) string {
+		var out []string
+		for _, pf := range flags {
+			out = append(out, pf.Path)
+		}
+		return strings.Join(out, " ")
+	}
+	all := cmd.All("")
+	if p := paths(all); p != "addr port foobar hex peer.tag peer.id misc.data misc.awesome misc.bad fork.digests fork.more" {
+		t.Fatalf("unexpected declaration order: %s", p)
+	}
+	SortFlags(all, OrderArgsFirst)
+	if p := paths(all); p != "peer.id misc.data fork.more addr port foobar hex peer.tag misc.awesome misc.bad fork.digests" {
+		t.Fatalf("unexpected args-first order: %s", p)
+	}
+	SortFlags(all, OrderByPath)
+	if p := paths(all); p != "addr foobar fork.digests fork.more hex misc.awesome misc.bad misc.data peer.id peer.tag port" {
+		t.Fatalf("unexpected path order: %s", p)
+	}
+}
+
+type LegacyCmd struct {
+	Name string `cli:"--name"`
+	Port uint16 `flag:"--port" cli:"--legacy-port"`
+	Sub  struct {
+		Addr string `cli:"--addr"`
+	} `cli:".sub"`
+}
+
+func (c *LegacyCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestLoadOptionsTagName(t *testing.T) {
+	var c LegacyCmd
+	cmd, err := LoadWithOptions(&c, &LoadOptions{TagName: "flag", LegacyTagNames: []string{"cli"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--name=foo", "--port=123", "--sub.addr=bar"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "foo" || c.Port != 123 || c.Sub.Addr != "bar" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+}
+
+type DefaultTagCmd struct {
+	Port    uint16        `ask:"--port" default:"9000"`
+	Timeout time.Duration `ask:"--timeout" default:"5s"`
+	Name    string        `ask:"--name" default:"node" transform:"upper"`
+	Peers   []string      `ask:"--peers" default:"a,b"`
+}
+
+func (c *DefaultTagCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDefaultTag(t *testing.T) {
+	c := DefaultTagCmd{Port: 8000}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// explicit values win over the tag
+	if c.Port != 8000 || c.Timeout != 5*time.Second || c.Name != "NODE" || len(c.Peers) != 2 {
+		t.Fatalf("unexpected defaults: %+v", c)
+	}
+	if pf, _ := cmd.Lookup("timeout"); pf.Default != "5s" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := Load(&struct {
+		Port uint16 `ask:"--port" default:"x"`
+	}{}); err == nil || !strings.Contains(err.Error(), "invalid default") {
+		t.Fatalf("expected invalid default error, got: %v", err)
+	}
+}
+
+type KongLogging struct {
+	LogLevel string `enum:"debug,info,warn" default:"info" help:"Log level"`
+}
+
+type KongCmd struct {
+	KongLogging `embed:""`
+	HTTPAddr    string `short:"a" help:"Address to listen on"`
+	Peers       uint64 `name:"max-peers" default:"10"`
+	Target      string `arg:"" help:"Target to connect to"`
+	Extra       string `arg:"" optional:""`
+	Ignored     string `kong:"-"`
+	internal    string
+}
+
+func (c *KongCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestKongTags(t *testing.T) {
+	var c KongCmd
+	cmd, err := LoadWithOptions(&c, &LoadOptions{Kong: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.LogLevel != "info" || c.Peers != 10 {
+		t.Fatalf("expected defaults, got: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--http-addr=foo", "--log-level=debug", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if c.HTTPAddr != "foo" || c.LogLevel != "debug" || c.Target != "bar" || c.Extra != "" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, ok := cmd.Lookup("ignored"); ok {
+		t.Fatal("expected ignored field to be skipped")
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--log-level=trace", "bar"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error for value outside of enum, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil); !IsUsageErr(err) {
+		t.Fatalf("expected usage error for missing arg, got: %v", err)
+	}
+}
+
+type HelpKeyCmd struct {
+	Addr string `ask:"--addr" helpkey:"connect.addr"`
+	Port uint16 `ask:"--port" helpkey:"connect.port" help:"Port fallback"`
+	Peer struct {
+		ID string `ask:"--id"`
+	} `ask:".peer" helpkey:"connect.peer"`
+}
+
+func (c *HelpKeyCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestHelpCatalog(t *testing.T) {
+	messages, err := ReadHelpMessages(strings.NewReader(`{"connect.addr": "Address to connect to", "connect.peer": "Peer options"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := LoadWithOptions(&HelpKeyCmd{}, &LoadOptions{HelpCatalog: messages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := cmd.Usage(false)
+	for _, help := range []string{"Address to connect to", "Port fallback", "Peer options"} {
+		if !strings.Contains(usage, help) {
+			t.Fatalf("expected %q in usage, got: %s", help, usage)
+		}
+	}
+	if _, err := LoadWithOptions(&HelpKeyCmd{}, &LoadOptions{HelpCatalog: HelpMessages{}}); err == nil {
+		t.Fatal("expected error for help key without fallback")
+	}
+	// without a catalog, help keys are not required to be found
+	if _, err := Load(&HelpKeyCmd{}); err != nil {
+		t.Fatalf("expected help keys to be optional without a catalog, got: %v", err)
+	}
+}
+
+type DefaultsCmd struct {
+	Port    uint16 `ask:"--port" help:"Port"`
+	Verbose bool   `ask:"--verbose" help:"Verbose"`
+	Ratio   uint8  `ask:"--ratio" help:"Ratio"`
+	Token   string `ask:"--token" help:"Token" showdefault:"false"`
+	Limit   *int   `ask:"--limit" help:"Limit"`
+}
+
+func (c *DefaultsCmd) Default() {
+	c.Ratio = 3
+	c.Token = "abc"
+}
+
+func TestHideDefaults(t *testing.T) {
+	var c DefaultsCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := c.Limit
+	usage := cmd.Usage(false)
+	if !strings.Contains(usage, "Port (default: 0)") || strings.Contains(usage, "default: abc") {
+		t.Fatalf("unexpected defaults in usage: %s", usage)
+	}
+	usage = cmd.Usage(false, WithoutZeroDefaults())
+	if strings.Contains(usage, "default: 0") || strings.Contains(usage, "default: false") || !strings.Contains(usage, "default: 3") {
+		t.Fatalf("expected zero defaults to be omitted: %s", usage)
+	}
+	if c.Limit != limit {
+		t.Fatal("expected rendering the usage to leave the fields alone")
+	}
+}
+
+// LegacySyntaxCmd parses flags like "/port:9000", and sub-commands inherit the syntax.
+type LegacySyntaxCmd struct {
+	Port uint16 `ask:"--port"`
+	Name string `ask:"<name>"`
+	Ran  bool
+}
+
+func (c *LegacySyntaxCmd) ParseArgs(args []string) (flags map[string]string, remaining []string, err error) {
+	flags = make(map[string]string)
+	for _, a := range args {
+		if a == "/?" {
+			return nil, nil, HelpErr
+		}
+		if strings.HasPrefix(a, "/") {
+			kv := strings.SplitN(a[1:], ":", 2)
+			if len(kv) != 2 {
+				return nil, nil, fmt.Errorf("bad flag: %s", a)
+			}
+			flags[kv[0]] = kv[1]
+		} else {
+			remaining = append(remaining, a)
+		}
+	}
+	return flags, remaining, nil
+}
+
+func (c *LegacySyntaxCmd) Run(ctx context.Context, args ...string) error {
+	c.Ran = true
+	return nil
+}
+
+type LegacySyntaxRoot struct {
+	LegacySyntaxCmd
+	Sub *ColonCmd
+}
+
+func (c *LegacySyntaxRoot) Cmd(route string) (cmd interface{}, err error) {
+	if route == "sub" {
+		return c.Sub, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+func TestArgParser(t *testing.T) {
+	var c LegacySyntaxCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "/port:9000", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 9000 || c.Name != "foo" || !c.Ran {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "/?"); err != HelpErr {
+		t.Fatalf("expected help, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "/unknown:1", "foo"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	root := LegacySyntaxRoot{Sub: &ColonCmd{}}
+	rootCmd, err := Load(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rootCmd.Execute(context.Background(), nil, "sub", "/addr:foo"); err != nil {
+		t.Fatal(err)
+	}
+	if root.Sub.Addr != "foo" {
+		t.Fatalf("expected sub-command to inherit the parser, got: %+v", root.Sub)
+	}
+}
+
+type ExecCmd struct {
+	Image   string `ask:"<image>"`
+	Verbose bool   `ask:"--verbose"`
+	Args    []string
+}
+
+func (c *ExecCmd) RawArgs() {}
+
+func (c *ExecCmd) Run(ctx context.Context, args ...string) error {
+	c.Args = args
+	return nil
+}
+
+func TestRawArgs(t *testing.T) {
+	var c ExecCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "alpine", "--verbose", "ls", "-la", "--", "--help"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Image != "alpine" || c.Verbose || strings.Join(c.Args, " ") != "--verbose ls -la -- --help" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--help"); err != nil {
+		t.Fatalf("expected --help to be bound as positional arg, got: %v", err)
+	}
+	if c.Image != "--help" {
+		t.Fatalf("unexpected image: %q", c.Image)
+	}
+}
+
+type EpilogueRoot struct{}
+
+func (c *EpilogueRoot) Cmd(route string) (cmd interface{}, err error) {
+	if route == "peer" {
+		return &Peer{ActorState: &ActorState{}}, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+func (c *EpilogueRoot) Routes() []string {
+	return []string{"peer"}
+}
+
+func (c *EpilogueRoot) Epilogue() string {
+	return "Report bugs at `https://example.com/issues`"
+}
+
+func TestEpilogueFooter(t *testing.T) {
+	cmd, err := Load(&EpilogueRoot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer := WithFooter("app", "{{if .SubCommands}}Use '{{.Command}} <command> --help' for more information.{{end}}")
+	usage := cmd.Usage(false, footer)
+	if !strings.HasSuffix(usage, "Report bugs at https://example.com/issues\n\nUse 'app <command> --help' for more information.\n") {
+		t.Fatalf("unexpected usage end: %s", usage)
+	}
+	sub, err := cmd.Execute(context.Background(), nil, "peer", "--help")
+	if err != HelpErr {
+		t.Fatalf("expected help, got: %v", err)
+	}
+	if usage := sub.Usage(false, footer); !strings.HasSuffix(usage, "Use 'app peer <command> --help' for more information.\n") {
+		t.Fatalf("expected route in footer: %s", usage)
+	}
+}
+
+type ResetUpstream struct {
+	Addr        string `ask:"--addr"`
+	AddrChanged bool   `changed:"addr"`
+}
+
+type ResetCmd struct {
+	Port        uint16          `ask:"--port"`
+	PortChanged bool            `changed:"port"`
+	Upstreams   []ResetUpstream `ask:".upstream"`
+}
+
+func (c *ResetCmd) Default() {
+	c.Port = 9000
+}
+
+func (c *ResetCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestReset(t *testing.T) {
+	c := ResetCmd{Upstreams: []ResetUpstream{{Addr: "a"}}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markers := func() (n int) {
+		for _, ptrs := range cmd.ChangedMarkers {
+			n += len(ptrs)
+		}
+		return n
+	}
+	if n := markers(); n != 2 {
+		t.Fatalf("expected 2 changed markers, got %d", n)
+	}
+	for i := 0; i < 10; i++ {
+		if err := cmd.Load(&c); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmd.Execute(context.Background(), nil, "--port=1234", "--upstream.0.addr=x", "--upstream.3.addr=y"); err != nil {
+			t.Fatal(err)
+		}
+		if !c.PortChanged || c.Port != 1234 || len(c.Upstreams) != 4 || c.Upstreams[0].Addr != "x" {
+			t.Fatalf("unexpected values: %+v", c)
+		}
+		if err := cmd.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if c.PortChanged || c.Port != 9000 || len(c.Upstreams) != 1 || c.Upstreams[0].Addr != "a" {
+			t.Fatalf("expected defaults after reset: %+v", c)
+		}
+		if cmd.Source("port") != SourceDefault {
+			t.Fatalf("expected source to be reset, got %q", cmd.Source("port"))
+		}
+		if n := markers(); n != 2 {
+			t.Fatalf("expected changed markers to not grow, got %d", n)
+		}
+	}
+}
+
+type IDOptions struct {
+	ID string `ask:"<id>" help:"identifier"`
+}
+
+type DuplicateArgsCmd struct {
+	Peer IDOptions `ask:".peer"`
+	Misc IDOptions `ask:".misc"`
+}
+
+func (c *DuplicateArgsCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDuplicateArgs(t *testing.T) {
+	cmd, err := Load(&DuplicateArgsCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.HasPrefix(usage, "(command) <peer.id> <misc.id>") ||
+		!strings.Contains(usage, "<peer.id>") || !strings.Contains(usage, "<misc.id>") {
+		t.Fatalf("expected prefixed args, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "a"); err == nil || !strings.Contains(err.Error(), "misc.id") {
+		t.Fatalf("expected missing misc.id, got: %v", err)
+	}
+	type Squashed struct {
+		Peer IDOptions `ask:"."`
+		Misc IDOptions `ask:"."`
+	}
+	if _, err := Load(&Squashed{}); err == nil || err.Error() != "<id> and <id> have the same name, declare one of them in a named group or rename it" {
+		t.Fatalf("expected duplicate error, got: %v", err)
+	}
+	type FlagAndArg struct {
+		ID   string    `ask:"--id"`
+		Peer IDOptions `ask:"."`
+	}
+	if _, err := Load(&FlagAndArg{}); err == nil || !strings.Contains(err.Error(), "--id and <id>") {
+		t.Fatalf("expected duplicate error, got: %v", err)
+	}
+}
+
+func TestShorthandCollisions(t *testing.T) {
+	type Verbosity struct {
+		Verbose bool `ask:"--verbose -v"`
+	}
+	for _, tc := range []struct {
+		cmd interface{}
+		msg string
+	}{
+		{&struct {
+			Version bool      `ask:"--v"`
+			Log     Verbosity `ask:".log"`
+		}{}, "--v shadows the shorthand of -v, --log.verbose"},
+		{&struct {
+			Version bool      `ask:"--version -v"`
+			Log     Verbosity `ask:".log"`
+		}{}, "-v, --version and -v, --log.verbose have the same shorthand -v"},
+		{&struct {
+			Version   bool      `ask:"-v"`
+			Verbosity Verbosity `ask:"."`
+		}{}, "-v and -v, --verbose have the same shorthand -v"},
+	} {
+		if _, err := Load(tc.cmd); err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%T: expected %q, got: %v", tc.cmd, tc.msg, err)
+		}
+	}
+	if _, err := Load(&struct {
+		X         bool      `ask:"--x -x"`
+		Y         bool      `ask:"--y"`
+		Verbosity Verbosity `ask:"."`
+	}{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type unexportedOptions struct {
+	retries int `ask:"--retries"`
+}
+
+type UnexportedCmd struct {
+	level   LevelValue        `ask:"--level"`
+	timeout time.Duration     `ask:"--timeout"`
+	opts    unexportedOptions `ask:".opts"`
+	changed bool              `changed:"level"`
+}
+
+func (c *UnexportedCmd) Default() {
+	c.timeout = time.Second
+}
+
+func (c *UnexportedCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestUnexportedFields(t *testing.T) {
+	var c UnexportedCmd
+	cmd, err := LoadWithOptions(&c, &LoadOptions{Unexported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--level=debug", "--timeout=5s", "--opts.retries=3"); err != nil {
+		t.Fatal(err)
+	}
+	if c.level.String() != "debug" || c.timeout != 5*time.Second || c.opts.retries != 3 || !c.changed {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if pf, _ := cmd.Lookup("timeout"); pf.DefaultOrigin != DefaultInit {
+		t.Fatalf("unexpected default origin: %s", pf.DefaultOrigin)
+	}
+}
+
+type SharedStateCmd struct {
+	*ActorState
+	Alias *ActorState
+	Name  string `ask:"--name"`
+	Tags  map[string]string
+}
+
+func (c *SharedStateCmd) Default() {
+	c.HostData = "default"
+	c.Tags["touched"] = "yes"
+}
+
+func (c *SharedStateCmd) Run(ctx context.Context, args ...string) error {
+	c.HostData = c.Name
+	return nil
+}
+
+func TestCopyOption(t *testing.T) {
+	state := &ActorState{HostData: "live"}
+	live := &SharedStateCmd{ActorState: state, Alias: state, Tags: map[string]string{}}
+	cmd, err := LoadWithOptions(live, &LoadOptions{Copy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = cmd.Usage(false)
+	if _, err := cmd.Execute(context.Background(), nil, "--name=copy"); err != nil {
+		t.Fatal(err)
+	}
+	if state.HostData != "live" || len(live.Tags) != 0 {
+		t.Fatalf("live state was mutated: %q %v", state.HostData, live.Tags)
+	}
+	cp, ok := cmd.Target().(*SharedStateCmd)
+	if !ok || cp == live {
+		t.Fatalf("expected a copy, got %v", cmd.Target())
+	}
+	if cp.HostData != "copy" || cp.Alias != cp.ActorState || cp.Tags["touched"] != "yes" {
+		t.Fatalf("unexpected copy: %q %v %v", cp.HostData, cp.Alias == cp.ActorState, cp.Tags)
+	}
+
+	plain, err := Load(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Target() != live || state.HostData != "default" {
+		t.Fatalf("expected the live value to be loaded, got %v", plain.Target())
+	}
+}
+
+type CopyUnexportedCmd struct {
+	opts *unexportedOptions `ask:".opts"`
+	buf  *bytes.Buffer
+}
+
+func (c *CopyUnexportedCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestCopyUnexported(t *testing.T) {
+	live := &CopyUnexportedCmd{opts: &unexportedOptions{retries: 1}, buf: bytes.NewBufferString("live")}
+	cmd, err := LoadWithOptions(live, &LoadOptions{Copy: true, Unexported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--opts.retries=3"); err != nil {
+		t.Fatal(err)
+	}
+	cp := cmd.Target().(*CopyUnexportedCmd)
+	if live.opts.retries != 1 || cp.opts.retries != 3 {
+		t.Fatalf("expected the unexported group to be copied: %d %d", live.opts.retries, cp.opts.retries)
+	}
+	// unexported state that does not declare flags is opaque, and shared
+	if cp.buf != live.buf {
+		t.Fatal("expected unexported state without flags to be copied shallowly")
+	}
+}
@@ -0,0 +1,147 @@
+// Package askcobra bridges cobra commands into ask, to mount legacy cobra commands under an ask command
+// while migrating an application.
+package askcobra
+
+import (
+	"context"
+
+	"github.com/protolambda/ask"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Command wraps a cobra command as ask command: the sub-commands of the cobra command are routes,
+// and the flags of the cobra command are bridged to ask flags.
+//
+// Only the PreRun, Run and PostRun hooks of the cobra command itself run,
+// the persistent hooks of parent commands do not.
+type Command struct {
+	Cobra *cobra.Command
+}
+
+// Wrap wraps a cobra command tree, to return as sub-command from an ask CommandRoute.
+func Wrap(cmd *cobra.Command) *Command {
+	return &Command{Cobra: cmd}
+}
+
+func (c *Command) Help() string {
+	if c.Cobra.Long != "" {
+		return c.Cobra.Long
+	}
+	return c.Cobra.Short
+}
+
+func (c *Command) Cmd(route string) (cmd interface{}, err error) {
+	for _, sub := range c.Cobra.Commands() {
+		if sub.Name() == route || sub.HasAlias(route) {
+			return Wrap(sub), nil
+		}
+	}
+	// a runnable command may take the route as argument instead
+	if c.Cobra.Runnable() {
+		return nil, nil
+	}
+	return nil, ask.UnrecognizedErr
+}
+
+func (c *Command) Routes() []string {
+	var out []string
+	for _, sub := range c.Cobra.Commands() {
+		if sub.IsAvailableCommand() {
+			out = append(out, sub.Name())
+		}
+	}
+	return out
+}
+
+func (c *Command) Run(ctx context.Context, args ...string) error {
+	cmd := c.Cobra
+	if !cmd.Runnable() {
+		return ask.UnrecognizedErr
+	}
+	if err := cmd.ValidateArgs(args); err != nil {
+		return &ask.UsageErr{Err: err}
+	}
+	cmd.SetContext(ctx)
+	if cmd.PreRunE != nil {
+		if err := cmd.PreRunE(cmd, args); err != nil {
+			return err
+		}
+	} else if cmd.PreRun != nil {
+		cmd.PreRun(cmd, args)
+	}
+	if cmd.RunE != nil {
+		if err := cmd.RunE(cmd, args); err != nil {
+			return err
+		}
+	} else {
+		cmd.Run(cmd, args)
+	}
+	if cmd.PostRunE != nil {
+		return cmd.PostRunE(cmd, args)
+	} else if cmd.PostRun != nil {
+		cmd.PostRun(cmd, args)
+	}
+	return nil
+}
+
+// ExtraFlags bridges the local and inherited flags of the cobra command.
+func (c *Command) ExtraFlags() []*ask.Flag {
+	var out []*ask.Flag
+	add := func(fl *pflag.Flag) {
+		out = append(out, bridgeFlag(fl))
+	}
+	c.Cobra.LocalFlags().VisitAll(add)
+	c.Cobra.InheritedFlags().VisitAll(add)
+	return out
+}
+
+func bridgeFlag(fl *pflag.Flag) *ask.Flag {
+	var value ask.TypedValue = &pflagValue{fl: fl}
+	if fl.NoOptDefVal != "" {
+		value = &implicitPflagValue{pflagValue{fl: fl}}
+	}
+	var shorthand uint8
+	if len(fl.Shorthand) == 1 {
+		shorthand = fl.Shorthand[0]
+	}
+	return &ask.Flag{
+		Value:      value,
+		Name:       fl.Name,
+		Shorthand:  shorthand,
+		Help:       fl.Usage,
+		Default:    fl.DefValue,
+		Deprecated: fl.Deprecated,
+		Hidden:     fl.Hidden,
+	}
+}
+
+// pflagValue marks the flag as changed when set, like pflag does, so cobra commands can check it.
+type pflagValue struct {
+	fl *pflag.Flag
+}
+
+func (v *pflagValue) String() string {
+	return v.fl.Value.String()
+}
+
+func (v *pflagValue) Set(s string) error {
+	if err := v.fl.Value.Set(s); err != nil {
+		return err
+	}
+	v.fl.Changed = true
+	return nil
+}
+
+func (v *pflagValue) Type() string {
+	return v.fl.Value.Type()
+}
+
+// implicitPflagValue is a pflag that can be used without value, like a bool flag.
+type implicitPflagValue struct {
+	pflagValue
+}
+
+func (v *implicitPflagValue) Implicit() string {
+	return v.fl.NoOptDefVal
+}
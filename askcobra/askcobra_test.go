@@ -0,0 +1,90 @@
+package askcobra
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ask"
+	"github.com/spf13/cobra"
+)
+
+type Root struct {
+	Legacy *cobra.Command
+}
+
+func (c *Root) Cmd(route string) (cmd interface{}, err error) {
+	if route == "legacy" {
+		return Wrap(c.Legacy), nil
+	}
+	return nil, ask.UnrecognizedErr
+}
+
+func TestWrap(t *testing.T) {
+	var got string
+	legacy := &cobra.Command{Use: "legacy"}
+	legacy.PersistentFlags().String("network", "mainnet", "network to use")
+	serve := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve things",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			network, _ := cmd.Flags().GetString("network")
+			got = strings.Join(append([]string{network}, args...), " ")
+			if verbose && cmd.Flags().Changed("port") {
+				got += " " + cmd.Flags().Lookup("port").Value.String()
+			}
+			return nil
+		},
+	}
+	serve.Flags().Uint16P("port", "p", 8080, "port to serve on")
+	serve.Flags().BoolP("verbose", "v", false, "verbose output")
+	legacy.AddCommand(serve)
+
+	cmd, err := ask.Load(&Root{Legacy: legacy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"legacy", "serve", "--port=9000", "--verbose", "--network=testnet", "extra"); err != nil {
+		t.Fatal(err)
+	}
+	if got != "testnet extra 9000" {
+		t.Fatalf("unexpected result: %q", got)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "legacy", "serve", "a", "b"); !ask.IsUsageErr(err) {
+		t.Fatalf("expected usage error for too many args, got: %v", err)
+	}
+	serveCmd, err := ask.Load(Wrap(serve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := serveCmd.Usage(false); !strings.Contains(usage, "--port") || !strings.Contains(usage, "default: 8080") {
+		t.Fatalf("expected bridged flags in usage, got: %s", usage)
+	}
+}
+
+type Embedded struct {
+	Legacy Command `ask:"."`
+}
+
+func (c *Embedded) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestExtraFlagsOfStruct(t *testing.T) {
+	legacy := &cobra.Command{Use: "legacy"}
+	legacy.Flags().String("network", "mainnet", "network to use")
+	c := &Embedded{Legacy: Command{Cobra: legacy}}
+	cmd, err := ask.Load(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--network=testnet"); err != nil {
+		t.Fatal(err)
+	}
+	if got := legacy.Flags().Lookup("network").Value.String(); got != "testnet" {
+		t.Fatalf("unexpected network: %q", got)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/protolambda/ask/askcobra
+
+go 1.21
+
+require (
+	github.com/protolambda/ask v0.0.0
+	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
+
+replace github.com/protolambda/ask => ../
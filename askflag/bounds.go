@@ -0,0 +1,48 @@
+package askflag
+
+import (
+	"fmt"
+	"time"
+)
+
+// DurationBounds restricts a duration flag to a range, and optionally to multiples of a step,
+// see the `min`, `max` and `multipleof` struct tags. Nil bounds are not checked.
+type DurationBounds struct {
+	*DurationValue
+	Min, Max   *time.Duration
+	MultipleOf time.Duration
+}
+
+func (b *DurationBounds) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if err := b.check(v); err != nil {
+		return err
+	}
+	*b.DurationValue = DurationValue(v)
+	return nil
+}
+
+// Canonical renders the duration, if it is within the bounds: e.g. a zero default may not be.
+func (b *DurationBounds) Canonical() (string, error) {
+	if err := b.check(time.Duration(*b.DurationValue)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// check checks the duration against the bounds.
+func (b *DurationBounds) check(v time.Duration) error {
+	if b.Min != nil && v < *b.Min {
+		return fmt.Errorf("duration %s is less than the minimum of %s", v, *b.Min)
+	}
+	if b.Max != nil && v > *b.Max {
+		return fmt.Errorf("duration %s is more than the maximum of %s", v, *b.Max)
+	}
+	if b.MultipleOf != 0 && v%b.MultipleOf != 0 {
+		return fmt.Errorf("duration %s is not a multiple of %s", v, b.MultipleOf)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package askflag
+
+import (
+	"net"
+	"strings"
+)
+
+// boolValues are the completions of boolean values.
+var boolValues = []string{"true", "false"}
+
+// Complete lists true and false.
+func (b *BoolValue) Complete(partial string) []string {
+	return boolValues
+}
+
+// Complete lists true and false.
+func (b *TriState) Complete(partial string) []string {
+	return boolValues
+}
+
+// Complete lists the log levels.
+func (l *LevelValue) Complete(partial string) []string {
+	return []string{"trace", "debug", "info", "warn", "error"}
+}
+
+// Complete lists the partial number with the common duration units, e.g. "10ms", "10s", "10m" and "10h" for "10".
+func (d *DurationValue) Complete(partial string) []string {
+	number := strings.TrimRight(partial, "nsuµmh")
+	if number == "" {
+		number = "1"
+	}
+	if c := number[len(number)-1]; c < '0' || c > '9' {
+		return nil
+	}
+	return []string{number + "ms", number + "s", number + "m", number + "h"}
+}
+
+// Complete lists the IP addresses of the network interfaces.
+func (i *IPValue) Complete(partial string) []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			out = append(out, n.IP.String())
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package askflag
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FileModeValue is a file permission, in octal like "0644" or "644", or symbolic like "rw-r--r--".
+// Symbolic modes may start with the "-" of a regular file, like ls prints them.
+// The mode is rendered in octal. Only the permission bits can be set.
+type FileModeValue fs.FileMode
+
+func (m *FileModeValue) Set(s string) error {
+	if len(s) == 10 && s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) == 9 && strings.Trim(s, "rwx-") == "" {
+		var v fs.FileMode
+		for i := 0; i < 9; i++ {
+			switch s[i] {
+			case "rwxrwxrwx"[i]:
+				v |= 1 << (8 - i)
+			case '-':
+			default:
+				return fmt.Errorf("invalid file mode %q, expected %q at position %d", s, "rwxrwxrwx"[i], i+1)
+			}
+		}
+		*m = FileModeValue(v)
+		return nil
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || v > uint64(fs.ModePerm) {
+		return fmt.Errorf("invalid file mode %q, expected octal permissions like 0644, or symbolic like rw-r--r--", s)
+	}
+	*m = FileModeValue(v)
+	return nil
+}
+
+func (m *FileModeValue) Type() string {
+	return "filemode"
+}
+
+func (m *FileModeValue) String() string {
+	return fmt.Sprintf("%#04o", uint32(*m))
+}
+
+// FileMode returns the mode, e.g. for os.WriteFile.
+func (m *FileModeValue) FileMode() fs.FileMode {
+	return fs.FileMode(*m)
+}
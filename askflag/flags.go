@@ -0,0 +1,1339 @@
+// Package askflag implements the flag value types of ask,
+// for use without the command loading and parsing of the ask package.
+package askflag
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DurationValue time.Duration
+
+func (d *DurationValue) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	*d = DurationValue(v)
+	return err
+}
+
+func (d *DurationValue) Type() string {
+	return "duration"
+}
+
+func (d *DurationValue) String() string {
+	return (*time.Duration)(d).String()
+}
+
+type IPValue net.IP
+
+func (i *IPValue) String() string {
+	return net.IP(*i).String()
+}
+
+// Set parses the IP address. An empty string unsets the address.
+func (i *IPValue) Set(s string) error {
+	if s == "" {
+		*i = nil
+		return nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return fmt.Errorf("failed to parse IP: %q", s)
+	}
+	*i = IPValue(ip)
+	return nil
+}
+
+func (i *IPValue) Type() string {
+	return "ip"
+}
+
+// Canonical renders an unset address as empty string, instead of "<nil>".
+func (i *IPValue) Canonical() (string, error) {
+	if len(*i) == 0 {
+		return "", nil
+	}
+	return i.String(), nil
+}
+
+type IPNetValue net.IPNet
+
+func (ipnet IPNetValue) String() string {
+	n := net.IPNet(ipnet)
+	return n.String()
+}
+
+// Set parses the CIDR notation of the network. An empty string unsets the network.
+func (ipnet *IPNetValue) Set(s string) error {
+	if s == "" {
+		*ipnet = IPNetValue{}
+		return nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	*ipnet = IPNetValue(*n)
+	return nil
+}
+
+func (*IPNetValue) Type() string {
+	return "ipNet"
+}
+
+// Canonical renders an unset network as empty string, instead of "<nil>".
+func (ipnet *IPNetValue) Canonical() (string, error) {
+	if len(ipnet.IP) == 0 {
+		return "", nil
+	}
+	return ipnet.String(), nil
+}
+
+// TCPAddrValue is a TCP address, formatted as host:port. The host must be an IP address, or empty for all interfaces.
+type TCPAddrValue net.TCPAddr
+
+func (a *TCPAddrValue) String() string {
+	return (*net.TCPAddr)(a).String()
+}
+
+func (a *TCPAddrValue) Set(s string) error {
+	ip, port, zone, err := parseHostPort(s)
+	if err != nil {
+		return err
+	}
+	*a = TCPAddrValue{IP: ip, Port: port, Zone: zone}
+	return nil
+}
+
+func (*TCPAddrValue) Type() string {
+	return "tcpAddr"
+}
+
+// UDPAddrValue is a UDP address, formatted as host:port. The host must be an IP address, or empty for all interfaces.
+type UDPAddrValue net.UDPAddr
+
+func (a *UDPAddrValue) String() string {
+	return (*net.UDPAddr)(a).String()
+}
+
+func (a *UDPAddrValue) Set(s string) error {
+	ip, port, zone, err := parseHostPort(s)
+	if err != nil {
+		return err
+	}
+	*a = UDPAddrValue{IP: ip, Port: port, Zone: zone}
+	return nil
+}
+
+func (*UDPAddrValue) Type() string {
+	return "udpAddr"
+}
+
+// parseHostPort parses a host:port address, without resolving host names.
+func parseHostPort(s string) (ip net.IP, port int, zone string, err error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	p, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("invalid port %q", portStr)
+	}
+	if host == "" {
+		return nil, int(p), "", nil
+	}
+	if i := strings.LastIndexByte(host, '%'); i >= 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+	ip = net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, "", fmt.Errorf("host %q is not an IP address", host)
+	}
+	return ip, int(p), zone, nil
+}
+
+type IPMaskValue net.IPMask
+
+func (i *IPMaskValue) String() string {
+	return net.IPMask(*i).String()
+}
+
+// Set parses the mask. An empty string unsets the mask.
+func (i *IPMaskValue) Set(s string) error {
+	if s == "" {
+		*i = nil
+		return nil
+	}
+	ip := ParseIPv4Mask(s)
+	if ip == nil {
+		return fmt.Errorf("failed to parse IP mask: %q", s)
+	}
+	*i = IPMaskValue(ip)
+	return nil
+}
+
+func (i *IPMaskValue) Type() string {
+	return "ipMask"
+}
+
+// Canonical renders an unset mask as empty string, instead of "<nil>".
+func (i *IPMaskValue) Canonical() (string, error) {
+	if len(*i) == 0 {
+		return "", nil
+	}
+	return i.String(), nil
+}
+
+// ParseIPv4Mask written in IP form (e.g. 255.255.255.0).
+// This function should really belong to the net package.
+func ParseIPv4Mask(s string) net.IPMask {
+	mask := net.ParseIP(s)
+	if mask == nil {
+		if len(s) != 8 {
+			return nil
+		}
+		// net.IPMask.String() actually outputs things like ffffff00
+		// so write a horrible parser for that as well  :-(
+		m := []int{}
+		for i := 0; i < 4; i++ {
+			b := "0x" + s[2*i:2*i+2]
+			d, err := strconv.ParseInt(b, 0, 0)
+			if err != nil {
+				return nil
+			}
+			m = append(m, int(d))
+		}
+		s := fmt.Sprintf("%d.%d.%d.%d", m[0], m[1], m[2], m[3])
+		mask = net.ParseIP(s)
+		if mask == nil {
+			return nil
+		}
+	}
+	return net.IPv4Mask(mask[12], mask[13], mask[14], mask[15])
+}
+
+type UintValue uint
+
+func (i *UintValue) Set(s string) error {
+	v, err := parseUint(s, strconv.IntSize, "uint")
+	if err != nil {
+		return err
+	}
+	*i = UintValue(v)
+	return nil
+}
+
+func (i *UintValue) Type() string {
+	return "uint"
+}
+
+func (i *UintValue) String() string {
+	return strconv.FormatUint(uint64(*i), 10)
+}
+
+type Uint8Value uint8
+
+func (i *Uint8Value) Set(s string) error {
+	v, err := parseUint(s, 8, "uint8")
+	if err != nil {
+		return err
+	}
+	*i = Uint8Value(v)
+	return nil
+}
+
+func (i *Uint8Value) Type() string {
+	return "uint8"
+}
+
+func (i *Uint8Value) String() string {
+	return strconv.FormatUint(uint64(*i), 10)
+}
+
+type Uint16Value uint16
+
+func (i *Uint16Value) Set(s string) error {
+	v, err := parseUint(s, 16, "uint16")
+	if err != nil {
+		return err
+	}
+	*i = Uint16Value(v)
+	return nil
+}
+
+func (i *Uint16Value) Type() string {
+	return "uint16"
+}
+
+func (i *Uint16Value) String() string {
+	return strconv.FormatUint(uint64(*i), 10)
+}
+
+type Uint32Value uint32
+
+func (i *Uint32Value) Set(s string) error {
+	v, err := parseUint(s, 32, "uint32")
+	if err != nil {
+		return err
+	}
+	*i = Uint32Value(v)
+	return nil
+}
+
+func (i *Uint32Value) Type() string {
+	return "uint32"
+}
+
+func (i *Uint32Value) String() string {
+	return strconv.FormatUint(uint64(*i), 10)
+}
+
+type Uint64Value uint64
+
+func (i *Uint64Value) Set(s string) error {
+	v, err := parseUint(s, 64, "uint64")
+	if err != nil {
+		return err
+	}
+	*i = Uint64Value(v)
+	return nil
+}
+
+func (i *Uint64Value) Type() string {
+	return "uint64"
+}
+
+func (i *Uint64Value) String() string {
+	return strconv.FormatUint(uint64(*i), 10)
+}
+
+type IntValue int
+
+func (i *IntValue) Set(s string) error {
+	v, err := parseInt(s, strconv.IntSize, "int")
+	if err != nil {
+		return err
+	}
+	*i = IntValue(v)
+	return nil
+}
+
+func (i *IntValue) Type() string {
+	return "int"
+}
+
+func (i *IntValue) String() string {
+	return strconv.Itoa(int(*i))
+}
+
+type Int8Value int8
+
+func (i *Int8Value) Set(s string) error {
+	v, err := parseInt(s, 8, "int8")
+	if err != nil {
+		return err
+	}
+	*i = Int8Value(v)
+	return nil
+}
+
+func (i *Int8Value) Type() string {
+	return "int8"
+}
+
+func (i *Int8Value) String() string {
+	return strconv.FormatInt(int64(*i), 10)
+}
+
+type Int16Value int16
+
+func (i *Int16Value) Set(s string) error {
+	v, err := parseInt(s, 16, "int16")
+	if err != nil {
+		return err
+	}
+	*i = Int16Value(v)
+	return nil
+}
+
+func (i *Int16Value) Type() string {
+	return "int16"
+}
+
+func (i *Int16Value) String() string {
+	return strconv.FormatInt(int64(*i), 10)
+}
+
+type Int32Value int32
+
+func (i *Int32Value) Set(s string) error {
+	v, err := parseInt(s, 32, "int32")
+	if err != nil {
+		return err
+	}
+	*i = Int32Value(v)
+	return nil
+}
+
+func (i *Int32Value) Type() string {
+	return "int32"
+}
+
+func (i *Int32Value) String() string {
+	return strconv.FormatInt(int64(*i), 10)
+}
+
+type Int64Value int64
+
+func (i *Int64Value) Set(s string) error {
+	v, err := parseInt(s, 64, "int64")
+	if err != nil {
+		return err
+	}
+	*i = Int64Value(v)
+	return nil
+}
+
+func (i *Int64Value) Type() string {
+	return "int64"
+}
+
+func (i *Int64Value) String() string {
+	return strconv.FormatInt(int64(*i), 10)
+}
+
+type StringValue string
+
+func (s *StringValue) Set(val string) error {
+	*s = StringValue(val)
+	return nil
+}
+func (s *StringValue) Type() string {
+	return "string"
+}
+
+func (s *StringValue) String() string {
+	return string(*s)
+}
+
+type BoolValue bool
+
+func (b *BoolValue) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	*b = BoolValue(v)
+	return err
+}
+
+func (b *BoolValue) Type() string {
+	return "bool"
+}
+
+func (b *BoolValue) String() string {
+	return strconv.FormatBool(bool(*b))
+}
+
+func (b *BoolValue) Implicit() string {
+	return "true"
+}
+
+// CountValue is an int flag that counts how often it is used without value, e.g. `-vvv` or `--verbose --verbose`.
+// An explicit value, like `--verbose=2`, sets the count. Use the `format:"count"` struct tag for int fields.
+type CountValue int
+
+func (c *CountValue) Set(s string) error {
+	v, err := parseInt(s, strconv.IntSize, "int")
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("count cannot be negative: %d", v)
+	}
+	*c = CountValue(v)
+	return nil
+}
+
+func (c *CountValue) Type() string {
+	return "count"
+}
+
+func (c *CountValue) String() string {
+	return strconv.Itoa(int(*c))
+}
+
+// Implicit increments the count.
+func (c *CountValue) Implicit() string {
+	return strconv.Itoa(int(*c) + 1)
+}
+
+// TriState is a boolean flag that tells an explicit false apart from a flag that was not provided,
+// e.g. to only override a setting of a config file if the flag is explicitly set.
+type TriState uint8
+
+const (
+	TriStateUnset TriState = iota
+	TriStateFalse
+	TriStateTrue
+)
+
+// Set parses the boolean. An empty string unsets the value.
+func (b *TriState) Set(s string) error {
+	if s == "" {
+		*b = TriStateUnset
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if v {
+		*b = TriStateTrue
+	} else {
+		*b = TriStateFalse
+	}
+	return nil
+}
+
+func (b *TriState) Type() string {
+	return "bool"
+}
+
+// String returns an empty string if unset.
+func (b *TriState) String() string {
+	if !b.IsSet() {
+		return ""
+	}
+	return strconv.FormatBool(b.Bool())
+}
+
+func (b *TriState) Implicit() string {
+	return "true"
+}
+
+// IsSet returns true if the value was explicitly set to true or false.
+func (b TriState) IsSet() bool {
+	return b != TriStateUnset
+}
+
+// Bool returns true if the value was set to true, false otherwise.
+func (b TriState) Bool() bool {
+	return b == TriStateTrue
+}
+
+type Float32Value float32
+
+func (f *Float32Value) Set(s string) error {
+	v, err := parseFloat(s, 32, "float32")
+	if err != nil {
+		return err
+	}
+	*f = Float32Value(v)
+	return nil
+}
+
+func (f *Float32Value) Type() string {
+	return "float32"
+}
+
+func (f *Float32Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+type Float64Value float64
+
+func (f *Float64Value) Set(s string) error {
+	v, err := parseFloat(s, 64, "float64")
+	if err != nil {
+		return err
+	}
+	*f = Float64Value(v)
+	return nil
+}
+
+func (f *Float64Value) Type() string {
+	return "float64"
+}
+
+func (f *Float64Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 64)
+}
+
+type DurationSliceValue []time.Duration
+
+func (s *DurationSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]time.Duration, len(ss))
+	for i, d := range ss {
+		var err error
+		out[i], err = time.ParseDuration(d)
+		if err != nil {
+			return err
+		}
+	}
+	*s = out
+	return nil
+}
+
+func (s *DurationSliceValue) Type() string {
+	return "durationSlice"
+}
+
+func (s *DurationSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = d.String()
+	}
+	return strings.Join(out, ",")
+}
+
+type IPSliceValue []net.IP
+
+func (s *IPSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]net.IP, len(ss))
+	for i, d := range ss {
+		out[i] = net.ParseIP(d)
+		if out[i] == nil {
+			return fmt.Errorf("invalid string being converted to IP address: %s", d)
+		}
+	}
+	*s = out
+	return nil
+}
+
+func (s *IPSliceValue) Type() string {
+	return "ipSlice"
+}
+
+func (s *IPSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = d.String()
+	}
+	return strings.Join(out, ",")
+}
+
+type IPNetSliceValue []net.IPNet
+
+func (s *IPNetSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]net.IPNet, len(ss))
+	for i, d := range ss {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(d))
+		if err != nil {
+			return err
+		}
+		out[i] = *n
+	}
+	*s = out
+	return nil
+}
+
+func (s *IPNetSliceValue) Type() string {
+	return "ipNetSlice"
+}
+
+func (s *IPNetSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = d.String()
+	}
+	return strings.Join(out, ",")
+}
+
+type Uint64SliceValue []uint64
+
+func (s *Uint64SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]uint64, len(ss))
+	for i, d := range ss {
+		v, err := parseUint(d, 64, "uint64")
+		if err != nil {
+			return err
+		}
+		out[i] = v
+	}
+	*s = out
+	return nil
+}
+
+func (s *Uint64SliceValue) Type() string {
+	return "uint64Slice"
+}
+
+func (s *Uint64SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Uint32SliceValue []uint32
+
+func (s *Uint32SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]uint32, len(ss))
+	for i, d := range ss {
+		v, err := parseUint(d, 32, "uint32")
+		if err != nil {
+			return err
+		}
+		out[i] = uint32(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Uint32SliceValue) Type() string {
+	return "uint32Slice"
+}
+
+func (s *Uint32SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Uint16SliceValue []uint16
+
+func (s *Uint16SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]uint16, len(ss))
+	for i, d := range ss {
+		v, err := parseUint(d, 16, "uint16")
+		if err != nil {
+			return err
+		}
+		out[i] = uint16(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Uint16SliceValue) Type() string {
+	return "uint16Slice"
+}
+
+func (s *Uint16SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type UintSliceValue []uint
+
+func (s *UintSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]uint, len(ss))
+	for i, d := range ss {
+		v, err := parseUint(d, strconv.IntSize, "uint")
+		if err != nil {
+			return err
+		}
+		out[i] = uint(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *UintSliceValue) Type() string {
+	return "uintSlice"
+}
+
+func (s *UintSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type IntSliceValue []int
+
+func (s *IntSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]int, len(ss))
+	for i, d := range ss {
+		v, err := parseInt(d, strconv.IntSize, "int")
+		if err != nil {
+			return err
+		}
+		out[i] = int(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *IntSliceValue) Type() string {
+	return "intSlice"
+}
+
+func (s *IntSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Int64SliceValue []int64
+
+func (s *Int64SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]int64, len(ss))
+	for i, d := range ss {
+		v, err := parseInt(d, 64, "int64")
+		if err != nil {
+			return err
+		}
+		out[i] = v
+	}
+	*s = out
+	return nil
+}
+
+func (s *Int64SliceValue) Type() string {
+	return "int64Slice"
+}
+
+func (s *Int64SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Int32SliceValue []int32
+
+func (s *Int32SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]int32, len(ss))
+	for i, d := range ss {
+		v, err := parseInt(d, 32, "int32")
+		if err != nil {
+			return err
+		}
+		out[i] = int32(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Int32SliceValue) Type() string {
+	return "int32Slice"
+}
+
+func (s *Int32SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Int16SliceValue []int16
+
+func (s *Int16SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]int16, len(ss))
+	for i, d := range ss {
+		v, err := parseInt(d, 16, "int16")
+		if err != nil {
+			return err
+		}
+		out[i] = int16(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Int16SliceValue) Type() string {
+	return "int16Slice"
+}
+
+func (s *Int16SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Int8SliceValue []int8
+
+func (s *Int8SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]int8, len(ss))
+	for i, d := range ss {
+		v, err := parseInt(d, 8, "int8")
+		if err != nil {
+			return err
+		}
+		out[i] = int8(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Int8SliceValue) Type() string {
+	return "int8Slice"
+}
+
+func (s *Int8SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%d", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Float32SliceValue []float32
+
+func (s *Float32SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]float32, len(ss))
+	for i, d := range ss {
+		v, err := parseFloat(d, 32, "float32")
+		if err != nil {
+			return err
+		}
+		out[i] = float32(v)
+	}
+	*s = out
+	return nil
+}
+
+func (s *Float32SliceValue) Type() string {
+	return "float32Slice"
+}
+
+// Canonical renders the numbers without rounding them, String rounds to 6 decimals.
+func (s *Float32SliceValue) Canonical() (string, error) {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = strconv.FormatFloat(float64(d), 'g', -1, 32)
+	}
+	return strings.Join(out, ","), nil
+}
+
+func (s *Float32SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%f", d)
+	}
+	return strings.Join(out, ",")
+}
+
+type Float64SliceValue []float64
+
+func (s *Float64SliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]float64, len(ss))
+	for i, d := range ss {
+		v, err := parseFloat(d, 64, "float64")
+		if err != nil {
+			return err
+		}
+		out[i] = v
+	}
+	*s = out
+	return nil
+}
+
+func (s *Float64SliceValue) Type() string {
+	return "float64Slice"
+}
+
+// Canonical renders the numbers without rounding them, String rounds to 6 decimals.
+func (s *Float64SliceValue) Canonical() (string, error) {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = strconv.FormatFloat(float64(d), 'g', -1, 64)
+	}
+	return strings.Join(out, ","), nil
+}
+
+func (s *Float64SliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = fmt.Sprintf("%f", d)
+	}
+	return strings.Join(out, ",")
+}
+
+// splitList splits a comma-separated list of values. An empty string is an empty list.
+func splitList(val string) []string {
+	if val == "" {
+		return nil
+	}
+	return strings.Split(val, ",")
+}
+
+type StringSliceValue []string
+
+func readAsCSV(val string) ([]string, error) {
+	if val == "" {
+		return []string{}, nil
+	}
+	stringReader := strings.NewReader(val)
+	csvReader := csv.NewReader(stringReader)
+	return csvReader.Read()
+}
+
+func writeAsCSV(vals []string) (string, error) {
+	b := &bytes.Buffer{}
+	w := csv.NewWriter(b)
+	err := w.Write(vals)
+	if err != nil {
+		return "", err
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func (s *StringSliceValue) Set(val string) error {
+	v, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+func (s *StringSliceValue) Type() string {
+	return "stringSlice"
+}
+
+func (s *StringSliceValue) String() string {
+	str, _ := writeAsCSV(*s)
+	return str
+}
+
+type BoolSliceValue []bool
+
+func (s *BoolSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]bool, len(ss))
+	for i, d := range ss {
+		v, err := strconv.ParseBool(d)
+		if err != nil {
+			return err
+		}
+		out[i] = v
+	}
+	*s = out
+	return nil
+}
+
+func (s *BoolSliceValue) Type() string {
+	return "boolSlice"
+}
+
+func (s *BoolSliceValue) String() string {
+	boolStrSlice := make([]string, len(*s))
+	for i, b := range *s {
+		boolStrSlice[i] = strconv.FormatBool(b)
+	}
+
+	return strings.Join(boolStrSlice, ",")
+}
+
+// MapStringValue is a map of strings, formatted as comma-separated key=value pairs, e.g. "a=1,b=2".
+// Pairs are CSV-decoded like StringSliceValue, to allow commas in quoted pairs.
+type MapStringValue map[string]string
+
+func (m *MapStringValue) Set(val string) error {
+	out := make(map[string]string)
+	if err := readPairs(val, func(k, v string) error {
+		out[k] = v
+		return nil
+	}); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+func (m *MapStringValue) Type() string {
+	return "stringMap"
+}
+
+func (m *MapStringValue) String() string {
+	return writePairs(*m, func(v string) string { return v })
+}
+
+// MapIntValue is a map of ints, formatted as comma-separated key=value pairs, e.g. "a=1,b=2".
+type MapIntValue map[string]int
+
+func (m *MapIntValue) Set(val string) error {
+	out := make(map[string]int)
+	if err := readPairs(val, func(k, v string) error {
+		x, err := parseInt(v, strconv.IntSize, "int")
+		out[k] = int(x)
+		return err
+	}); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+func (m *MapIntValue) Type() string {
+	return "intMap"
+}
+
+func (m *MapIntValue) String() string {
+	return writePairs(*m, strconv.Itoa)
+}
+
+// MapUint64Value is a map of uint64s, formatted as comma-separated key=value pairs, e.g. "a=1,b=2".
+type MapUint64Value map[string]uint64
+
+func (m *MapUint64Value) Set(val string) error {
+	out := make(map[string]uint64)
+	if err := readPairs(val, func(k, v string) error {
+		x, err := parseUint(v, 64, "uint64")
+		out[k] = x
+		return err
+	}); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+func (m *MapUint64Value) Type() string {
+	return "uint64Map"
+}
+
+func (m *MapUint64Value) String() string {
+	return writePairs(*m, func(v uint64) string { return strconv.FormatUint(v, 10) })
+}
+
+// MapBoolValue is a map of bools, formatted as comma-separated key=value pairs, e.g. "a=true,b=false".
+type MapBoolValue map[string]bool
+
+func (m *MapBoolValue) Set(val string) error {
+	out := make(map[string]bool)
+	if err := readPairs(val, func(k, v string) error {
+		x, err := strconv.ParseBool(v)
+		out[k] = x
+		return err
+	}); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+func (m *MapBoolValue) Type() string {
+	return "boolMap"
+}
+
+func (m *MapBoolValue) String() string {
+	return writePairs(*m, strconv.FormatBool)
+}
+
+// MapDurationValue is a map of durations, formatted as comma-separated key=value pairs, e.g. "a=1s,b=2m".
+type MapDurationValue map[string]time.Duration
+
+func (m *MapDurationValue) Set(val string) error {
+	out := make(map[string]time.Duration)
+	if err := readPairs(val, func(k, v string) error {
+		x, err := time.ParseDuration(v)
+		out[k] = x
+		return err
+	}); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+func (m *MapDurationValue) Type() string {
+	return "durationMap"
+}
+
+func (m *MapDurationValue) String() string {
+	return writePairs(*m, time.Duration.String)
+}
+
+// readPairs reads CSV-encoded key=value pairs, and passes each pair to the given function.
+func readPairs(val string, fn func(k, v string) error) error {
+	if val == "" {
+		return nil
+	}
+	pairs, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%q must be formatted as key=value", p)
+		}
+		if err := fn(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("invalid value of key %q: %w", kv[0], err)
+		}
+	}
+	return nil
+}
+
+// writePairs writes the entries of a map as CSV-encoded key=value pairs, sorted by key.
+func writePairs[V any](m map[string]V, format func(V) string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + format(m[k])
+	}
+	str, _ := writeAsCSV(pairs)
+	return str
+}
+
+// RegexpValue is a regular expression, compiled when the flag is set, to report invalid patterns as flag errors.
+type RegexpValue struct {
+	Dest **regexp.Regexp
+}
+
+func (r *RegexpValue) Set(val string) error {
+	re, err := regexp.Compile(val)
+	if err != nil {
+		return err
+	}
+	*r.Dest = re
+	return nil
+}
+
+func (r *RegexpValue) Type() string {
+	return "regexp"
+}
+
+func (r *RegexpValue) String() string {
+	if r.Dest == nil || *r.Dest == nil {
+		return ""
+	}
+	return (*r.Dest).String()
+}
+
+// TextValue wraps a type that implements encoding.TextUnmarshaler, for types without a flag.Value implementation.
+// The value is rendered with encoding.TextMarshaler if implemented, and fmt.Sprint otherwise.
+type TextValue struct {
+	// Dest is a pointer to the destination value
+	Dest encoding.TextUnmarshaler
+}
+
+func (t *TextValue) Set(val string) error {
+	return t.Dest.UnmarshalText([]byte(val))
+}
+
+func (t *TextValue) Type() string {
+	return strings.TrimPrefix(reflect.TypeOf(t.Dest).String(), "*")
+}
+
+func (t *TextValue) String() string {
+	if t.Dest == nil {
+		return ""
+	}
+	if m, ok := t.Dest.(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	return fmt.Sprint(reflect.ValueOf(t.Dest).Elem().Interface())
+}
+
+// Canonical renders the value with encoding.TextMarshaler, the fmt.Sprint rendering cannot be parsed back.
+func (t *TextValue) Canonical() (string, error) {
+	m, ok := t.Dest.(encoding.TextMarshaler)
+	if !ok {
+		return "", fmt.Errorf("%s does not implement encoding.TextMarshaler", t.Type())
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// EnumValue restricts a flag to a set of allowed values, see the `enum` struct tag.
+type EnumValue struct {
+	flag.Value
+	Allowed []string
+}
+
+func (v *EnumValue) Set(s string) error {
+	for _, a := range v.Allowed {
+		if a == s {
+			return v.Value.Set(s)
+		}
+	}
+	return fmt.Errorf("%q is not one of: %s", s, strings.Join(v.Allowed, ", "))
+}
+
+func (v *EnumValue) Type() string {
+	return strings.Join(v.Allowed, "|")
+}
+
+// Canonical renders the value, if it is one of the allowed values.
+func (v *EnumValue) Canonical() (string, error) {
+	s := v.Value.String()
+	if c, ok := v.Value.(interface{ Canonical() (string, error) }); ok {
+		var err error
+		if s, err = c.Canonical(); err != nil {
+			return "", err
+		}
+	}
+	for _, a := range v.Allowed {
+		if a == s {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of: %s", s, strings.Join(v.Allowed, ", "))
+}
+
+// BytesHex exposes bytes as a flag, hex-encoded,
+// optional whitespace padding, case insensitive, and optional 0x prefix.
+type BytesHexFlag []byte
+
+func (f BytesHexFlag) String() string {
+	return hex.EncodeToString(f)
+}
+
+func (f *BytesHexFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	value = strings.ToLower(value)
+	if strings.HasPrefix(value, "0x") {
+		value = value[2:]
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return err
+	}
+	*f = b
+	return nil
+}
+
+func (f *BytesHexFlag) Type() string {
+	return "bytes"
+}
@@ -0,0 +1,321 @@
+package askflag
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValues(t *testing.T) {
+	var (
+		d  time.Duration
+		ss []string
+		m  map[string]int
+		ts TriState
+	)
+	for _, c := range []struct {
+		v     flag.Value
+		input string
+		out   string
+	}{
+		{(*DurationValue)(&d), "1m30s", "1m30s"},
+		{(*StringSliceValue)(&ss), `a,"b,c"`, `a,"b,c"`},
+		{(*MapIntValue)(&m), "b=2,a=1", "a=1,b=2"},
+		{&ts, "false", "false"},
+	} {
+		if err := c.v.Set(c.input); err != nil {
+			t.Fatalf("%q: %v", c.input, err)
+		}
+		if got := c.v.String(); got != c.out {
+			t.Errorf("%q: expected %q, got %q", c.input, c.out, got)
+		}
+	}
+	if !ts.IsSet() || ts.Bool() {
+		t.Fatalf("unexpected tri-state: %d", ts)
+	}
+}
+
+// checkInvalid checks that every input is rejected, and does not change the value.
+func checkInvalid(t *testing.T, v flag.Value, inputs ...string) {
+	t.Helper()
+	before := v.String()
+	for _, input := range inputs {
+		if err := v.Set(input); err == nil {
+			t.Fatalf("%q: expected error", input)
+		}
+		if v.String() != before {
+			t.Fatalf("%q: invalid value must not be set, got %q", input, v.String())
+		}
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	var (
+		labels   map[string]string
+		weights  map[string]int
+		limits   map[string]uint64
+		features map[string]bool
+		timeouts map[string]time.Duration
+	)
+	if err := (*MapStringValue)(&labels).Set(`b=2,a=1,"c=x,y"`); err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 3 || labels["c"] != "x,y" || (*MapStringValue)(&labels).String() != `a=1,b=2,"c=x,y"` {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	for _, c := range []struct {
+		v     flag.Value
+		input string
+	}{
+		{(*MapIntValue)(&weights), "a=-1,b=2"},
+		{(*MapUint64Value)(&limits), "x=18446744073709551615"},
+		{(*MapBoolValue)(&features), "bar=false,foo=true"},
+		{(*MapDurationValue)(&timeouts), "dial=500ms,read=1s"},
+	} {
+		if err := c.v.Set(c.input); err != nil || c.v.String() != c.input {
+			t.Fatalf("expected round-trip of %q, got %q (err: %v)", c.input, c.v.String(), err)
+		}
+	}
+	if err := (*MapIntValue)(&weights).Set("a=x"); err == nil || !strings.Contains(err.Error(), `key "a"`) {
+		t.Fatalf("expected invalid value error, got: %v", err)
+	}
+	checkInvalid(t, (*MapStringValue)(&labels), "novalue")
+}
+
+func TestIPNetSliceValue(t *testing.T) {
+	var nets []net.IPNet
+	v := (*IPNetSliceValue)(&nets)
+	if err := v.Set("10.0.0.0/8,192.168.1.1/16,fd00::/8"); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.String(); got != "10.0.0.0/8,192.168.0.0/16,fd00::/8" {
+		t.Fatalf("unexpected value: %s", got)
+	}
+	checkInvalid(t, v, "10.0.0.0")
+}
+
+func TestLevelValue(t *testing.T) {
+	var l LevelValue
+	for input, expected := range map[string]slog.Level{
+		"TRACE": LevelTrace, "debug": slog.LevelDebug, " warning ": slog.LevelWarn, "info+2": slog.LevelInfo + 2,
+	} {
+		if err := l.Set(input); err != nil || l.Level() != expected {
+			t.Fatalf("%q: unexpected level %s: %v", input, &l, err)
+		}
+	}
+	if err := l.Set("info+2"); err != nil {
+		t.Fatal(err)
+	}
+	var leveler slog.Leveler = &l
+	if leveler.Level() != slog.LevelInfo+2 || l.String() != "info+2" {
+		t.Fatalf("unexpected leveler: %s", &l)
+	}
+	if err := l.Set("trace"); err != nil || l.String() != "trace" {
+		t.Fatalf("unexpected trace rendering: %s", &l)
+	}
+	if err := l.Set("loud"); err == nil || !strings.Contains(err.Error(), "expected one of") {
+		t.Fatalf("expected level error, got: %v", err)
+	}
+}
+
+func TestRuneValue(t *testing.T) {
+	var r RuneValue
+	for input, expected := range map[string]rune{";": ';', "é": 'é', `\t`: '\t', `\u00e9`: 'é', `\x00`: 0} {
+		if err := r.Set(input); err != nil || rune(r) != expected {
+			t.Fatalf("%q: unexpected rune %q: %v", input, rune(r), err)
+		}
+	}
+	r = 0
+	if c, err := r.Canonical(); err != nil || c != `\x00` {
+		t.Fatalf("unexpected canonical zero rune: %q", c)
+	}
+	checkInvalid(t, &r, "", "ab", `\q`, `\tx`, "\xff")
+}
+
+func TestUUIDValue(t *testing.T) {
+	var u UUIDValue
+	if err := u.Set("123E4567-E89B-12D3-A456-426614174000"); err != nil {
+		t.Fatal(err)
+	}
+	if u[0] != 0x12 || u[15] != 0x00 || u.String() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Fatalf("unexpected uuid: %x", u)
+	}
+	checkInvalid(t, &u, "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g",
+		"123e4567-e89b-12d3-a456_426614174000")
+}
+
+func TestPercentValue(t *testing.T) {
+	var threshold, quota float64
+	fraction, whole := &PercentValue{Dest: &threshold}, &PercentValue{Dest: &quota, Whole: true}
+	for _, c := range []struct {
+		v        *PercentValue
+		input    string
+		expected float64
+	}{
+		{fraction, "0.5", 0.5},
+		{fraction, "12.5%", 0.125},
+		{whole, "20", 0.2},
+		{whole, "100%", 1},
+	} {
+		if err := c.v.Set(c.input); err != nil || *c.v.Dest != c.expected {
+			t.Fatalf("%q: unexpected value %v: %v", c.input, *c.v.Dest, err)
+		}
+	}
+	if fraction.String() != "12.5%" {
+		t.Fatalf("unexpected rendering: %s", fraction)
+	}
+	// the canonical rendering parses back into the same fraction, String rounds
+	for _, v := range []float64{1.0 / 3, 0.333333333333} {
+		threshold = v
+		c, err := fraction.Canonical()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fraction.Set(c); err != nil || threshold != v {
+			t.Fatalf("canonical %q does not round-trip: %v", c, err)
+		}
+	}
+	checkInvalid(t, fraction, "85", "x%")
+	checkInvalid(t, whole, "101", "-1%")
+}
+
+func TestRateValue(t *testing.T) {
+	var r RateValue
+	for input, perSecond := range map[string]float64{"5/m": 5.0 / 60, "0.5/h": 0.5 / 3600, "1000/10s": 100, "2/ms": 2000} {
+		if err := r.Set(input); err != nil {
+			t.Fatal(err)
+		}
+		if r.PerSecond() != perSecond || r.String() != input {
+			t.Fatalf("%s: unexpected rate: %s (%f/s)", input, &r, r.PerSecond())
+		}
+	}
+	if err := r.Set("2/ms"); err != nil || r.Interval() != 500*time.Microsecond {
+		t.Fatalf("unexpected interval: %s", r.Interval())
+	}
+	checkInvalid(t, &r, "100", "x/s", "-1/s", "1/0s", "1/parsec")
+}
+
+func TestFileModeValue(t *testing.T) {
+	var m FileModeValue
+	for input, expected := range map[string]os.FileMode{
+		"600": 0o600, "0o755": 0o755, "rwxr-x---": 0o750, "-rw-r--r--": 0o644, "0": 0,
+	} {
+		if err := m.Set(input); err != nil || m.FileMode() != expected {
+			t.Fatalf("%s: unexpected mode %v: %v", input, m.FileMode(), err)
+		}
+	}
+	if err := m.Set("644"); err != nil || m.String() != "0644" {
+		t.Fatalf("expected octal rendering, got: %s", &m)
+	}
+	checkInvalid(t, &m, "1000", "0649", "rw-rw-rwz", "wr-r--r--", "")
+}
+
+func TestHostListValue(t *testing.T) {
+	var h HostListValue
+	if err := h.Set("10.0.0.0/8, ::1,Example.com.,*.internal"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.String(); got != "::1,10.0.0.0/8,example.com,*.internal" {
+		t.Fatalf("unexpected hosts: %q", got)
+	}
+	for host, expected := range map[string]bool{
+		"10.1.2.3": true, "11.0.0.1": false, "::1": true, "::2": false,
+		"EXAMPLE.com": true, "www.example.com": false, "db.internal": true, "internal": false,
+	} {
+		if h.ContainsHost(host) != expected {
+			t.Errorf("%s: expected %v", host, expected)
+		}
+	}
+	if !h.Contains(net.ParseIP("10.255.0.1")) {
+		t.Fatal("expected IP in CIDR")
+	}
+	checkInvalid(t, &h, "10.0.0.0/33", "bad_host", "-a.com")
+}
+
+func TestMACValue(t *testing.T) {
+	var (
+		iface net.HardwareAddr
+		peers []net.HardwareAddr
+	)
+	if err := (*MACValue)(&iface).Set("00-00-5E-00-53-02"); err != nil || iface.String() != "00:00:5e:00:53:02" {
+		t.Fatalf("unexpected iface %s: %v", iface, err)
+	}
+	v := (*MACSliceValue)(&peers)
+	if err := v.Set("02:00:5e:10:00:00,0000.5e00.5303"); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.String(); got != "02:00:5e:10:00:00,00:00:5e:00:53:03" {
+		t.Fatalf("unexpected peers: %s", got)
+	}
+	checkInvalid(t, (*MACValue)(&iface), "00:00:5e")
+	checkInvalid(t, v, "00:00:5e:00:53:01,x")
+}
+
+func TestPortValues(t *testing.T) {
+	var (
+		p PortValue
+		r PortRangeValue
+	)
+	if err := p.Set("65535"); err != nil || p.Port() != 65535 {
+		t.Fatalf("unexpected port %d: %v", p.Port(), err)
+	}
+	if err := r.Set("8000-8003"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 4 || !r.Contains(8003) || r.Contains(8004) {
+		t.Fatalf("unexpected range: %+v", r)
+	}
+	if got := fmt.Sprint(r.Ports()); got != "[8000 8001 8002 8003]" {
+		t.Fatalf("unexpected ports: %s", got)
+	}
+	if err := r.Set("22"); err != nil || r.String() != "22" || r.Len() != 1 {
+		t.Fatalf("unexpected single port range: %v %+v", err, r)
+	}
+	checkInvalid(t, &p, "0", "65536")
+	checkInvalid(t, &r, "9000-8000", "0-10", "1-")
+}
+
+func TestWeightedEndpointsValue(t *testing.T) {
+	var w WeightedEndpointsValue
+	if err := w.Set("1.2.3.4:9000=3, [::1]:9000,example.com:80=2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String(); got != "1.2.3.4:9000=3,[::1]:9000=1,example.com:80=2" {
+		t.Fatalf("unexpected endpoints: %s", got)
+	}
+	if w.TotalWeight() != 6 || strings.Join(w.Addrs(), " ") != "1.2.3.4:9000 [::1]:9000 example.com:80" {
+		t.Fatalf("unexpected helpers: %d %v", w.TotalWeight(), w.Addrs())
+	}
+	checkInvalid(t, &w, "a:1=0", "a:1=-1", "a=1", "a:0=1", "a:1,a:1=2")
+}
+
+func TestSecretListValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ASK_TEST_KEY", "from-env")
+	var s SecretListValue
+	if err := s.Set("current,file:" + path + ",env:ASK_TEST_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(s.Secrets(), " "); got != "current from-file from-env" || s.Primary() != "current" {
+		t.Fatalf("unexpected secrets: %q", got)
+	}
+	if got := s.String(); got != "current,file:"+path+",env:ASK_TEST_KEY" {
+		t.Fatalf("expected lossless value, got: %q", got)
+	}
+	if got := s.Redacted(); got != "***,file:"+path+",env:ASK_TEST_KEY" {
+		t.Fatalf("expected redacted value, got: %q", got)
+	}
+	if !s.Match("from-env") || s.Match("other") || s.Match("") || s.Match("***") {
+		t.Fatal("unexpected match")
+	}
+	checkInvalid(t, &s, "env:ASK_TEST_MISSING", "file:"+path+".missing", "a,***")
+}
@@ -0,0 +1,104 @@
+package askflag
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// HostListValue is a comma-separated list of IPs, CIDRs and hostnames, e.g. for --allow and --deny flags.
+// Hostnames may start with a "*." wildcard to match all sub-domains, e.g. "*.example.com".
+type HostListValue struct {
+	IPs   []net.IP
+	Nets  []*net.IPNet
+	Hosts []string
+}
+
+func (h *HostListValue) Set(s string) error {
+	var out HostListValue
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			out.IPs = append(out.IPs, ip)
+		} else if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return err
+			}
+			out.Nets = append(out.Nets, ipNet)
+		} else if isHostname(strings.TrimPrefix(entry, "*.")) {
+			out.Hosts = append(out.Hosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+		} else {
+			return fmt.Errorf("invalid host %q, expected an IP, CIDR or hostname", entry)
+		}
+	}
+	*h = out
+	return nil
+}
+
+func (h *HostListValue) Type() string {
+	return "hostList"
+}
+
+func (h *HostListValue) String() string {
+	var out []string
+	for _, ip := range h.IPs {
+		out = append(out, ip.String())
+	}
+	for _, ipNet := range h.Nets {
+		out = append(out, ipNet.String())
+	}
+	out = append(out, h.Hosts...)
+	return strings.Join(out, ",")
+}
+
+// Contains checks if the IP is listed, or is part of a listed CIDR. Hostnames are not resolved.
+func (h *HostListValue) Contains(ip net.IP) bool {
+	for _, v := range h.IPs {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	for _, ipNet := range h.Nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsHost checks if the host, an IP or hostname, is listed. Hostnames are matched case-insensitively.
+func (h *HostListValue) ContainsHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return h.Contains(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, v := range h.Hosts {
+		if v == host || (strings.HasPrefix(v, "*.") && strings.HasSuffix(host, v[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHostname checks if s is a valid DNS name: dot-separated labels of letters, digits and hyphens.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
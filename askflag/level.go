@@ -0,0 +1,49 @@
+package askflag
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LevelTrace is the level below debug, for the "trace" log level, which slog does not define.
+const LevelTrace = slog.LevelDebug - 4
+
+// LevelValue is a log level: trace, debug, info, warn or error, case-insensitive.
+// Offsets like "info+2" are accepted too, like slog.Level.
+// It implements slog.Leveler, to configure a slog handler with the flag directly.
+type LevelValue slog.Level
+
+func (l *LevelValue) Set(s string) error {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch name {
+	case "trace":
+		*l = LevelValue(LevelTrace)
+		return nil
+	case "warning":
+		*l = LevelValue(slog.LevelWarn)
+		return nil
+	}
+	var v slog.Level
+	if err := v.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("invalid log level %q, expected one of: trace, debug, info, warn, error", s)
+	}
+	*l = LevelValue(v)
+	return nil
+}
+
+func (l *LevelValue) Type() string {
+	return "loglevel"
+}
+
+func (l *LevelValue) String() string {
+	if slog.Level(*l) == LevelTrace {
+		return "trace"
+	}
+	return strings.ToLower(slog.Level(*l).String())
+}
+
+// Level returns the slog level.
+func (l *LevelValue) Level() slog.Level {
+	return slog.Level(*l)
+}
@@ -0,0 +1,60 @@
+package askflag
+
+import (
+	"net"
+	"strings"
+)
+
+// MACValue is a hardware address, parsed with net.ParseMAC, e.g. "00:00:5e:00:53:01".
+type MACValue net.HardwareAddr
+
+// Set parses the address. An empty string unsets the address.
+func (m *MACValue) Set(s string) error {
+	if s == "" {
+		*m = nil
+		return nil
+	}
+	v, err := net.ParseMAC(s)
+	if err != nil {
+		return err
+	}
+	*m = MACValue(v)
+	return nil
+}
+
+func (m *MACValue) Type() string {
+	return "mac"
+}
+
+func (m *MACValue) String() string {
+	return net.HardwareAddr(*m).String()
+}
+
+// MACSliceValue is a comma-separated list of hardware addresses, see MACValue.
+type MACSliceValue []net.HardwareAddr
+
+func (s *MACSliceValue) Set(val string) error {
+	ss := splitList(val)
+	out := make([]net.HardwareAddr, len(ss))
+	for i, d := range ss {
+		var err error
+		out[i], err = net.ParseMAC(d)
+		if err != nil {
+			return err
+		}
+	}
+	*s = out
+	return nil
+}
+
+func (s *MACSliceValue) Type() string {
+	return "macSlice"
+}
+
+func (s *MACSliceValue) String() string {
+	out := make([]string, len(*s))
+	for i, d := range *s {
+		out[i] = d.String()
+	}
+	return strings.Join(out, ",")
+}
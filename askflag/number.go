@@ -0,0 +1,61 @@
+package askflag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// RangeError is returned when a number is out of range for the type of the flag,
+// e.g. "value 70000 out of range for uint16 flag --port [0..65535]".
+type RangeError struct {
+	// Value that was parsed
+	Value string
+	// Type of the number, e.g. "uint16"
+	Type string
+	// Min and Max are the valid range of the type
+	Min, Max string
+	// Flag is the flag that the value was applied to, if known, e.g. "--port"
+	Flag string
+}
+
+func (e *RangeError) Error() string {
+	if e.Flag != "" {
+		return fmt.Sprintf("value %s out of range for %s flag %s [%s..%s]", e.Value, e.Type, e.Flag, e.Min, e.Max)
+	}
+	return fmt.Sprintf("value %s out of range for %s [%s..%s]", e.Value, e.Type, e.Min, e.Max)
+}
+
+// parseInt parses a signed integer of the given bit size, with a RangeError if it does not fit.
+func parseInt(s string, bitSize int, typ string) (int64, error) {
+	v, err := strconv.ParseInt(s, 0, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &RangeError{Value: s, Type: typ,
+			Min: strconv.FormatInt(-1<<(bitSize-1), 10), Max: strconv.FormatInt(1<<(bitSize-1)-1, 10)}
+	}
+	return v, err
+}
+
+// parseUint parses an unsigned integer of the given bit size, with a RangeError if it does not fit.
+func parseUint(s string, bitSize int, typ string) (uint64, error) {
+	v, err := strconv.ParseUint(s, 0, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &RangeError{Value: s, Type: typ, Min: "0", Max: strconv.FormatUint(1<<bitSize-1, 10)}
+	}
+	return v, err
+}
+
+// parseFloat parses a float of the given bit size, with a RangeError if it does not fit.
+func parseFloat(s string, bitSize int, typ string) (float64, error) {
+	v, err := strconv.ParseFloat(s, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		max := math.MaxFloat64
+		if bitSize == 32 {
+			max = math.MaxFloat32
+		}
+		return 0, &RangeError{Value: s, Type: typ,
+			Min: strconv.FormatFloat(-max, 'g', -1, bitSize), Max: strconv.FormatFloat(max, 'g', -1, bitSize)}
+	}
+	return v, err
+}
@@ -0,0 +1,67 @@
+package askflag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PercentValue is a percentage, stored as a fraction in [0, 1].
+// It accepts "85%", and a number without % sign as fraction, e.g. "0.85", or as percentage if Whole, e.g. "85".
+// Use the `format:"percent"` or `format:"percent-whole"` struct tag for float64 fields.
+type PercentValue struct {
+	// Dest is a pointer to the destination value
+	Dest *float64
+	// Whole parses a number without % sign as percentage, instead of as fraction.
+	Whole bool
+}
+
+func (p *PercentValue) Set(s string) error {
+	v := strings.TrimSpace(s)
+	percent := p.Whole
+	if strings.HasSuffix(v, "%") {
+		v = strings.TrimSpace(v[:len(v)-1])
+		percent = true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", s)
+	}
+	if percent {
+		f /= 100
+	}
+	if !(f >= 0 && f <= 1) {
+		return fmt.Errorf("percentage %q is out of range [0%%..100%%]", s)
+	}
+	*p.Dest = f
+	return nil
+}
+
+func (p *PercentValue) Type() string {
+	return "percent"
+}
+
+// Canonical renders the percentage with as many digits as needed to parse it back into the same fraction,
+// String rounds to 10 digits.
+func (p *PercentValue) Canonical() (string, error) {
+	if p.Dest == nil {
+		return "", fmt.Errorf("percentage has no destination")
+	}
+	for prec := 1; prec <= 17; prec++ {
+		v := strconv.FormatFloat(*p.Dest*100, 'g', prec, 64)
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f/100 == *p.Dest {
+			return v + "%", nil
+		}
+	}
+	if !p.Whole {
+		return strconv.FormatFloat(*p.Dest, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("percentage %v cannot be rendered exactly", *p.Dest)
+}
+
+func (p *PercentValue) String() string {
+	if p.Dest == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p.Dest*100, 'g', 10, 64) + "%"
+}
@@ -0,0 +1,127 @@
+package askflag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortValue is a TCP or UDP port number, in the range 1-65535. Port 0 is rejected, to not listen on a random port.
+type PortValue uint16
+
+func (p *PortValue) Set(s string) error {
+	v, err := parsePort(s)
+	if err != nil {
+		return err
+	}
+	*p = PortValue(v)
+	return nil
+}
+
+func (p *PortValue) Type() string {
+	return "port"
+}
+
+func (p *PortValue) String() string {
+	return strconv.FormatUint(uint64(*p), 10)
+}
+
+// Canonical renders the port, if it is set: port 0 cannot be parsed.
+func (p *PortValue) Canonical() (string, error) {
+	if *p == 0 {
+		return "", fmt.Errorf("port is not set")
+	}
+	return p.String(), nil
+}
+
+// Port returns the port number.
+func (p *PortValue) Port() uint16 {
+	return uint16(*p)
+}
+
+// PortRangeValue is an inclusive range of ports, like "8000-8100", or a single port like "8000".
+type PortRangeValue struct {
+	First, Last uint16
+}
+
+func (r *PortRangeValue) Set(s string) error {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		last = first
+	}
+	a, err := parsePort(first)
+	if err != nil {
+		return err
+	}
+	b, err := parsePort(last)
+	if err != nil {
+		return err
+	}
+	if a > b {
+		return fmt.Errorf("invalid port range %q, first port is larger than last port", s)
+	}
+	*r = PortRangeValue{First: a, Last: b}
+	return nil
+}
+
+func (r *PortRangeValue) Type() string {
+	return "portRange"
+}
+
+func (r *PortRangeValue) String() string {
+	if r.First == 0 && r.Last == 0 {
+		return ""
+	}
+	if r.First == r.Last {
+		return strconv.FormatUint(uint64(r.First), 10)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Canonical renders the range, if it is set: an empty range cannot be parsed.
+func (r *PortRangeValue) Canonical() (string, error) {
+	if r.First == 0 && r.Last == 0 {
+		return "", fmt.Errorf("port range is not set")
+	}
+	return r.String(), nil
+}
+
+// Len returns the number of ports in the range.
+func (r *PortRangeValue) Len() int {
+	if r.First == 0 && r.Last == 0 {
+		return 0
+	}
+	return int(r.Last) - int(r.First) + 1
+}
+
+// Contains checks if the port is in the range.
+func (r *PortRangeValue) Contains(port uint16) bool {
+	return port != 0 && r.First <= port && port <= r.Last
+}
+
+// Each calls fn for every port in the range, in order, until fn returns false.
+func (r *PortRangeValue) Each(fn func(port uint16) bool) {
+	for i := 0; i < r.Len(); i++ {
+		if !fn(r.First + uint16(i)) {
+			return
+		}
+	}
+}
+
+// Ports lists all ports in the range, in order.
+func (r *PortRangeValue) Ports() []uint16 {
+	out := make([]uint16, 0, r.Len())
+	r.Each(func(port uint16) bool {
+		out = append(out, port)
+		return true
+	})
+	return out
+}
+
+func parsePort(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid port %q, expected a number in the range 1-65535", s)
+	}
+	return uint16(v), nil
+}
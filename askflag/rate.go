@@ -0,0 +1,88 @@
+package askflag
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateValue is a number of events per duration, e.g. "100/s", "5/m", "0.5/h" or "1000/10s", for throttling options.
+// The duration is a unit (ms, s, m or h), or any duration like "10s".
+type RateValue struct {
+	Count float64
+	Per   time.Duration
+}
+
+// rateUnits are the durations that can be written without number, e.g. "/s" instead of "/1s".
+var rateUnits = []struct {
+	name string
+	d    time.Duration
+}{{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}, {"ms", time.Millisecond}}
+
+// Set parses the rate. An empty string unsets the rate.
+func (r *RateValue) Set(s string) error {
+	if s == "" {
+		*r = RateValue{}
+		return nil
+	}
+	count, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("invalid rate %q, expected count/duration, e.g. 100/s", s)
+	}
+	c, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || c < 0 || math.IsInf(c, 0) || math.IsNaN(c) {
+		return fmt.Errorf("invalid rate count %q", count)
+	}
+	per = strings.TrimSpace(per)
+	var d time.Duration
+	for _, u := range rateUnits {
+		if per == u.name {
+			d = u.d
+		}
+	}
+	if d == 0 {
+		if d, err = time.ParseDuration(per); err != nil {
+			return fmt.Errorf("invalid rate duration %q", per)
+		}
+		if d <= 0 {
+			return fmt.Errorf("rate duration must be positive, got %s", d)
+		}
+	}
+	r.Count, r.Per = c, d
+	return nil
+}
+
+func (r *RateValue) Type() string {
+	return "rate"
+}
+
+func (r *RateValue) String() string {
+	if r.Per == 0 {
+		return ""
+	}
+	per := r.Per.String()
+	for _, u := range rateUnits {
+		if r.Per == u.d {
+			per = u.name
+		}
+	}
+	return strconv.FormatFloat(r.Count, 'g', -1, 64) + "/" + per
+}
+
+// PerSecond returns the number of events per second.
+func (r *RateValue) PerSecond() float64 {
+	if r.Per == 0 {
+		return 0
+	}
+	return r.Count / r.Per.Seconds()
+}
+
+// Interval returns the time between events, or 0 if the count is 0.
+func (r *RateValue) Interval() time.Duration {
+	if r.Count == 0 {
+		return 0
+	}
+	return time.Duration(float64(r.Per) / r.Count)
+}
@@ -0,0 +1,61 @@
+package askflag
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RuneValue is a single character, e.g. a delimiter. Escape sequences like `\t`, `\x00` and `\u00e9` are accepted.
+// Use the `format:"rune"` struct tag for rune fields, since a rune cannot be told apart from an int32.
+type RuneValue rune
+
+func (r *RuneValue) Set(s string) error {
+	if s == "" {
+		return fmt.Errorf("expected a single character, got empty string")
+	}
+	if s[0] == '\\' {
+		v, _, tail, err := strconv.UnquoteChar(s, 0)
+		if err != nil {
+			return fmt.Errorf("invalid escape sequence %q", s)
+		}
+		if tail != "" {
+			return fmt.Errorf("expected a single character, got %q", s)
+		}
+		*r = RuneValue(v)
+		return nil
+	}
+	v, size := utf8.DecodeRuneInString(s)
+	if v == utf8.RuneError && size <= 1 {
+		return fmt.Errorf("invalid UTF-8 character %q", s)
+	}
+	if size != len(s) {
+		return fmt.Errorf("expected a single character, got %q", s)
+	}
+	*r = RuneValue(v)
+	return nil
+}
+
+func (r *RuneValue) Type() string {
+	return "rune"
+}
+
+// Canonical renders the zero rune as escape sequence, String renders it as empty string.
+func (r *RuneValue) Canonical() (string, error) {
+	if *r == 0 {
+		return `\x00`, nil
+	}
+	return r.String(), nil
+}
+
+func (r *RuneValue) String() string {
+	if *r == 0 {
+		return ""
+	}
+	if unicode.IsPrint(rune(*r)) && *r != '\\' {
+		return string(rune(*r))
+	}
+	q := strconv.QuoteRune(rune(*r))
+	return q[1 : len(q)-1]
+}
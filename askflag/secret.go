@@ -0,0 +1,100 @@
+package askflag
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretListValue is a comma-separated list of secrets in priority order,
+// e.g. the current and the previous key during key rotation.
+// Every entry is a literal secret, "file:<path>" to read the secret from a file, or "env:<name>" to read it
+// from an environment variable. References are resolved when the value is set.
+//
+// String renders the entries as they were set, so the value can be set again.
+// Redacted renders the value without the literal secrets, so it is safe to log.
+type SecretListValue struct {
+	secrets []string
+	entries []string
+}
+
+func (s *SecretListValue) Set(val string) error {
+	var out SecretListValue
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		secret := entry
+		if path, ok := strings.CutPrefix(entry, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read secret file: %w", err)
+			}
+			secret = strings.TrimSpace(string(data))
+		} else if name, ok := strings.CutPrefix(entry, "env:"); ok {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				return fmt.Errorf("secret environment variable %s is not set", name)
+			}
+			secret = v
+		} else if entry == redacted {
+			return fmt.Errorf("secret cannot be the redaction marker %s", redacted)
+		}
+		if secret == "" {
+			return fmt.Errorf("secret %s is empty", entry)
+		}
+		out.secrets = append(out.secrets, secret)
+		out.entries = append(out.entries, entry)
+	}
+	*s = out
+	return nil
+}
+
+// redacted replaces the literal secrets in Redacted.
+const redacted = "***"
+
+func (s *SecretListValue) Type() string {
+	return "secretList"
+}
+
+func (s *SecretListValue) String() string {
+	return strings.Join(s.entries, ",")
+}
+
+// Redacted renders the entries with the literal secrets replaced by "***", and keeps the references.
+func (s *SecretListValue) Redacted() string {
+	out := make([]string, len(s.entries))
+	for i, entry := range s.entries {
+		if strings.HasPrefix(entry, "file:") || strings.HasPrefix(entry, "env:") {
+			out[i] = entry
+		} else {
+			out[i] = redacted
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// Secrets returns the resolved secrets, in priority order.
+func (s *SecretListValue) Secrets() []string {
+	return append([]string(nil), s.secrets...)
+}
+
+// Primary returns the secret with the highest priority, e.g. to sign with. Empty if there are no secrets.
+func (s *SecretListValue) Primary() string {
+	if len(s.secrets) == 0 {
+		return ""
+	}
+	return s.secrets[0]
+}
+
+// Match checks if the candidate equals any of the secrets, e.g. to verify with the current and previous keys.
+// Every secret is compared in constant time.
+func (s *SecretListValue) Match(candidate string) bool {
+	match := 0
+	for _, secret := range s.secrets {
+		match |= subtle.ConstantTimeCompare([]byte(secret), []byte(candidate))
+	}
+	return match == 1
+}
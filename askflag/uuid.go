@@ -0,0 +1,36 @@
+package askflag
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// UUIDValue is a UUID in the canonical format, e.g. "f81d4fae-7dec-11d0-a765-00a0c91e6bf6", case-insensitive.
+// Use the `format:"uuid"` struct tag for [16]byte fields, or UUID types based on [16]byte.
+type UUIDValue [16]byte
+
+func (u *UUIDValue) Set(s string) error {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return fmt.Errorf("invalid UUID %q, expected format xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx", s)
+	}
+	var out UUIDValue
+	i := 0
+	for _, part := range []string{s[0:8], s[9:13], s[14:18], s[19:23], s[24:36]} {
+		n, err := hex.Decode(out[i:], []byte(part))
+		if err != nil {
+			return fmt.Errorf("invalid UUID %q: %v", s, err)
+		}
+		i += n
+	}
+	*u = out
+	return nil
+}
+
+func (u *UUIDValue) Type() string {
+	return "uuid"
+}
+
+func (u *UUIDValue) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,83 @@
+package askflag
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// WeightedEndpoint is an address, like "1.2.3.4:9000", with a positive weight.
+type WeightedEndpoint struct {
+	Addr   string
+	Weight uint32
+}
+
+// WeightedEndpointsValue is a comma-separated list of host:port addresses with weights,
+// like "1.2.3.4:9000=3,5.6.7.8:9000=1", e.g. for load-balancing. The weight defaults to 1 if omitted.
+// Addresses must be unique.
+type WeightedEndpointsValue []WeightedEndpoint
+
+func (w *WeightedEndpointsValue) Set(val string) error {
+	var out []WeightedEndpoint
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		addr, weight := entry, uint32(1)
+		if i := strings.LastIndexByte(entry, '='); i >= 0 {
+			addr = entry[:i]
+			v, err := strconv.ParseUint(entry[i+1:], 10, 32)
+			if err != nil || v == 0 {
+				return fmt.Errorf("invalid weight of %q, expected a positive integer", addr)
+			}
+			weight = uint32(v)
+		}
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", addr, err)
+		}
+		if _, err := parsePort(port); err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", addr, err)
+		}
+		if _, ok := seen[addr]; ok {
+			return fmt.Errorf("duplicate endpoint %q", addr)
+		}
+		seen[addr] = struct{}{}
+		out = append(out, WeightedEndpoint{Addr: addr, Weight: weight})
+	}
+	*w = out
+	return nil
+}
+
+func (w *WeightedEndpointsValue) Type() string {
+	return "weightedEndpoints"
+}
+
+func (w *WeightedEndpointsValue) String() string {
+	out := make([]string, len(*w))
+	for i, e := range *w {
+		out[i] = e.Addr + "=" + strconv.FormatUint(uint64(e.Weight), 10)
+	}
+	return strings.Join(out, ",")
+}
+
+// TotalWeight returns the sum of the weights of all endpoints.
+func (w *WeightedEndpointsValue) TotalWeight() uint64 {
+	var total uint64
+	for _, e := range *w {
+		total += uint64(e.Weight)
+	}
+	return total
+}
+
+// Addrs lists the addresses of the endpoints, in order.
+func (w *WeightedEndpointsValue) Addrs() []string {
+	out := make([]string, len(*w))
+	for i, e := range *w {
+		out[i] = e.Addr
+	}
+	return out
+}
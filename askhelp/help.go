@@ -0,0 +1,95 @@
+// Package askhelp renders the help text of commands and flags, for terminals, markdown docs and man pages.
+package askhelp
+
+import "strings"
+
+// Format is a format to render help text in, see Render.
+type Format uint8
+
+const (
+	// Text renders help for a terminal.
+	Text Format = iota
+	// Markdown renders help for markdown docs.
+	Markdown
+	// Man renders help for man pages, in roff.
+	Man
+)
+
+// Render renders help text that contains simple markdown:
+// `code` in back-ticks, list items on lines starting with "- " or "* ", and line breaks.
+// For a terminal the back-ticks are removed, the lines are kept as-is.
+func Render(help string, format Format) string {
+	help = strings.TrimSpace(help)
+	if help == "" {
+		return ""
+	}
+	lines := strings.Split(help, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	switch format {
+	case Markdown:
+		for i, line := range lines {
+			// hard line break, unless the next line starts a new paragraph or list item already
+			if i+1 < len(lines) && line != "" && lines[i+1] != "" && !isListItem(lines[i+1]) {
+				lines[i] = line + "  "
+			}
+		}
+		return strings.Join(lines, "\n")
+	case Man:
+		var out strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				if line == "" {
+					out.WriteString("\n.sp")
+					continue
+				}
+				out.WriteString("\n.br\n")
+			}
+			if isListItem(line) {
+				out.WriteString(`\(bu `)
+				line = strings.TrimLeft(line[2:], " ")
+			}
+			out.WriteString(roffCode(RoffEscape(line)))
+		}
+		return out.String()
+	default:
+		return strings.ReplaceAll(help, "`", "")
+	}
+}
+
+func isListItem(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+// RoffEscape escapes backslashes, and control characters at the start of a line.
+func RoffEscape(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\e`)
+	if strings.HasPrefix(v, ".") || strings.HasPrefix(v, "'") {
+		v = `\&` + v
+	}
+	return v
+}
+
+// roffCode renders back-tick quoted code in bold.
+func roffCode(v string) string {
+	parts := strings.Split(v, "`")
+	if len(parts) < 3 {
+		return v
+	}
+	var out strings.Builder
+	for i, p := range parts {
+		// an unmatched back-tick at the end is kept as-is
+		if i == len(parts)-1 && i%2 == 1 {
+			out.WriteString("`")
+		} else if i%2 == 1 {
+			out.WriteString(`\fB`)
+			out.WriteString(p)
+			out.WriteString(`\fR`)
+			continue
+		}
+		out.WriteString(p)
+	}
+	return out.String()
+}
@@ -0,0 +1,32 @@
+package askhelp
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	help := "Connect to `addr`.\nModes:\n- fast\n- safe"
+	for format, expected := range map[Format]string{
+		Text:     "Connect to addr.\nModes:\n- fast\n- safe",
+		Markdown: "Connect to `addr`.  \nModes:\n- fast\n- safe",
+		Man:      "Connect to \\fBaddr\\fR.\n.br\nModes:\n.br\n\\(bu fast\n.br\n\\(bu safe",
+	} {
+		if got := Render(help, format); got != expected {
+			t.Errorf("format %d: expected %q, got %q", format, expected, got)
+		}
+	}
+	if got := Render("a `b", Man); got != "a `b" {
+		t.Errorf("expected unmatched back-tick to be kept, got %q", got)
+	}
+}
+
+func TestRoffEscape(t *testing.T) {
+	for v, expected := range map[string]string{
+		`C:\dir`:  `C:\edir`,
+		".hidden": `\&.hidden`,
+		"'quote":  `\&'quote`,
+		"plain":   "plain",
+	} {
+		if got := RoffEscape(v); got != expected {
+			t.Errorf("%q: expected %q, got %q", v, expected, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package askparse tokenizes command-line arguments with the syntax of ask, without knowledge of the flags,
+// e.g. to classify arguments for completion or highlighting without loading commands.
+package askparse
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kind is the kind of argument of a Token.
+type Kind uint8
+
+const (
+	// Positional is an argument that is not a flag, including "" and "-".
+	Positional Kind = iota
+	// Long is a long flag, like "--name" or "--name=value".
+	Long
+	// Short is a group of shorthand flags, like "-v", "-vvv" or "-p9000".
+	// Which shorthands take a value depends on the flags, see Token.Name.
+	Short
+	// Terminator is the "--" argument: the arguments after it are positional.
+	Terminator
+)
+
+// Token is an argument, classified by its syntax.
+type Token struct {
+	Kind Kind
+	// Arg is the argument the token was read from.
+	Arg string
+	// Name is the name of a long flag, e.g. "name" for "--name=x",
+	// or the shorthands of a short flag with everything attached, e.g. "p9000" for "-p9000".
+	Name string
+	// Value is the value attached to a long flag, e.g. "x" for "--name=x". Only valid if HasValue.
+	Value    string
+	HasValue bool
+}
+
+// Syntax configures the accepted syntax, in addition to the default syntax. Nil is the default syntax.
+type Syntax struct {
+	// ColonValues accepts `--flag:value` in addition to `--flag=value`.
+	ColonValues bool
+}
+
+// Tokenize is the same as Syntax.Tokenize, with the default syntax.
+func Tokenize(arg string) (Token, error) {
+	return (*Syntax)(nil).Tokenize(arg)
+}
+
+// Tokenize classifies the argument. A long flag without name, like "---x" or "--=x", is an error.
+// The value of a long flag is separated by the first separator, e.g. "--flag=a:b" has the value "a:b".
+func (s *Syntax) Tokenize(arg string) (Token, error) {
+	tok := Token{Arg: arg}
+	switch {
+	case len(arg) < 2 || arg[0] != '-':
+		tok.Kind = Positional
+	case arg == "--":
+		tok.Kind = Terminator
+	case arg[1] != '-':
+		tok.Kind = Short
+		tok.Name = arg[1:]
+	default:
+		tok.Kind = Long
+		name := arg[2:]
+		separators := "="
+		if s != nil && s.ColonValues {
+			separators = "=:"
+		}
+		if name[0] == '-' || strings.IndexByte(separators, name[0]) >= 0 {
+			return Token{}, fmt.Errorf("bad flag syntax: %s", arg)
+		}
+		if i := strings.IndexAny(name, separators); i >= 0 {
+			tok.Name, tok.Value, tok.HasValue = name[:i], name[i+1:], true
+		} else {
+			tok.Name = name
+		}
+	}
+	return tok, nil
+}
+
+// ReadResponseFile reads the arguments of a response file: one argument per line.
+// Surrounding whitespace and empty lines are ignored, and lines starting with `#` are comments.
+func ReadResponseFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response file: %w", err)
+	}
+	var args []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args = append(args, line)
+	}
+	return args, nil
+}
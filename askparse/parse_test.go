@@ -0,0 +1,33 @@
+package askparse
+
+import "testing"
+
+func TestTokenize(t *testing.T) {
+	for arg, expected := range map[string]Token{
+		"":             {Kind: Positional},
+		"-":            {Kind: Positional, Arg: "-"},
+		"x":            {Kind: Positional, Arg: "x"},
+		"--":           {Kind: Terminator, Arg: "--"},
+		"-vp9000":      {Kind: Short, Arg: "-vp9000", Name: "vp9000"},
+		"--name":       {Kind: Long, Arg: "--name", Name: "name"},
+		"--name=":      {Kind: Long, Arg: "--name=", Name: "name", HasValue: true},
+		"--name=a:b=c": {Kind: Long, Arg: "--name=a:b=c", Name: "name", Value: "a:b=c", HasValue: true},
+		"--name:a":     {Kind: Long, Arg: "--name:a", Name: "name:a"},
+	} {
+		if got, err := Tokenize(arg); err != nil || got != expected {
+			t.Errorf("%q: unexpected token %+v: %v", arg, got, err)
+		}
+	}
+	colon := &Syntax{ColonValues: true}
+	if got, err := colon.Tokenize("--name:a=b"); err != nil || got.Name != "name" || got.Value != "a=b" {
+		t.Errorf("unexpected colon token %+v: %v", got, err)
+	}
+	for _, arg := range []string{"---x", "--=x"} {
+		if _, err := Tokenize(arg); err == nil {
+			t.Errorf("%q: expected syntax error", arg)
+		}
+	}
+	if _, err := colon.Tokenize("--:x"); err == nil {
+		t.Error("expected colon syntax error")
+	}
+}
@@ -0,0 +1,54 @@
+// Package asktest provides helpers to test commands built with ask.
+package asktest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ask"
+)
+
+// MaxDepth limits how deep SmokeTest walks into sub-commands, since commands may be recursive.
+const MaxDepth = 8
+
+// SmokeTest walks every known route of the command recursively (see ask.CommandKnownRoutes),
+// and checks that each command loads, renders its usage, and has no issues with the given lint rules.
+// This is a one-call regression test, to ensure no route in a large command tree breaks.
+func SmokeTest(t testing.TB, cmd interface{}, rules ...ask.LintRule) {
+	t.Helper()
+	smokeTest(t, nil, cmd, rules)
+}
+
+func smokeTest(t testing.TB, path []string, cmd interface{}, rules []ask.LintRule) {
+	t.Helper()
+	name := strings.Join(append([]string{"(command)"}, path...), " ")
+	descr, err := ask.Load(cmd)
+	if err != nil {
+		t.Errorf("%s: failed to load: %v", name, err)
+		return
+	}
+	if descr.Usage(true) == "" {
+		t.Errorf("%s: empty usage", name)
+	}
+	for _, issue := range descr.Lint(rules...) {
+		t.Errorf("%s: lint: %s", name, issue)
+	}
+	if descr.CommandRoute == nil || len(path) >= MaxDepth {
+		return
+	}
+	knownRoutes, ok := descr.CommandRoute.(ask.CommandKnownRoutes)
+	if !ok {
+		return
+	}
+	for _, route := range knownRoutes.Routes() {
+		sub, err := descr.CommandRoute.Cmd(route)
+		if err != nil {
+			t.Errorf("%s: failed to get route %q: %v", name, route, err)
+			continue
+		}
+		if sub == nil {
+			continue
+		}
+		smokeTest(t, append(append([]string{}, path...), route), sub, rules)
+	}
+}
@@ -0,0 +1,91 @@
+package asktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/protolambda/ask"
+)
+
+type Root struct{}
+
+func (c *Root) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "hello":
+		return &Hello{}, nil
+	case "again":
+		return &Root{}, nil
+	default:
+		return nil, ask.UnrecognizedErr
+	}
+}
+
+func (c *Root) Routes() []string {
+	return []string{"hello", "again"}
+}
+
+type Hello struct {
+	Name string `ask:"--name" help:"Name to greet"`
+}
+
+func (c *Hello) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+type Broken struct{}
+
+func (c *Broken) Cmd(route string) (cmd interface{}, err error) {
+	return nil, errors.New("broken route")
+}
+
+func (c *Broken) Routes() []string {
+	return []string{"x"}
+}
+
+func TestSmokeTest(t *testing.T) {
+	SmokeTest(t, &Root{}, ask.HelpPresentRule)
+
+	var rec recorder
+	SmokeTest(&rec, &Broken{})
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected broken route to be reported, got: %v", rec.errors)
+	}
+}
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestBench(t *testing.T) {
+	if testing.Short() {
+		t.Skip("benchmarks take a while")
+	}
+	for _, mode := range []BenchMode{ParseOnly, FullRun} {
+		res := testing.Benchmark(func(b *testing.B) {
+			Bench(b, func() interface{} { return &Root{} }, mode, "hello", "--name=foo")
+		})
+		if res.N == 0 {
+			t.Fatalf("mode %d: benchmark did not run", mode)
+		}
+		if _, ok := res.Extra["p99-ns"]; !ok {
+			t.Fatalf("mode %d: expected latency percentiles, got: %v", mode, res.Extra)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	Table(t, func() interface{} { return &Root{} }, []Case{
+		{Args: []string{"hello", "--name=foo"}, Expect: &Hello{Name: "foo"}},
+		{Name: "nested", Args: []string{"again", "hello", "--name", "bar", "x", "y"}, Expect: &Hello{Name: "bar"}, Remaining: []string{"x", "y"}},
+		{Args: []string{"hello", "--unknown"}, Err: "unknown"},
+		{Args: []string{"nope"}, Err: ask.UnrecognizedErr.Error()},
+	})
+}
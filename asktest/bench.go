@@ -0,0 +1,53 @@
+package asktest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/protolambda/ask"
+)
+
+// BenchMode is what Bench measures of a command execution.
+type BenchMode uint8
+
+const (
+	// ParseOnly loads the command and parses the arguments, but does not run the command.
+	ParseOnly BenchMode = iota
+	// FullRun loads, parses and runs the command.
+	FullRun
+)
+
+// Bench executes a new command with the given arguments b.N times, to measure the overhead of loading and
+// parsing commands, e.g. when a command is executed for every request of a server.
+// Allocations are reported, and the p50, p90 and p99 latency of the executions as "p50-ns" etc. metrics.
+func Bench(b *testing.B, newCmd func() interface{}, mode BenchMode, args ...string) {
+	b.Helper()
+	opts := &ask.ExecutionOptions{ParseOnly: mode == ParseOnly}
+	ctx := context.Background()
+	latencies := make([]time.Duration, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		descr, err := ask.Load(newCmd())
+		if err != nil {
+			b.Fatalf("failed to load: %v", err)
+		}
+		if _, err := descr.Execute(ctx, opts, args...); err != nil {
+			b.Fatalf("failed to execute: %v", err)
+		}
+		latencies[i] = time.Since(start)
+	}
+	b.StopTimer()
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	for _, p := range []struct {
+		name string
+		q    int
+	}{{"p50-ns", 50}, {"p90-ns", 90}, {"p99-ns", 99}} {
+		b.ReportMetric(float64(latencies[(len(latencies)-1)*p.q/100].Nanoseconds()), p.name)
+	}
+}
@@ -0,0 +1,61 @@
+package asktest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ask"
+)
+
+// Case is a command execution to test with Table.
+type Case struct {
+	// Name of the sub-test. Defaults to the arguments.
+	Name string
+	// Args to execute the command with.
+	Args []string
+	// Expect is the expected command after parsing, e.g. a pointer to the struct with the expected flag values.
+	// It is compared to the executed (routed) command with reflect.DeepEqual. Nil to not compare.
+	Expect interface{}
+	// Err is a substring of the expected error. Empty if no error is expected.
+	Err string
+	// Remaining are the expected arguments that are left for Run, after flags and positional args.
+	Remaining []string
+}
+
+// Table executes a new command for each case, in a sub-test, and checks the parsed command,
+// the error and the remaining arguments. Commands are only parsed, not run (see ask.ExecutionOptions.ParseOnly).
+func Table(t *testing.T, newCmd func() interface{}, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		name := c.Name
+		if name == "" {
+			name = strings.Join(c.Args, " ")
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Helper()
+			descr, err := ask.Load(newCmd())
+			if err != nil {
+				t.Fatalf("failed to load: %v", err)
+			}
+			cmd, err := descr.Execute(context.Background(), &ask.ExecutionOptions{ParseOnly: true}, c.Args...)
+			if c.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.Err) {
+					t.Fatalf("expected error %q, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Expect != nil && !reflect.DeepEqual(cmd.Command, c.Expect) {
+				t.Errorf("unexpected command:\n got: %+v\nwant: %+v", cmd.Command, c.Expect)
+			}
+			if len(cmd.Remaining) != len(c.Remaining) || (len(c.Remaining) > 0 && !reflect.DeepEqual(cmd.Remaining, c.Remaining)) {
+				t.Errorf("unexpected remaining args: got %q, want %q", cmd.Remaining, c.Remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchOptions configures ExecuteAll.
+type BatchOptions struct {
+	// Options to execute each invocation with
+	ExecutionOptions
+	// Workers is the maximum number of invocations to execute concurrently.
+	// Defaults to GOMAXPROCS if 0 or less.
+	Workers int
+	// FailFast cancels the remaining invocations after the first error.
+	FailFast bool
+}
+
+// ExecuteAll executes multiple independent invocations of the command concurrently,
+// and returns the errors of all invocations joined together, in order of invocation.
+//
+// Invocations run concurrently on the same command description: each invocation must route to a sub-command,
+// and the routes must return a new sub-command on every call, so invocations do not share flag values.
+//
+// Invocations are not isolated at the root: the early flags of the root command (see Flag.Early) are shared,
+// and an invocation that does not route applies its flags and Configure to the root command.
+// The root keeps these values for the invocations after it, and after ExecuteAll, until it is Reset.
+// The records of the invocations (see ExecutionOptions.Record) are written one at a time.
+func (descr *CommandDescription) ExecuteAll(ctx context.Context, invocations [][]string, opts *BatchOptions) error {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	execOpts := opts.ExecutionOptions
+	if execOpts.Record != nil {
+		execOpts.Record = &lockedWriter{w: execOpts.Record}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(invocations))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				args := invocations[i]
+				if err := ctx.Err(); err != nil {
+					errs[i] = fmt.Errorf("invocation %d (%s) skipped: %w", i, strings.Join(args, " "), err)
+					continue
+				}
+				if _, err := descr.Execute(ctx, &execOpts, args...); err != nil {
+					errs[i] = fmt.Errorf("invocation %d (%s): %w", i, strings.Join(args, " "), err)
+					if opts.FailFast {
+						cancel()
+					}
+				}
+			}
+		}()
+	}
+	for i := range invocations {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// lockedWriter writes to the underlying writer one write at a time.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,77 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+type BatchRoute struct {
+	Sum *int64
+	Tag string `ask:"--tag" early:"true"`
+}
+
+func (c *BatchRoute) Cmd(route string) (cmd interface{}, err error) {
+	if route == "add" {
+		return &BatchAdd{Sum: c.Sum}, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+type BatchAdd struct {
+	Sum   *int64
+	Value int64 `ask:"<value>"`
+}
+
+func (c *BatchAdd) Run(ctx context.Context, args ...string) error {
+	if c.Value == 0 {
+		return errors.New("zero value")
+	}
+	atomic.AddInt64(c.Sum, c.Value)
+	return nil
+}
+
+func TestExecuteAll(t *testing.T) {
+	var sum int64
+	cmd, err := Load(&BatchRoute{Sum: &sum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invocations := [][]string{{"add", "1"}, {"add", "0"}, {"add", "3"}, {"sub", "4"}, {"add", "5"}}
+	err = cmd.ExecuteAll(context.Background(), invocations, &BatchOptions{Workers: 2})
+	if sum != 9 {
+		t.Fatalf("unexpected sum: %d", sum)
+	}
+	if err == nil || !errors.Is(err, UnrecognizedErr) ||
+		err.Error() != "invocation 1 (add 0): zero value\ninvocation 3 (sub 4): command was not recognized" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExecuteAllSharedRoot(t *testing.T) {
+	var sum int64
+	root := BatchRoute{Sum: &sum}
+	cmd, err := Load(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuredRoot := 0
+	opts := &BatchOptions{Workers: 1, ExecutionOptions: ExecutionOptions{Configure: func(c *CommandDescription) error {
+		if len(c.Route) == 0 {
+			configuredRoot++
+		}
+		return nil
+	}}}
+	invocations := [][]string{{"--tag=a", "add", "1"}, {"--tag=b"}, {"sub", "4"}, {"add", "2"}}
+	if err := cmd.ExecuteAll(context.Background(), invocations, opts); !errors.Is(err, UnrecognizedErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the root is shared by the invocations, and keeps their changes
+	if sum != 3 || root.Tag != "b" || configuredRoot != 1 {
+		t.Fatalf("unexpected root state: sum %d, tag %q, configured %d", sum, root.Tag, configuredRoot)
+	}
+	if err := cmd.Reset(); err != nil || root.Tag != "" {
+		t.Fatalf("expected reset root, got tag %q: %v", root.Tag, err)
+	}
+}
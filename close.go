@@ -0,0 +1,38 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Close cleans up the command after it ran: the command is closed if it implements io.Closer,
+// then the modules that are still set up (see ModuleSet),
+// and then every flag value that implements io.Closer, e.g. values that open a file or a connection, in reverse order.
+// All values are closed, even if closing the command fails, and the errors are joined.
+// See ExecutionOptions.Close to have Execute close the command, Main always does.
+func (descr *CommandDescription) Close() error {
+	var errs []error
+	if cl, ok := descr.Command.(io.Closer); ok {
+		if err := cl.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close command: %w", err))
+		}
+	}
+	if err := descr.closeModules(context.Background()); err != nil {
+		errs = append(errs, err)
+	}
+	all := descr.All("")
+	for i := len(all) - 1; i >= 0; i-- {
+		pf := all[i]
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		if cl, ok := pf.Value.(io.Closer); ok {
+			if err := cl.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s: %w", flagDecl(pf), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,109 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// OutputValue opens the file to write to when it is set.
+type OutputValue struct {
+	File   *os.File
+	closed int
+}
+
+func (v *OutputValue) String() string {
+	if v.File == nil {
+		return ""
+	}
+	return v.File.Name()
+}
+
+func (v *OutputValue) Set(s string) error {
+	if s == "" {
+		return nil
+	}
+	f, err := os.Create(s)
+	if err != nil {
+		return err
+	}
+	v.File = f
+	return nil
+}
+
+func (v *OutputValue) Close() error {
+	v.closed++
+	if v.File == nil {
+		return errors.New("no output")
+	}
+	return v.File.Close()
+}
+
+type WriteCmd struct {
+	Out OutputValue `ask:"--out"`
+}
+
+func (c *WriteCmd) Run(ctx context.Context, args ...string) error {
+	_, err := c.Out.File.WriteString(strings.Join(args, " "))
+	return err
+}
+
+type ServeCmd struct {
+	Out    OutputValue `ask:"--out"`
+	closed bool
+}
+
+func (c *ServeCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func (c *ServeCmd) Close() error {
+	if c.Out.closed != 0 {
+		return errors.New("expected command to be closed before its flag values")
+	}
+	c.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	var c WriteCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "out.txt")
+	if _, err := cmd.Execute(context.Background(), nil, "--out="+path, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Out.closed != 0 {
+		t.Fatal("expected values not to be closed by default")
+	}
+	_ = c.Out.File.Close()
+	if _, err := cmd.Execute(context.Background(), &ExecutionOptions{Close: true}, "--out="+path, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "a b" || c.Out.closed != 1 {
+		t.Fatalf("expected output to be written and closed, got %q (closed %d): %v", data, c.Out.closed, err)
+	}
+
+	var s ServeCmd
+	serve, err := Load(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = serve.Execute(ctx, &ExecutionOptions{Close: true})
+	if !s.closed || time.Since(start) < 20*time.Millisecond {
+		t.Fatal("expected long-running command to be closed after the context is canceled")
+	}
+	if err == nil || !strings.Contains(err.Error(), "failed to close --out: no output") {
+		t.Fatalf("expected close error of the flag value, got: %v", err)
+	}
+}
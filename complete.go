@@ -0,0 +1,152 @@
+//go:build !ask_minimal
+
+package ask
+
+import "strings"
+
+// Suggest returns the candidates for the last of the partial arguments, i.e. the token that is being typed,
+// which may be empty. The arguments before it are routed and parsed, and invalid arguments are skipped.
+// This is the shared basis of completion in a shell, REPL or TUI.
+func (descr *CommandDescription) Suggest(partialArgs []string) []Suggestion {
+	partial := ""
+	var args []string
+	if n := len(partialArgs); n > 0 {
+		args, partial = partialArgs[:n-1], partialArgs[n-1]
+	}
+	cmd := descr
+	for len(args) > 0 && cmd.CommandRoute != nil {
+		sub, err := cmd.CommandRoute.Cmd(args[0])
+		if err != nil || sub == nil {
+			break
+		}
+		subCmd, err := LoadWithOptions(sub, cmd.LoadOptions)
+		if err != nil {
+			return nil
+		}
+		cmd, args = subCmd, args[1:]
+	}
+	p := cmd.Parser(nil, nil)
+	routed := len(args) == 0
+	for _, a := range args {
+		_ = p.Feed(a)
+	}
+	var out []Suggestion
+	switch p.State() {
+	case ExpectValue:
+		fl, _ := p.Pending()
+		out = valueSuggestions(fl, partial)
+	case ExpectAny:
+		if strings.HasPrefix(partial, "-") {
+			return p.flagSuggestions(partial)
+		}
+		if routed {
+			routes, help := cmd.subCommands()
+			for i, r := range routes {
+				if strings.HasPrefix(r, partial) {
+					out = append(out, Suggestion{Value: r, Description: firstLine(help[i]), Kind: SuggestRoute})
+				}
+			}
+		}
+		fallthrough
+	case ExpectPositional:
+		if fl, ok := cmd.positional(len(p.Remaining())); ok {
+			out = append(out, valueSuggestions(fl, partial)...)
+		}
+	}
+	return out
+}
+
+// positional returns the positional argument at the given index, if any.
+func (descr *CommandDescription) positional(i int) (PrefixedFlag, bool) {
+	_, _, required, optional := descr.sortedFlags()
+	args := append(required, optional...)
+	if i >= len(args) {
+		return PrefixedFlag{}, false
+	}
+	return args[i], true
+}
+
+// valueSuggestions suggests the candidate values of a flag, or a file hint for a string flag.
+func valueSuggestions(fl PrefixedFlag, partial string) []Suggestion {
+	if out := completeValue(fl, partial); out != nil {
+		return out
+	}
+	if typed, ok := fl.Value.(TypedValue); ok && typed.Type() == "string" {
+		return []Suggestion{{Value: partial, Description: flagDescription(fl), Kind: SuggestFile}}
+	}
+	return nil
+}
+
+// completeValue lists the allowed values of an enum flag, or the candidates of a ValueCompleter,
+// that start with the partial value.
+func completeValue(fl PrefixedFlag, partial string) []Suggestion {
+	var candidates []string
+	if enum, ok := fl.Value.(*EnumValue); ok {
+		candidates = enum.Allowed
+	} else if c, ok := fl.Value.(ValueCompleter); ok {
+		candidates = c.Complete(partial)
+	} else {
+		return nil
+	}
+	out := []Suggestion{}
+	for _, v := range candidates {
+		if strings.HasPrefix(v, partial) {
+			out = append(out, Suggestion{Value: v, Description: flagDescription(fl), Kind: SuggestValue})
+		}
+	}
+	return out
+}
+
+// flagDescription describes a flag briefly, with the first line of the help and the metadata of the flag.
+func flagDescription(fl PrefixedFlag) string {
+	out := firstLine(fl.Help)
+	if meta := fl.meta(); len(meta) > 0 {
+		out = strings.TrimSpace(out + " (" + strings.Join(meta, ", ") + ")")
+	}
+	return out
+}
+
+// firstLine returns the first line of a help text, for brief descriptions.
+func firstLine(v string) string {
+	v, _, _ = strings.Cut(strings.TrimSpace(v), "\n")
+	return v
+}
+
+// Candidates lists the possible completions of the partial next argument:
+// the allowed values of the pending flag if it is an EnumValue or ValueCompleter, or else flags if the partial argument starts with "-".
+// See CommandDescription.Suggest for completions with descriptions, including sub-commands.
+func (p *Parser) Candidates(partial string) []string {
+	var suggestions []Suggestion
+	switch p.State() {
+	case ExpectValue:
+		suggestions = completeValue(p.pendingFlag, partial)
+	case ExpectAny:
+		suggestions = p.flagSuggestions(partial)
+	}
+	var out []string
+	for _, s := range suggestions {
+		out = append(out, s.Value)
+	}
+	return out
+}
+
+// flagSuggestions lists the visible flags that start with the partial argument, if it starts with "-".
+func (p *Parser) flagSuggestions(partial string) []Suggestion {
+	if !strings.HasPrefix(partial, "-") {
+		return nil
+	}
+	var out []Suggestion
+	for _, fl := range p.long {
+		if v := "--" + fl.Path; strings.HasPrefix(v, partial) && !fl.Hidden {
+			out = append(out, Suggestion{Value: v, Description: flagDescription(fl), Kind: SuggestFlag})
+		}
+	}
+	if len(partial) <= 2 && !strings.HasPrefix(partial, "--") {
+		for _, fl := range p.short {
+			if v := "-" + string(fl.Shorthand); strings.HasPrefix(v, partial) && !fl.Hidden {
+				out = append(out, Suggestion{Value: v, Description: flagDescription(fl), Kind: SuggestFlag})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// UserConfigPath is the conventional location of the config file of an application:
+// "$XDG_CONFIG_HOME/<app>/config.yaml" (or "~/.config/<app>/config.yaml") on Unix,
+// "~/Library/Application Support/<app>/config.yaml" on macOS, and "%AppData%\<app>\config.yaml" on Windows.
+func UserConfigPath(app string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, app, "config.yaml"), nil
+}
+
+// LoadConfigFile reads the flag values of the YAML config file at the given path, see ReadConfigYAML.
+// A missing file results in no values.
+func LoadConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values, err := ReadConfigYAML(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// UserConfig returns an ExecutionOptions.Configure function that applies the user config file of the application,
+// see UserConfigPath, with SetFromMap and a "config:" source. A missing file is not an error.
+// Keys that are not flags of the command that runs are ignored, so one file can configure all sub-commands.
+func UserConfig(app string) func(cmd *CommandDescription) error {
+	return userConfig(app, false)
+}
+
+// StrictUserConfig is like UserConfig, but reports keys that are not flags of the command that runs as errors,
+// like invalid values, for applications where every key of the file is meant for the command, to catch typos.
+func StrictUserConfig(app string) func(cmd *CommandDescription) error {
+	return userConfig(app, true)
+}
+
+func userConfig(app string, strict bool) func(cmd *CommandDescription) error {
+	return func(cmd *CommandDescription) error {
+		path, err := UserConfigPath(app)
+		if err != nil {
+			return err
+		}
+		values, err := LoadConfigFile(path)
+		if err != nil {
+			return err
+		}
+		if !strict {
+			for key := range values {
+				if _, ok := cmd.Lookup(key); !ok {
+					delete(values, key)
+				}
+			}
+		}
+		return cmd.SetFromMap(values, "config:"+path)
+	}
+}
+
+// ReadConfigYAML reads flag values by path from YAML.
+// Nested mappings are flattened into dotted paths, e.g. `tag: fav` nested in `peer:` is the value of "peer.tag",
+// and sequences of scalars are joined with commas, like the values of slice flags. Null values are omitted.
+//
+// Only a subset of YAML is supported, without a YAML dependency: block mappings, block sequences of scalars,
+// flow sequences of scalars like `[a, "b"]`, comments, and plain, single-quoted and double-quoted scalars,
+// on a single line each. Double-quoted scalars support the YAML escape sequences.
+// Anchors, aliases, tags, flow mappings, multi-line scalars and multiple documents are rejected or not supported.
+func ReadConfigYAML(r io.Reader) (map[string]string, error) {
+	type level struct {
+		indent int
+		path   string
+	}
+	values := make(map[string]string)
+	stack := []level{{indent: -1}}
+	// the path of the last key without value, that sequence items belong to
+	pending := ""
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if text[0] == '\t' {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", n)
+		}
+		indent := len(line) - len(text)
+		if text == "-" || strings.HasPrefix(text, "- ") {
+			if pending == "" {
+				return nil, fmt.Errorf("line %d: sequence item without key", n)
+			}
+			item, null, err := parseYAMLScalar(text[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if null {
+				continue
+			}
+			if prev, ok := values[pending]; ok {
+				item = prev + "," + item
+			}
+			values[pending] = item
+			continue
+		}
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		key, rest, ok := cutYAMLKey(text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key, got %q", n, text)
+		}
+		if k, null, err := parseYAMLScalar(key); err != nil || null {
+			return nil, fmt.Errorf("line %d: invalid key %q", n, key)
+		} else {
+			key = k
+		}
+		path := key
+		if parent := stack[len(stack)-1].path; parent != "" {
+			path = parent + "." + key
+		}
+		if rest == "" {
+			pending = path
+			stack = append(stack, level{indent: indent, path: path})
+			continue
+		}
+		pending = ""
+		value, null, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if !null {
+			values[path] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// stripYAMLComment removes a comment from the line: a `#` at the start or after whitespace, outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// cutYAMLKey splits a mapping entry into the key and the remaining value, at the first colon outside of quotes
+// that is followed by whitespace or the end of the line.
+func cutYAMLKey(text string) (key string, rest string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar parses a plain, quoted or null scalar, or a flow sequence of scalars, which is joined with commas.
+func parseYAMLScalar(s string) (value string, null bool, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == "~" || s == "null":
+		return "", true, nil
+	case s[0] == '"':
+		v, err := unquoteYAML(s)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid double-quoted value %s: %w", s, err)
+		}
+		return v, false, nil
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", false, fmt.Errorf("invalid single-quoted value %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), false, nil
+	case s[0] == '[':
+		if s[len(s)-1] != ']' {
+			return "", false, fmt.Errorf("invalid flow sequence %s", s)
+		}
+		var items []string
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+			v, null, err := parseYAMLScalar(item)
+			if err != nil {
+				return "", false, err
+			}
+			if !null {
+				items = append(items, v)
+			}
+		}
+		return strings.Join(items, ","), false, nil
+	case s[0] == '{' || s[0] == '&' || s[0] == '*' || s[0] == '|' || s[0] == '>':
+		return "", false, fmt.Errorf("unsupported YAML value %s", s)
+	default:
+		return s, false, nil
+	}
+}
+
+// splitYAMLFlow splits the items of a flow sequence at the commas outside of quotes.
+func splitYAMLFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// yamlEscapes are the single-character escape sequences of double-quoted YAML scalars.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f", 'r': "\r",
+	'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// unquoteYAML unquotes a double-quoted YAML scalar, with the YAML escape sequences, e.g. "\e" and "\x41".
+func unquoteYAML(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return "", errors.New("missing closing quote")
+	}
+	s = s[1 : len(s)-1]
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' {
+			return "", errors.New("unescaped quote")
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			continue
+		}
+		if i+1 == len(s) {
+			return "", errors.New("incomplete escape sequence")
+		}
+		i++
+		if v, ok := yamlEscapes[s[i]]; ok {
+			out.WriteString(v)
+			continue
+		}
+		size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+		if size == 0 || i+size >= len(s) {
+			return "", fmt.Errorf("invalid escape sequence \\%c", s[i])
+		}
+		code, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+		if err != nil || code > unicode.MaxRune {
+			return "", fmt.Errorf("invalid escape sequence \\%s", s[i:i+1+size])
+		}
+		out.WriteRune(rune(code))
+		i += size
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,101 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigYAML(t *testing.T) {
+	values, err := ReadConfigYAML(strings.NewReader(`---
+# generated
+port: 9000 # inline comment
+name: "a #b"
+empty: ~
+peer:
+  tag: 'it''s'
+  deep:
+    x: 1
+labels:
+  - a
+  - "b,c"
+bootnodes: [x, 'y']
+quoted: ["a,b", c, 'd,''e']
+escaped: "\e\x41\u00e9\_\/"
+after: z
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"port": "9000", "name": "a #b", "peer.tag": "it's", "peer.deep.x": "1",
+		"labels": "a,b,c", "bootnodes": "x,y", "quoted": "a,b,c,d,'e", "escaped": "\x1bA\u00e9\u00a0/",
+		"after": "z"}
+	if len(values) != len(expected) {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Fatalf("expected %s to be %q, got %q", k, v, values[k])
+		}
+	}
+	for _, bad := range []string{"\tport: 1\n", "- a\n", "port\n", "port: {a: 1}\n", "port: \"x\n", "port: \"\\q\"\n", "port: \"\\x4\"\n"} {
+		if _, err := ReadConfigYAML(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestUserConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path, err := UserConfigPath("myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := Load(&Peer{ActorState: &ActorState{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &ExecutionOptions{ParseOnly: true, Configure: UserConfig("myapp")}
+	// a missing config file is not an error
+	if _, err := cmd.Execute(context.Background(), opts, "connect", "id", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("port: 4000\naddr: 1.2.3.4\npeer:\n  tag: fav\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	final, err := cmd.Execute(context.Background(), opts, "connect", "--port=5000", "id", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := final.Command.(*Connect)
+	if c.Port != 5000 || c.Addr.String() != "1.2.3.4" || c.Tag != "fav" {
+		t.Fatalf("unexpected values: %d %s %q", c.Port, c.Addr, c.Tag)
+	}
+	if src := final.Source("addr"); src != "config:"+path {
+		t.Fatalf("unexpected source: %q", src)
+	}
+	// keys that are not flags of the command are ignored, they may be for another command
+	if err := os.WriteFile(path, []byte("port: 4000\nverbosity: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	final, err = cmd.Execute(context.Background(), opts, "connect", "id", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := final.Command.(*Connect); c.Port != 4000 {
+		t.Fatalf("unexpected port: %d", c.Port)
+	}
+	// unless the config is strict
+	opts.Configure = StrictUserConfig("myapp")
+	if _, err := cmd.Execute(context.Background(), opts, "connect", "id", "1"); err == nil || !strings.Contains(err.Error(), `unknown flag "verbosity"`) {
+		t.Fatalf("expected unknown key error, got: %v", err)
+	}
+}
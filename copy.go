@@ -0,0 +1,139 @@
+package ask
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// copier replaces the shared references in a value with copies,
+// pointers that are shared within the value stay shared within the copy.
+type copier struct {
+	seen map[copyKey]reflect.Value
+	// tags of the unexported fields that are copied too, because they declare flags, see LoadOptions.Unexported
+	tags []string
+}
+
+// deepCopy returns a copy of the value that shares no pointers, slices, maps or interfaces with the original,
+// through exported fields. Unexported fields are opaque state of their type, and copied shallowly.
+// Functions and channels are shared.
+func deepCopy(v reflect.Value) reflect.Value {
+	return (&copier{seen: make(map[copyKey]reflect.Value)}).copy(v)
+}
+
+// restoreCopy sets dst to a deep copy of src, through the pointers that both have,
+// so the pointees of dst stay the same and values bound to them stay bound.
+func restoreCopy(dst, src reflect.Value) {
+	for dst.Kind() == reflect.Ptr && !dst.IsNil() && !src.IsNil() {
+		dst, src = dst.Elem(), src.Elem()
+	}
+	dst.Set(deepCopy(src))
+}
+
+// copyTarget returns a deep copy of the target of a command, see LoadOptions.Copy.
+// With LoadOptions.Unexported, the unexported fields that declare flags, groups or changed markers are copied deeply too.
+func (opts *LoadOptions) copyTarget(v reflect.Value) reflect.Value {
+	c := &copier{seen: make(map[copyKey]reflect.Value)}
+	if opts.Unexported {
+		c.tags = append([]string{opts.tagName(), "changed"}, opts.LegacyTagNames...)
+	}
+	return c.copy(v)
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Type()).Elem()
+	cp.Set(v)
+	c.deepen(cp)
+	return cp
+}
+
+// declaresFlags checks if the unexported field is tagged to declare flags, and should be copied deeply.
+func (c *copier) declaresFlags(f reflect.StructField) bool {
+	for _, tag := range c.tags {
+		if _, ok := f.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// deepen replaces the references in the settable value with copies.
+func (c *copier) deepen(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		key := copyKey{ptr: v.Pointer(), typ: v.Type()}
+		if n, ok := c.seen[key]; ok {
+			v.Set(n)
+			return
+		}
+		n := reflect.New(v.Type().Elem())
+		n.Elem().Set(v.Elem())
+		c.seen[key] = n
+		c.deepen(n.Elem())
+		v.Set(n)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		e := v.Elem()
+		n := reflect.New(e.Type()).Elem()
+		n.Set(e)
+		c.deepen(n)
+		v.Set(n)
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Cap())
+		reflect.Copy(n, v)
+		for i := 0; i < n.Len(); i++ {
+			c.deepen(n.Index(i))
+		}
+		v.Set(n)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.deepen(v.Index(i))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := reflect.New(v.Type().Key()).Elem()
+			k.Set(iter.Key())
+			c.deepen(k)
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(iter.Value())
+			c.deepen(e)
+			n.SetMapIndex(k, e)
+		}
+		v.Set(n)
+	case reflect.Struct:
+		// the location of a time is compared by pointer, and immutable
+		if v.Type() == timeType {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanSet() {
+				if !c.declaresFlags(v.Type().Field(i)) {
+					continue
+				}
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			c.deepen(f)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package ask
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FlagChange is a change of a flag value.
+type FlagChange struct {
+	Path string
+	Old  string
+	New  string
+	// Secret flags have their values redacted when rendered.
+	Secret bool
+}
+
+// FlagChanges is a list of flag value changes, ordered by flag path.
+type FlagChanges []FlagChange
+
+// Values returns the current value of every flag and positional arg, by path.
+// The values are rendered with Flag.Canonical, or with String if the value has no canonical rendering.
+func (descr *CommandDescription) Values() map[string]string {
+	out := make(map[string]string)
+	for _, pf := range descr.All("") {
+		// deprecated args share the value with their replacement
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		v, err := pf.Canonical()
+		if err != nil {
+			v = pf.Value.String()
+		}
+		out[pf.Path] = v
+	}
+	return out
+}
+
+// Diff compares the current flag values with the given values by path, e.g. the values of a new config file,
+// to review what applying the values would change. The values are parsed like the flags would parse them,
+// so equal values with different renderings, like "60s" and "1m0s", are not changes.
+// Paths that are not flags and values that cannot be parsed are returned as error.
+func (descr *CommandDescription) Diff(values map[string]string) (FlagChanges, error) {
+	paths := make([]string, 0, len(values))
+	for p := range values {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var changes FlagChanges
+	var errs []error
+	for _, p := range paths {
+		pf, ok := descr.Lookup(p)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unrecognized flag: %s", p))
+			continue
+		}
+		old, err := pf.Canonical()
+		if err != nil {
+			old = pf.Value.String()
+		}
+		new, err := pf.parseCanonical(values[p])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for flag %s: %w", p, err))
+			continue
+		}
+		if old != new {
+			changes = append(changes, FlagChange{Path: p, Old: old, New: values[p], Secret: pf.Secret})
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// parseCanonical parses the value like the flag would, and renders it canonically, see Canonical.
+// The value is parsed into a copy of the current value, the flag is left alone.
+// Values that are not bound to a field are compared as given.
+func (f *Flag) parseCanonical(value string) (string, error) {
+	if !f.dest.IsValid() {
+		return value, nil
+	}
+	scratch := reflect.New(f.dest.Type()).Elem()
+	scratch.Set(deepCopy(f.dest))
+	v, err := f.bind(scratch)
+	if err != nil {
+		return "", err
+	}
+	cp := Flag{Value: v, Transforms: f.Transforms}
+	if err := cp.Set(value); err != nil {
+		return "", err
+	}
+	return cp.Canonical()
+}
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorBold  = "\x1b[1m"
+	colorReset = "\x1b[0m"
+)
+
+// DiffString renders the changes in unified-diff style, optionally colored with ANSI escape codes for a terminal.
+// Without changes the rendering is empty.
+func (changes FlagChanges) DiffString(color bool) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	line := func(c string, v string) {
+		if color {
+			out.WriteString(c)
+		}
+		out.WriteString(v)
+		if color {
+			out.WriteString(colorReset)
+		}
+		out.WriteString("\n")
+	}
+	line(colorBold, "--- current")
+	line(colorBold, "+++ new")
+	for _, c := range changes {
+		old, new := c.Old, c.New
+		if c.Secret {
+			old, new = RedactedValue, RedactedValue
+		}
+		line(colorRed, "-"+c.Path+"="+old)
+		line(colorGreen, "+"+c.Path+"="+new)
+	}
+	return out.String()
+}
@@ -0,0 +1,66 @@
+package ask
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type DiffCmd struct {
+	Addr    string        `ask:"--addr"`
+	Port    uint16        `ask:"--port"`
+	Token   string        `ask:"--token" secret:"true"`
+	Timeout time.Duration `ask:"--timeout"`
+	Retries *int          `ask:"--retries"`
+}
+
+func TestDiff(t *testing.T) {
+	cmd, err := Load(&DiffCmd{Addr: "localhost", Port: 9000, Token: "abc", Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := cmd.Diff(map[string]string{"addr": "localhost", "port": "9001", "token": "def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "--- current\n+++ new\n-port=9000\n+port=9001\n-token=***\n+token=***\n"
+	if got := changes.DiffString(false); got != expected {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := changes.DiffString(true); !strings.Contains(got, colorRed+"-port=9000"+colorReset) {
+		t.Fatalf("expected colored diff, got: %q", got)
+	}
+	if _, err := cmd.Diff(map[string]string{"unknown": "1"}); err == nil {
+		t.Fatal("expected error for unknown flag")
+	}
+	// equal values with a different rendering are not changes
+	changes, err = cmd.Diff(map[string]string{"port": "0x2328", "timeout": "60s"})
+	if err != nil || len(changes) != 0 || changes.DiffString(false) != "" {
+		t.Fatalf("unexpected changes: %v: %v", changes, err)
+	}
+	if _, err := cmd.Diff(map[string]string{"port": "x"}); err == nil {
+		t.Fatal("expected error for invalid value")
+	}
+	if v := cmd.Values(); v["port"] != "9000" || v["addr"] != "localhost" || v["timeout"] != "1m0s" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+}
+
+func TestDiffLeavesFields(t *testing.T) {
+	c := DiffCmd{Port: 9000}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retries := c.Retries
+	changes, err := cmd.Diff(map[string]string{"port": "9001", "retries": "3"})
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("unexpected changes: %v: %v", changes, err)
+	}
+	if c.Port != 9000 || c.Retries != retries || *c.Retries != 0 {
+		t.Fatalf("expected the fields to be left alone, got port %d and retries %d", c.Port, *c.Retries)
+	}
+	if pf, _ := cmd.Lookup("retries"); pf.Set("5") != nil || *c.Retries != 5 {
+		t.Fatalf("expected the flag to stay bound to the field: %v", err)
+	}
+}
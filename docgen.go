@@ -0,0 +1,145 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/protolambda/ask/askhelp"
+)
+
+// Markdown renders the documentation of the command, with the given command name, as markdown.
+// Hidden flags are not included. Of the usage options, WithoutZeroDefaults applies to the docs too.
+func (descr *CommandDescription) Markdown(name string, options ...UsageOption) string {
+	opts := &UsageOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+	var out strings.Builder
+	out.WriteString("# ")
+	out.WriteString(name)
+	out.WriteString("\n\n")
+	if descr.Help != nil {
+		if h := RenderHelp(descr.Help.Help(), HelpMarkdown); h != "" {
+			out.WriteString(h)
+			out.WriteString("\n\n")
+		}
+	}
+	var flags []PrefixedFlag
+	for _, pf := range descr.All("") {
+		if !pf.Hidden {
+			flags = append(flags, pf)
+		}
+	}
+	if len(flags) > 0 {
+		out.WriteString("## Flags\n\n")
+		for _, pf := range flags {
+			out.WriteString("- `")
+			out.WriteString(flagDecl(pf))
+			out.WriteString("`")
+			if details := flagDetails(pf, opts); len(details) > 0 {
+				out.WriteString(" (")
+				out.WriteString(strings.Join(details, ", "))
+				out.WriteString(")")
+			}
+			if h := RenderHelp(pf.Help, HelpMarkdown); h != "" {
+				out.WriteString(":\n  ")
+				out.WriteString(strings.ReplaceAll(h, "\n", "\n  "))
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	if routes, help := descr.subCommands(); len(routes) > 0 {
+		out.WriteString("## Sub commands\n\n")
+		for i, k := range routes {
+			out.WriteString("- `")
+			out.WriteString(k)
+			out.WriteString("`")
+			if h := RenderHelp(help[i], HelpMarkdown); h != "" {
+				out.WriteString(": ")
+				out.WriteString(strings.ReplaceAll(h, "\n", "\n  "))
+			}
+			out.WriteString("\n")
+		}
+		if note := descr.routePolicyNote(); note != "" {
+			out.WriteString("\n")
+			out.WriteString(note)
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	if descr.Epilogue != nil {
+		if e := RenderHelp(descr.Epilogue.Epilogue(), HelpMarkdown); e != "" {
+			out.WriteString(e)
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
+
+// Man renders the documentation of the command, with the given command name, as man page in the given section.
+// Hidden flags are not included. Of the usage options, WithoutZeroDefaults applies to the docs too.
+func (descr *CommandDescription) Man(name string, section int, options ...UsageOption) string {
+	opts := &UsageOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, ".TH %q %d\n", strings.ToUpper(name), section)
+	out.WriteString(".SH NAME\n")
+	out.WriteString(askhelp.RoffEscape(name))
+	out.WriteString("\n")
+	if descr.Help != nil {
+		if h := RenderHelp(descr.Help.Help(), HelpMan); h != "" {
+			out.WriteString(".SH DESCRIPTION\n")
+			out.WriteString(h)
+			out.WriteString("\n")
+		}
+	}
+	var flags []PrefixedFlag
+	for _, pf := range descr.All("") {
+		if !pf.Hidden {
+			flags = append(flags, pf)
+		}
+	}
+	if len(flags) > 0 {
+		out.WriteString(".SH OPTIONS\n")
+		for _, pf := range flags {
+			out.WriteString(".TP\n\\fB")
+			out.WriteString(strings.ReplaceAll(askhelp.RoffEscape(flagDecl(pf)), "-", `\-`))
+			out.WriteString("\\fR")
+			if details := flagDetails(pf, opts); len(details) > 0 {
+				out.WriteString(" (")
+				out.WriteString(askhelp.RoffEscape(strings.Join(details, ", ")))
+				out.WriteString(")")
+			}
+			out.WriteString("\n")
+			if h := RenderHelp(pf.Help, HelpMan); h != "" {
+				out.WriteString(h)
+				out.WriteString("\n")
+			}
+		}
+	}
+	if routes, help := descr.subCommands(); len(routes) > 0 {
+		out.WriteString(".SH COMMANDS\n")
+		for i, k := range routes {
+			out.WriteString(".TP\n\\fB")
+			out.WriteString(askhelp.RoffEscape(k))
+			out.WriteString("\\fR\n")
+			if h := RenderHelp(help[i], HelpMan); h != "" {
+				out.WriteString(h)
+				out.WriteString("\n")
+			}
+		}
+	}
+	if descr.Epilogue != nil {
+		if e := RenderHelp(descr.Epilogue.Epilogue(), HelpMan); e != "" {
+			out.WriteString(".SH NOTES\n")
+			out.WriteString(e)
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,90 @@
+package ask
+
+import "github.com/protolambda/ask/askhelp"
+
+// HelpFormat is a format to render help text in, see RenderHelp.
+type HelpFormat = askhelp.Format
+
+const (
+	// HelpText renders help for a terminal.
+	HelpText = askhelp.Text
+	// HelpMarkdown renders help for markdown docs.
+	HelpMarkdown = askhelp.Markdown
+	// HelpMan renders help for man pages, in roff.
+	HelpMan = askhelp.Man
+)
+
+// RenderHelp is the same as askhelp.Render.
+func RenderHelp(help string, format HelpFormat) string {
+	return askhelp.Render(help, format)
+}
+
+// flagDecl formats how a flag is used on the command-line, e.g. "-p, --peer.port" or "<peer.id>".
+func flagDecl(pf PrefixedFlag) string {
+	if pf.IsArg {
+		if pf.Required {
+			return "<" + pf.Path + ">"
+		}
+		return "[" + pf.Path + "]"
+	}
+	if pf.Shorthand == 0 {
+		return "--" + pf.Path
+	}
+	if pf.Name == string(pf.Shorthand) {
+		return "-" + pf.Name
+	}
+	return "-" + string(pf.Shorthand) + ", --" + pf.Path
+}
+
+// flagDetails lists the default, type, metadata and deprecation of a flag, e.g. "default: 9000".
+func flagDetails(pf PrefixedFlag, opts *UsageOptions) []string {
+	var details []string
+	if pf.Default != "" && !pf.HideDefault && !(opts.HideZeroDefaults && pf.isZeroDefault()) {
+		details = append(details, "default: "+pf.usageDefault())
+	}
+	if tv, ok := pf.Value.(TypedValue); ok && tv.Type() != "" {
+		details = append(details, "type: "+tv.Type())
+	}
+	details = append(details, pf.meta()...)
+	if pf.Deprecated != "" {
+		details = append(details, "DEPRECATED: "+pf.Deprecated)
+	}
+	return details
+}
+
+// subCommands lists the known routes of the command, with the help of each sub-command.
+func (descr *CommandDescription) subCommands() (routes []string, help []string) {
+	if descr.CommandRoute == nil {
+		return nil, nil
+	}
+	knownRoutes, ok := descr.CommandRoute.(CommandKnownRoutes)
+	if !ok {
+		return nil, nil
+	}
+	for _, k := range knownRoutes.Routes() {
+		h := ""
+		if subCmd, err := descr.CommandRoute.Cmd(k); err == nil && subCmd != nil {
+			if subDescr, err := LoadWithOptions(subCmd, descr.LoadOptions); err == nil && subDescr.Help != nil {
+				h = subDescr.Help.Help()
+			}
+		}
+		routes = append(routes, k)
+		help = append(help, h)
+	}
+	return routes, help
+}
+
+// routePolicyNote explains the RoutePolicy in help, if the command accepts arguments that may match a sub-command.
+func (descr *CommandDescription) routePolicyNote() string {
+	if !descr.acceptsArgs() {
+		return ""
+	}
+	switch descr.routePolicy() {
+	case PreferCommand:
+		return "Arguments are passed to this command, also if they match a sub command."
+	case RouteAmbiguityError:
+		return "Arguments that match a sub command are rejected, pass them after -- instead."
+	default:
+		return "Arguments that match a sub command run the sub command."
+	}
+}
@@ -0,0 +1,124 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type DocsCmd struct {
+	Addr string `ask:"--addr" help:"Address to connect to.\nFormats:\n- host:port\n- ip"`
+	Peer string `ask:"<peer>" help:"Peer ID"`
+}
+
+func (c *DocsCmd) Help() string {
+	return "Connect with `docs`"
+}
+
+func TestDocs(t *testing.T) {
+	cmd, err := Load(&DocsCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := cmd.Usage(false)
+	if !strings.Contains(usage, "Address to connect to.\n"+strings.Repeat(" ", 30)+"Formats:") {
+		t.Fatalf("expected help lines to be indented, got: %s", usage)
+	}
+	md := cmd.Markdown("app")
+	if !strings.Contains(md, "- `--addr` (type: string):\n  Address to connect to.  \n  Formats:\n  - host:port") ||
+		!strings.Contains(md, "- `<peer>` (type: string):\n  Peer ID") {
+		t.Fatalf("unexpected markdown: %s", md)
+	}
+	man := cmd.Man("app", 1)
+	if !strings.HasPrefix(man, ".TH \"APP\" 1\n") || !strings.Contains(man, ".TP\n\\fB\\-\\-addr\\fR (type: string)\nAddress to connect to.\n.br\nFormats:\n.br\n\\(bu host:port") {
+		t.Fatalf("unexpected man page: %s", man)
+	}
+}
+
+type MetaCmd struct {
+	Timeout int    `ask:"--timeout" unit:"ms" help:"Dial timeout"`
+	Addr    string `ask:"--addr" example:"1.2.3.4:9000" link:"https://example.com/addr"`
+}
+
+func TestFlagMeta(t *testing.T) {
+	cmd, err := Load(&MetaCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "Dial timeout (default: 0) (type: int) (unit: ms)") ||
+		!strings.Contains(usage, "(example: 1.2.3.4:9000) (see: https://example.com/addr)") {
+		t.Fatalf("expected metadata in usage, got: %s", usage)
+	}
+	if md := cmd.Markdown("app"); !strings.Contains(md, "- `--addr` (type: string, example: 1.2.3.4:9000, see: https://example.com/addr)") {
+		t.Fatalf("unexpected markdown: %s", md)
+	}
+	sugg := cmd.Suggest([]string{"--ti"})
+	if len(sugg) != 1 || sugg[0].Description != "Dial timeout (unit: ms)" {
+		t.Fatalf("unexpected suggestions: %+v", sugg)
+	}
+}
+
+type LongDefaultCmd struct {
+	Keys []string `ask:"--keys"`
+}
+
+func TestLongDefaults(t *testing.T) {
+	c := LongDefaultCmd{Keys: []string{strings.Repeat("a", 50), strings.Repeat("b", 50)}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := strings.Repeat("a", 50) + "," + strings.Repeat("b", 50)
+	if usage := cmd.Usage(false); !strings.Contains(usage, "(default: "+full[:MaxDefaultLength]+"… (21 more))") {
+		t.Fatalf("expected truncated default, got: %s", usage)
+	}
+	if usage := cmd.Usage(false, WithFullDefaults()); !strings.Contains(usage, "(default: "+full+")") {
+		t.Fatalf("expected full default, got: %s", usage)
+	}
+}
+
+type SecretDefaultCmd struct {
+	Token   string `ask:"--token" secret:"true"`
+	Name    string `ask:"--name"`
+	Missing string `ask:"--missing" secret:"true"`
+}
+
+func (c *SecretDefaultCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestSecretDefaults(t *testing.T) {
+	c := SecretDefaultCmd{Token: "hunter2", Name: "bob"}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []string{cmd.Usage(false), cmd.Markdown("app"), cmd.Man("app", 1)} {
+		if strings.Contains(doc, "hunter2") || !strings.Contains(doc, "bob") || !strings.Contains(doc, RedactedValue) {
+			t.Fatalf("expected secret default to be redacted:\n%s", doc)
+		}
+		if strings.Count(doc, RedactedValue) != 1 {
+			t.Fatalf("expected empty secret default to be omitted:\n%s", doc)
+		}
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--token=s3cret"); err != nil || c.Token != "s3cret" {
+		t.Fatalf("expected secret to be parsed normally, got %q: %v", c.Token, err)
+	}
+}
+
+func TestHideDefaultsDocs(t *testing.T) {
+	cmd, err := Load(&DefaultsCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []string{cmd.Markdown("app", WithoutZeroDefaults()), cmd.Man("app", 1, WithoutZeroDefaults())} {
+		if strings.Contains(doc, "default: 0") || strings.Contains(doc, "default: false") || !strings.Contains(doc, "default: 3") {
+			t.Fatalf("expected zero defaults to be omitted: %s", doc)
+		}
+	}
+	if md := cmd.Markdown("app"); !strings.Contains(md, "default: 0") {
+		t.Fatalf("expected zero defaults in docs by default: %s", md)
+	}
+}
@@ -0,0 +1,90 @@
+package ask
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigFormat is the encoding of a configuration dump, see DumpConfig.
+type ConfigFormat string
+
+const (
+	ConfigYAML ConfigFormat = "yaml"
+	ConfigJSON ConfigFormat = "json"
+)
+
+// ConfigDump is the effective configuration of a command, after all sources were applied.
+type ConfigDump struct {
+	// Values of all flags and positional args by path, see Values. Secret values are redacted.
+	Values map[string]string `json:"values"`
+	// Changed maps the paths of the flags that were changed from their default to the source of their value.
+	Changed map[string]string `json:"changed,omitempty"`
+}
+
+// Config returns the effective configuration of the command, e.g. to print it for debugging,
+// or to reproduce a run: the values can be applied again with SetFromMap.
+func (descr *CommandDescription) Config() ConfigDump {
+	out := ConfigDump{Values: descr.Values()}
+	for _, pf := range descr.All("") {
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		if pf.Secret {
+			out.Values[pf.Path] = pf.redacted()
+		}
+		if src, ok := descr.Sources[pf.Path]; ok {
+			if out.Changed == nil {
+				out.Changed = make(map[string]string)
+			}
+			out.Changed[pf.Path] = src
+		}
+	}
+	return out
+}
+
+// DumpConfig writes the effective configuration of the command, see Config, as YAML or JSON.
+func (descr *CommandDescription) DumpConfig(w io.Writer, format ConfigFormat) error {
+	cfg := descr.Config()
+	switch format {
+	case ConfigJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	case ConfigYAML:
+		var out strings.Builder
+		writeYAMLMap(&out, "values", cfg.Values)
+		if len(cfg.Changed) > 0 {
+			writeYAMLMap(&out, "changed", cfg.Changed)
+		}
+		_, err := io.WriteString(w, out.String())
+		return err
+	default:
+		return fmt.Errorf("unknown config format %q", format)
+	}
+}
+
+// writeYAMLMap writes a map of strings as YAML, sorted by key, with quoted values.
+func writeYAMLMap(out *strings.Builder, name string, m map[string]string) {
+	out.WriteString(name)
+	out.WriteString(":\n")
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out.WriteString("  ")
+		if strings.Trim(k, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-") == "" {
+			out.WriteString(k)
+		} else {
+			out.WriteString(strconv.Quote(k))
+		}
+		out.WriteString(": ")
+		out.WriteString(strconv.Quote(m[k]))
+		out.WriteString("\n")
+	}
+}
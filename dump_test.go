@@ -0,0 +1,43 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDumpConfig(t *testing.T) {
+	var c EnvCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &ExecutionOptions{LookupEnv: func(key string) (string, bool) { return "", false }}
+	if _, err := cmd.Execute(context.Background(), opts, "--token=abc", "--port=9000", "bob \"b\""); err != nil {
+		t.Fatal(err)
+	}
+	var yaml strings.Builder
+	if err := cmd.DumpConfig(&yaml, ConfigYAML); err != nil {
+		t.Fatal(err)
+	}
+	expected := "values:\n  peer: \"bob \\\"b\\\"\"\n  port: \"9000\"\n  token: \"***\"\n" +
+		"changed:\n  peer: \"flag\"\n  port: \"flag\"\n  token: \"flag\"\n"
+	if yaml.String() != expected {
+		t.Fatalf("unexpected yaml:\n%s", yaml.String())
+	}
+	var out strings.Builder
+	if err := cmd.DumpConfig(&out, ConfigJSON); err != nil {
+		t.Fatal(err)
+	}
+	var cfg ConfigDump
+	if err := json.Unmarshal([]byte(out.String()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Values["port"] != "9000" || cfg.Values["token"] != RedactedValue || cfg.Changed["peer"] != SourceFlag {
+		t.Fatalf("unexpected json config: %+v", cfg)
+	}
+	if err := cmd.DumpConfig(&out, "toml"); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
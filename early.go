@@ -0,0 +1,129 @@
+package ask
+
+import (
+	"context"
+	"sort"
+
+	"github.com/protolambda/ask/askparse"
+)
+
+// parseEarly sets the early flags of the command (see Flag.Early) from the arguments, before routing,
+// and returns the other arguments. Only the long format of early flags is recognized.
+// Arguments after "--" are not parsed.
+//
+// The other flags are skipped with the values they take, so a value is never mistaken for an early flag.
+// The arguments after a sub-command route are skipped with the flags of the sub-command,
+// which shadow the early flags with the same name.
+func (descr *CommandDescription) parseEarly(ctx context.Context, opts *ExecutionOptions, args []string) ([]string, error) {
+	early := descr.earlyFlags()
+	if len(early) == 0 {
+		return args, nil
+	}
+	set := func(fl PrefixedFlag, value string) error {
+		return descr.setFlag(ctx, fl, opts.ParseOptions.normalizeNumber(fl, value), SourceFlag)
+	}
+	// only count the arguments that the other flags take, without setting them
+	skip := func(fl PrefixedFlag, value string) error {
+		return nil
+	}
+	// the command that the arguments are for, the route of a sub-command passes the arguments after it on
+	cmd := descr
+	short, long, _, _ := cmd.sortedFlags()
+	routing := true
+	out := make([]string, 0, len(args))
+	for len(args) > 0 {
+		a := args[0]
+		args = args[1:]
+		if a == "--" {
+			out = append(out, a)
+			out = append(out, args...)
+			break
+		}
+		tok, err := opts.syntax().Tokenize(a)
+		if err != nil || tok.Kind == askparse.Positional {
+			out = append(out, a)
+			if err == nil && routing {
+				if sub := cmd.routeEarly(opts, a); sub != nil {
+					if _, raw := sub.Command.(RawArgs); raw {
+						out = append(out, args...)
+						break
+					}
+					cmd = sub
+					short, long, _, _ = cmd.sortedFlags()
+					continue
+				}
+			}
+			routing = false
+			continue
+		}
+		if tok.Kind == askparse.Long && hasLongFlag(early, tok.Name) && (cmd == descr || !hasLongFlag(long, tok.Name)) {
+			if args, err = opts.ParseOptions.ParseLongArg(early, a, args, set); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// a flag that stays in the arguments comes before any route, so the arguments are not routed
+		routing = false
+		out = append(out, a)
+		// errors, like unrecognized flags, are left to the parser of the command
+		var next []string
+		if tok.Kind == askparse.Short {
+			next, err = opts.ParseOptions.ParseShortArg(short, a, args, skip)
+		} else {
+			next, err = opts.ParseOptions.ParseLongArg(long, a, args, skip)
+		}
+		if err == nil {
+			out = append(out, args[:len(args)-len(next)]...)
+			args = next
+		}
+	}
+	return out, nil
+}
+
+// earlyFlags returns the early flags of the command, sorted by path.
+func (descr *CommandDescription) earlyFlags() []PrefixedFlag {
+	var early []PrefixedFlag
+	for _, pf := range descr.All("") {
+		if pf.Early && !pf.IsArg {
+			early = append(early, pf)
+		}
+	}
+	sort.Slice(early, func(i, j int) bool {
+		return early[i].Path < early[j].Path
+	})
+	return early
+}
+
+// hasLongFlag checks if the sorted flags have a flag with the name, or can negate a flag with it, e.g. "no-verbose".
+func hasLongFlag(sortedFlags []PrefixedFlag, name string) bool {
+	i := sort.Search(len(sortedFlags), func(i int) bool {
+		return sortedFlags[i].Path >= name
+	})
+	if i < len(sortedFlags) && sortedFlags[i].Path == name {
+		return true
+	}
+	_, ok := negatedFlag(sortedFlags, name)
+	return ok
+}
+
+// routeEarly loads the sub-command that the argument routes to, like execute does, to skip the arguments after it
+// with the flags of the sub-command. Nil if the argument does not route to a sub-command.
+func (descr *CommandDescription) routeEarly(opts *ExecutionOptions, arg string) *CommandDescription {
+	policy := descr.routePolicy()
+	if descr.CommandRoute == nil || ((policy == PreferCommand || policy == RouteAmbiguityError) && descr.acceptsArgs()) {
+		return nil
+	}
+	name, err := opts.resolveRoute(descr.CommandRoute, arg)
+	if err != nil {
+		return nil
+	}
+	sub, err := descr.CommandRoute.Cmd(name)
+	if err != nil || sub == nil {
+		return nil
+	}
+	subCmd, err := LoadWithOptions(sub, descr.LoadOptions)
+	if err != nil {
+		return nil
+	}
+	return subCmd
+}
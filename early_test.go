@@ -0,0 +1,100 @@
+package ask
+
+import (
+	"context"
+	"testing"
+)
+
+type EarlyRoot struct {
+	Config  string `ask:"--config" early:"true"`
+	Verbose bool   `ask:"--verbose" early:"true"`
+	Level   string `ask:"--log.level" early:"true"`
+	Trace   bool   `ask:"--trace" early:"true"`
+	Name    string `ask:"--name"`
+	// configured is the config path that the sub-command was configured with
+	configured string
+}
+
+func (c *EarlyRoot) Cmd(route string) (cmd interface{}, err error) {
+	if route == "serve" {
+		return &EarlyServe{Root: c}, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+func (c *EarlyRoot) Routes() []string {
+	return []string{"serve"}
+}
+
+type EarlyServe struct {
+	Root  *EarlyRoot
+	Port  uint16 `ask:"--port"`
+	Msg   string `ask:"--msg -m"`
+	Trace bool   `ask:"--trace"`
+}
+
+func (c *EarlyServe) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestEarlyFlags(t *testing.T) {
+	var c EarlyRoot
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &ExecutionOptions{Configure: func(sub *CommandDescription) error {
+		c.configured = c.Config
+		return nil
+	}}
+	final, err := cmd.Execute(context.Background(), opts, "serve", "--config", "app.yaml", "--port=80", "--log.level=debug", "--verbose", "--", "--name=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.configured != "app.yaml" || c.Level != "debug" || !c.Verbose || c.Name != "" {
+		t.Fatalf("unexpected root values: %+v", c)
+	}
+	if final.Command.(*EarlyServe).Port != 80 || len(final.Remaining) != 1 || final.Remaining[0] != "--name=x" {
+		t.Fatalf("unexpected sub-command: %+v, remaining %q", final.Command, final.Remaining)
+	}
+	if cmd.Source("config") != SourceFlag {
+		t.Fatalf("unexpected source: %q", cmd.Source("config"))
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--no-verbose", "--help"); err != HelpErr || c.Verbose {
+		t.Fatalf("expected help after early flags, got: %v (verbose %v)", err, c.Verbose)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "serve", "--config"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error for missing value, got: %v", err)
+	}
+}
+
+func TestEarlyFlagsSkipValues(t *testing.T) {
+	var c EarlyRoot
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the values of other flags are not early flags
+	final, err := cmd.Execute(context.Background(), nil, "serve", "--msg", "--log.level", "-m", "--config", "--port=80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := final.Command.(*EarlyServe); s.Msg != "--config" || s.Port != 80 || c.Level != "" || c.Config != "" {
+		t.Fatalf("unexpected values: %+v, root %+v", s, c)
+	}
+	// the flag of the sub-command shadows the early flag of the parent
+	final, err = cmd.Execute(context.Background(), nil, "serve", "--trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !final.Command.(*EarlyServe).Trace || c.Trace {
+		t.Fatalf("expected the sub-command flag to be set, got %v (root %v)", final.Command.(*EarlyServe).Trace, c.Trace)
+	}
+	final, err = cmd.Execute(context.Background(), nil, "--trace", "serve", "--msg", "--log.level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Command.(*EarlyServe).Trace || !c.Trace || c.Level != "" {
+		t.Fatalf("expected the early flag to be set before the route, got %v (root %v)", final.Command.(*EarlyServe).Trace, c.Trace)
+	}
+}
@@ -0,0 +1,964 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TriStateCmd struct {
+	A TriState `ask:"--a"`
+	B TriState `ask:"--b"`
+	C TriState `ask:"--c"`
+}
+
+func (c *TriStateCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestTriState(t *testing.T) {
+	var c TriStateCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--a", "--b=false"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.A.IsSet() || !c.A.Bool() {
+		t.Fatal("expected a to be true")
+	}
+	if !c.B.IsSet() || c.B.Bool() {
+		t.Fatal("expected b to be false")
+	}
+	if c.C.IsSet() {
+		t.Fatal("expected c to be unset")
+	}
+}
+
+type TransformCmd struct {
+	Dir  string `ask:"--dir" transform:"expandenv,trim"`
+	Name string `ask:"<name>" transform:"upper"`
+}
+
+func (c *TransformCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestTransform(t *testing.T) {
+	t.Setenv("ASK_TEST_DIR", "/tmp/foo")
+	var c TransformCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--dir", " $ASK_TEST_DIR/bar ", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Dir != "/tmp/foo/bar" || c.Name != "ALICE" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := Load(&struct {
+		X string `ask:"--x" transform:"unknown"`
+	}{}); err == nil {
+		t.Fatal("expected unknown transform error")
+	}
+}
+
+type FileRefCmd struct {
+	Token string `ask:"--token" file-ok:"true" secret:"true"`
+	Name  string `ask:"--name" file-ok:"true" transform:"upper"`
+	Plain string `ask:"--plain"`
+}
+
+func (c *FileRefCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestFileRef(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("s3cret\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var c FileRefCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--token", "@"+path, "--name=@"+path, "--plain=@"+path); err != nil {
+		t.Fatal(err)
+	}
+	if c.Token != "s3cret" || c.Name != "S3CRET" || c.Plain != "@"+path {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--token=@@literal"); err != nil || c.Token != "@literal" {
+		t.Fatalf("expected escaped literal, got %q: %v", c.Token, err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--token=@"+path+".missing"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+}
+
+type keystoreKey struct{}
+
+// KeyValue resolves a key name with the keystore in the context.
+type KeyValue struct {
+	Name, Key string
+}
+
+func (v *KeyValue) String() string {
+	return v.Name
+}
+
+func (v *KeyValue) Set(s string) error {
+	if s == "" {
+		*v = KeyValue{}
+		return nil
+	}
+	return fmt.Errorf("key %q cannot be resolved without keystore", s)
+}
+
+func (v *KeyValue) SetCtx(ctx context.Context, s string) error {
+	keystore, ok := ctx.Value(keystoreKey{}).(map[string]string)
+	if !ok {
+		return v.Set(s)
+	}
+	key, ok := keystore[s]
+	if !ok {
+		return fmt.Errorf("unknown key %q", s)
+	}
+	v.Name, v.Key = s, key
+	return nil
+}
+
+type KeystoreCmd struct {
+	Signer KeyValue `ask:"--signer" env:"SIGNER"`
+	Backup KeyValue `ask:"--backup"`
+}
+
+func (c *KeystoreCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestCtxValue(t *testing.T) {
+	var c KeystoreCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), keystoreKey{}, map[string]string{"alice": "0xa1", "bob": "0xb0"})
+	opts := &ExecutionOptions{LookupEnv: func(key string) (string, bool) {
+		return "bob", key == "SIGNER"
+	}}
+	if _, err := cmd.Execute(ctx, opts, "--backup=alice"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Signer.Key != "0xb0" || c.Backup.Key != "0xa1" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(ctx, nil, "--backup=carol"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if err := cmd.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.SetFromMap(map[string]string{"backup": "alice"}, "config"); err == nil {
+		t.Fatal("expected keystore to be unavailable outside of Execute")
+	}
+}
+
+type MapCmd struct {
+	Labels   map[string]string        `ask:"--labels"`
+	Weights  map[string]int           `ask:"--weights"`
+	Limits   map[string]uint64        `ask:"--limits"`
+	Features map[string]bool          `ask:"--features"`
+	Timeouts map[string]time.Duration `ask:"--timeouts"`
+}
+
+func (c *MapCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestMapStringValue(t *testing.T) {
+	c := MapCmd{Labels: map[string]string{"env": "dev"}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("labels"); pf.Default != "env=dev" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, `--labels=b=2,a=1,"c=x,y"`); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Labels) != 3 || c.Labels["a"] != "1" || c.Labels["b"] != "2" || c.Labels["c"] != "x,y" {
+		t.Fatalf("unexpected labels: %v", c.Labels)
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	c := MapCmd{Timeouts: map[string]time.Duration{"read": time.Second, "dial": 500 * time.Millisecond}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("timeouts"); pf.Default != "dial=500ms,read=1s" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "dial=500ms,read=1s") {
+		t.Fatalf("expected default in usage, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"--weights=a=-1,b=2", "--limits=x=18446744073709551615", "--features=foo=true,bar=false", "--timeouts=write=2m"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Weights["a"] != -1 || c.Weights["b"] != 2 || c.Limits["x"] != math.MaxUint64 ||
+		!c.Features["foo"] || c.Features["bar"] || len(c.Timeouts) != 1 || c.Timeouts["write"] != 2*time.Minute {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	type BadMapCmd struct {
+		M map[string]time.Time `ask:"--m"`
+	}
+	if _, err := Load(&BadMapCmd{}); err == nil {
+		t.Fatal("expected unrecognized map value type error")
+	}
+}
+
+type RegexpCmd struct {
+	Match *regexp.Regexp `ask:"--match"`
+}
+
+func (c *RegexpCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestRegexpValue(t *testing.T) {
+	c := RegexpCmd{Match: regexp.MustCompile("^a")}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("match"); pf.Default != "^a" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--match=fo+$"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Match.MatchString("foo") || c.Match.MatchString("a") {
+		t.Fatalf("unexpected regexp: %s", c.Match)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--match=(x"); !IsUsageErr(err) || !strings.Contains(err.Error(), "missing closing )") {
+		t.Fatalf("expected compile error, got: %v", err)
+	}
+	if c.Match.String() != "fo+$" {
+		t.Fatalf("invalid pattern must not change the value, got: %s", c.Match)
+	}
+	var empty RegexpCmd
+	if _, err := Load(&empty); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type AddrCmd struct {
+	Listen net.TCPAddr  `ask:"--listen"`
+	Dial   *net.TCPAddr `ask:"--dial"`
+	Disc   net.UDPAddr  `ask:"--disc"`
+}
+
+func (c *AddrCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestAddrValues(t *testing.T) {
+	c := AddrCmd{Listen: net.TCPAddr{Port: 8080}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("listen"); pf.Default != ":8080" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--listen=127.0.0.1:9000", "--dial=[::1]:30303", "--disc=[fe80::1%eth0]:9001"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Listen.String() != "127.0.0.1:9000" || c.Dial.String() != "[::1]:30303" || c.Disc.Port != 9001 || c.Disc.Zone != "eth0" {
+		t.Fatalf("unexpected addresses: %s %s %s", &c.Listen, c.Dial, &c.Disc)
+	}
+	for _, v := range []string{"localhost:80", "127.0.0.1", "127.0.0.1:65536", ":http"} {
+		if _, err := cmd.Execute(context.Background(), nil, "--listen="+v); !IsUsageErr(err) {
+			t.Fatalf("%s: expected usage error, got: %v", v, err)
+		}
+	}
+}
+
+// Level only implements encoding.TextUnmarshaler.
+type Level struct {
+	N int
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		l.N = 1
+	case "high":
+		l.N = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type TextCmd struct {
+	Amount big.Int   `ask:"--amount"`
+	Since  time.Time `ask:"--since"`
+	Level  Level     `ask:"--level"`
+	Levels *Level    `ask:"--levels"`
+}
+
+func (c *TextCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestTextValue(t *testing.T) {
+	var c TextCmd
+	c.Amount.SetInt64(42)
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("amount"); pf.Default != "42" || pf.Value.(TypedValue).Type() != "big.Int" {
+		t.Fatalf("unexpected amount flag: %q %q", pf.Default, pf.Value.(TypedValue).Type())
+	}
+	if pf, _ := cmd.Lookup("level"); pf.Default != "{0}" {
+		t.Fatalf("unexpected level default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"--amount=123456789012345678901234567890", "--since=2024-01-02T03:04:05Z", "--level=high", "--levels=low"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Amount.String() != "123456789012345678901234567890" || c.Since.Year() != 2024 || c.Level.N != 2 || c.Levels.N != 1 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--level=mid"); !IsUsageErr(err) || !strings.Contains(err.Error(), `unknown level "mid"`) {
+		t.Fatalf("expected level error, got: %v", err)
+	}
+}
+
+type EnumCmd struct {
+	Mode  string  `ask:"--mode" enum:"fast, slow" help:"Sync mode"`
+	Level *uint8  `ask:"--level" enum:"1,2,3"`
+	Ratio float64 `ask:"--ratio"`
+}
+
+func (c *EnumCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestEnumTag(t *testing.T) {
+	c := EnumCmd{Mode: "fast"}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "Sync mode (default: fast) (type: fast|slow)") {
+		t.Fatalf("expected allowed values in usage, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--mode=slow", "--level=2"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != "slow" || *c.Level != 2 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--level=4"); !IsUsageErr(err) || !strings.Contains(err.Error(), `"4" is not one of: 1, 2, 3`) {
+		t.Fatalf("expected enum error, got: %v", err)
+	}
+	if *c.Level != 2 {
+		t.Fatalf("invalid value must not be set, got %d", *c.Level)
+	}
+	for _, bad := range []interface{}{
+		&struct {
+			X float64 `ask:"--x" enum:"1.5,2"`
+		}{},
+		&struct {
+			X uint8 `ask:"--x" enum:"1,300"`
+		}{},
+		&struct {
+			X string `ask:"--x" enum:""`
+		}{},
+	} {
+		if _, err := Load(bad); err == nil {
+			t.Fatalf("%T: expected invalid enum error", bad)
+		}
+	}
+}
+
+type IPNetSliceCmd struct {
+	Allow []net.IPNet `ask:"--allow"`
+}
+
+func (c *IPNetSliceCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestIPNetSliceFlag(t *testing.T) {
+	_, def, _ := net.ParseCIDR("127.0.0.0/8")
+	c := IPNetSliceCmd{Allow: []net.IPNet{*def}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("allow"); pf.Default != "127.0.0.0/8" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--allow=10.0.0.0/8,192.168.1.1/16,fd00::/8"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Allow) != 3 || c.Allow[1].String() != "192.168.0.0/16" {
+		t.Fatalf("unexpected value: %v", c.Allow)
+	}
+}
+
+type LevelCmd struct {
+	Level    slog.Level `ask:"--level"`
+	LogLevel LevelValue `ask:"--log-level"`
+}
+
+func (c *LevelCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestLevelFlag(t *testing.T) {
+	c := LevelCmd{Level: slog.LevelWarn}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("level"); pf.Default != "warn" || pf.Value.(TypedValue).Type() != "loglevel" {
+		t.Fatalf("unexpected level flag: %q %q", pf.Default, pf.Value.(TypedValue).Type())
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--level=TRACE", "--log-level=info+2"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != LevelTrace || c.LogLevel.Level() != slog.LevelInfo+2 {
+		t.Fatalf("unexpected levels: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--level=loud"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+}
+
+type RuneCmd struct {
+	Delim rune  `ask:"--delim" format:"rune"`
+	Quote rune  `ask:"--quote" format:"rune"`
+	Code  int32 `ask:"--code"`
+}
+
+func (c *RuneCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestRuneFormat(t *testing.T) {
+	c := RuneCmd{Delim: '\t', Quote: '"'}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("delim"); pf.Default != `\t` {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--delim=;", `--quote=é`, "--code=59"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Delim != ';' || c.Quote != 'é' || c.Code != 59 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--delim=ab"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		X string `ask:"--x" format:"rune"`
+	}{}); err == nil {
+		t.Fatal("expected rune type error")
+	}
+}
+
+type UUIDCmd struct {
+	ID    [16]byte  `ask:"--id" format:"uuid"`
+	Trace UUIDValue `ask:"--trace"`
+}
+
+func (c *UUIDCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestUUIDFormat(t *testing.T) {
+	c := UUIDCmd{ID: [16]byte{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("id"); pf.Default != "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--id=123E4567-E89B-12D3-A456-426614174000", "--trace=00000000-0000-0000-0000-000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID[0] != 0x12 || c.ID[15] != 0x00 || c.Trace[15] != 1 || (*UUIDValue)(&c.ID).String() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Fatalf("unexpected values: %x %x", c.ID, c.Trace)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--id=123e4567e89b12d3a456426614174000"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		X [20]byte `ask:"--x" format:"uuid"`
+	}{}); err == nil {
+		t.Fatal("expected uuid type error")
+	}
+}
+
+type BoundsCmd struct {
+	Slot    time.Duration `ask:"--slot" min:"12s" max:"2m" multipleof:"12s"`
+	Timeout time.Duration `ask:"--timeout" max:"1m"`
+}
+
+func (c *BoundsCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDurationBounds(t *testing.T) {
+	c := BoundsCmd{Slot: 12 * time.Second}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("slot"); pf.Default != "12s" || pf.Value.(TypedValue).Type() != "duration" {
+		t.Fatalf("unexpected slot flag: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--slot=1m12s", "--timeout=-5s"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Slot != 72*time.Second || c.Timeout != -5*time.Second {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	for arg, msg := range map[string]string{
+		"--slot=0s":       "duration 0s is less than the minimum of 12s",
+		"--slot=2m12s":    "duration 2m12s is more than the maximum of 2m0s",
+		"--slot=30s":      "duration 30s is not a multiple of 12s",
+		"--timeout=1m30s": "duration 1m30s is more than the maximum of 1m0s",
+	} {
+		if _, err := cmd.Execute(context.Background(), nil, arg); !IsUsageErr(err) || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%s: expected %q, got: %v", arg, msg, err)
+		}
+	}
+	if c.Slot != 72*time.Second {
+		t.Fatalf("invalid value must not be set, got %s", c.Slot)
+	}
+	for _, bad := range []interface{}{
+		&struct {
+			X int `ask:"--x" min:"1s"`
+		}{},
+		&struct {
+			X time.Duration `ask:"--x" min:"1m" max:"1s"`
+		}{},
+		&struct {
+			X time.Duration `ask:"--x" multipleof:"0s"`
+		}{},
+	} {
+		if _, err := Load(bad); err == nil {
+			t.Fatalf("%T: expected invalid bounds error", bad)
+		}
+	}
+}
+
+type PercentCmd struct {
+	Threshold float64 `ask:"--threshold" format:"percent"`
+	Quota     float64 `ask:"--quota" format:"percent-whole"`
+}
+
+func (c *PercentCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestPercentFormat(t *testing.T) {
+	c := PercentCmd{Threshold: 0.85}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "(default: 85%) (type: percent)") {
+		t.Fatalf("expected percent default, got: %s", usage)
+	}
+	for _, tc := range []struct {
+		args      []string
+		threshold float64
+		quota     float64
+	}{
+		{[]string{"--threshold=0.5", "--quota=20"}, 0.5, 0.2},
+		{[]string{"--threshold=12.5%", "--quota=100%"}, 0.125, 1},
+	} {
+		if _, err := cmd.Execute(context.Background(), nil, tc.args...); err != nil {
+			t.Fatal(err)
+		}
+		if c.Threshold != tc.threshold || c.Quota != tc.quota {
+			t.Fatalf("%v: unexpected values: %+v", tc.args, c)
+		}
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--threshold=85"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		X float32 `ask:"--x" format:"percent"`
+	}{}); err == nil {
+		t.Fatal("expected percent type error")
+	}
+}
+
+type FormatAliasCmd struct {
+	Verbose int     `ask:"--verbose -v" count:"true"`
+	Delim   rune    `ask:"--delim" rune:"true"`
+	Ratio   float64 `ask:"--ratio" percent:"fraction"`
+	Quota   float64 `ask:"--quota" percent:"whole"`
+}
+
+func (c *FormatAliasCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestFormatAliases(t *testing.T) {
+	var c FormatAliasCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "-vvv", "--delim=\\t", "--ratio=0.5", "--quota=20"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Verbose != 3 || c.Delim != '\t' || c.Ratio != 0.5 || c.Quota != 0.2 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := Load(&struct {
+		X float64 `ask:"--x" percent:"half"`
+	}{}); err == nil || !strings.Contains(err.Error(), "invalid percent tag") {
+		t.Fatalf("expected percent tag error, got: %v", err)
+	}
+}
+
+type CanonicalCmd struct {
+	Dur    time.Duration     `ask:"--dur"`
+	IP     net.IP            `ask:"--ip"`
+	Net    net.IPNet         `ask:"--net"`
+	Names  []string          `ask:"--names"`
+	Labels map[string]string `ask:"--labels"`
+	Hex    []byte            `ask:"--hex"`
+	Tri    TriState          `ask:"--tri"`
+	Since  time.Time         `ask:"--since"`
+	Mode   string            `ask:"--mode" enum:"a,b"`
+	Level  Level             `ask:"--level"`
+}
+
+func (c *CanonicalCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestCanonical(t *testing.T) {
+	var c CanonicalCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"mode", "level"} {
+		pf, _ := cmd.Lookup(path)
+		if v, err := pf.Canonical(); err == nil {
+			t.Fatalf("%s: expected no canonical rendering, got %q", path, v)
+		}
+	}
+	for _, path := range []string{"ip", "net", "tri"} {
+		pf, _ := cmd.Lookup(path)
+		if v, err := pf.Canonical(); err != nil || v != "" {
+			t.Fatalf("%s: expected empty canonical rendering of unset value, got %q: %v", path, v, err)
+		}
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--dur=1m30s", "--ip=::1", "--net=10.0.0.0/8",
+		`--names=a,"b,c"`, "--labels=x=1,y=2", "--hex=0xC0FFEE", "--tri=false", "--since=2024-01-02T03:04:05Z", "--mode=b"); err != nil {
+		t.Fatal(err)
+	}
+	for _, pf := range cmd.All("") {
+		if pf.Path == "level" {
+			continue
+		}
+		v, err := pf.Canonical()
+		if err != nil {
+			t.Fatalf("%s: %v", pf.Path, err)
+		}
+		before := pf.Value.String()
+		if err := pf.Value.Set(v); err != nil || pf.Value.String() != before {
+			t.Fatalf("%s: canonical %q does not round-trip: %v", pf.Path, v, err)
+		}
+	}
+	if v := cmd.Values()["since"]; v != "2024-01-02T03:04:05Z" {
+		t.Fatalf("unexpected canonical time: %q", v)
+	}
+}
+
+func TestCanonicalAllValueTypes(t *testing.T) {
+	var c AllValuesCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(unset ...string) {
+		t.Helper()
+		var failed []string
+		for _, pf := range cmd.All("") {
+			v, err := pf.Canonical()
+			if err != nil {
+				failed = append(failed, pf.Path)
+				continue
+			}
+			before := pf.Value.String()
+			if err := pf.Value.Set(v); err != nil {
+				t.Fatalf("%s: canonical %q cannot be parsed: %v", pf.Path, v, err)
+			}
+			if again, _ := pf.Canonical(); again != v || pf.Value.String() != before {
+				t.Fatalf("%s: canonical %q does not round-trip, got %q", pf.Path, v, again)
+			}
+		}
+		if strings.Join(failed, " ") != strings.Join(unset, " ") {
+			t.Fatalf("unexpected values without canonical rendering: %v", failed)
+		}
+	}
+	check("enum", "bounded", "port", "port-range", "optional")
+	if _, err := cmd.Execute(context.Background(), nil, "--float64=0.1", "--float32=0.1", "--percent=33.333333333333%",
+		"--rate=0.5/h", "--rune=\\t", "--tristate=false", "--bounded=1m", "--optional=0", "--float64s=0.1234567891,3",
+		"--enum=slow", "--port=80", "--port-range=80-90"); err != nil {
+		t.Fatal(err)
+	}
+	percent := c.Percent
+	check()
+	if c.Percent != percent || c.Float64s[0] != 0.1234567891 {
+		t.Fatalf("unexpected values after round-trip: %v %v", c.Percent, c.Float64s)
+	}
+}
+
+type AppendCmd struct {
+	Headers []string `ask:"--header -H" mode:"append"`
+	Ports   []uint16 `ask:"--port" mode:"append"`
+	Names   []string `ask:"--name"`
+}
+
+func (c *AppendCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestAppendMode(t *testing.T) {
+	c := AppendCmd{Headers: []string{"default"}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"--header", "a", "-H", "b,c", "--port=1", "--port=2,3", "--name=x", "--name=y"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.Headers, " "); got != "a b c" {
+		t.Fatalf("unexpected headers: %q", got)
+	}
+	if len(c.Ports) != 3 || c.Ports[2] != 3 {
+		t.Fatalf("unexpected ports: %v", c.Ports)
+	}
+	if got := strings.Join(c.Names, " "); got != "y" {
+		t.Fatalf("expected last names to replace, got %q", got)
+	}
+	// a new execution starts over, and a failed occurrence does not change the values
+	if _, err := cmd.Execute(context.Background(), nil, "--port=4", "--port=x"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if len(c.Ports) != 1 || c.Ports[0] != 4 {
+		t.Fatalf("unexpected ports: %v", c.Ports)
+	}
+	if _, err := Load(&struct {
+		X string `ask:"--x" mode:"append"`
+	}{}); err == nil {
+		t.Fatal("expected slice error")
+	}
+}
+
+type SepCmd struct {
+	URLs  []string        `ask:"--url" sep:";"`
+	Waits []time.Duration `ask:"--wait" sep:" " mode:"append"`
+}
+
+func (c *SepCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestSepTag(t *testing.T) {
+	c := SepCmd{URLs: []string{"http://a/?x=1,2", "http://b"}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "(default: http://a/?x=1,2;http://b) (type: stringSlice)") {
+		t.Fatalf("expected separated default, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--url=http://c/?y=3,4;\"q\"", "--wait=1s 2m", "--wait=3h"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.URLs) != 2 || c.URLs[0] != "http://c/?y=3,4" || c.URLs[1] != `"q"` {
+		t.Fatalf("unexpected urls: %q", c.URLs)
+	}
+	if len(c.Waits) != 3 || c.Waits[2] != 3*time.Hour {
+		t.Fatalf("unexpected waits: %v", c.Waits)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--wait=1s,2s"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		X string `ask:"--x" sep:";"`
+	}{}); err == nil {
+		t.Fatal("expected slice error")
+	}
+}
+
+type FileModeCmd struct {
+	Mode    os.FileMode   `ask:"--mode"`
+	DirMode FileModeValue `ask:"--dir-mode"`
+}
+
+func (c *FileModeCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestFileModeFlag(t *testing.T) {
+	c := FileModeCmd{Mode: 0o644}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "(default: 0644) (type: filemode)") {
+		t.Fatalf("expected octal default, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--mode=rwxr-x---", "--dir-mode=600"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != 0o750 || c.DirMode.FileMode() != 0o600 {
+		t.Fatalf("unexpected modes: %v %v", c.Mode, c.DirMode.FileMode())
+	}
+}
+
+type MapAppendCmd struct {
+	Labels map[string]string `ask:"--label" mode:"append"`
+	Limits map[string]int    `ask:"--limit" mode:"append"`
+}
+
+func (c *MapAppendCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestMapAppendMode(t *testing.T) {
+	c := MapAppendCmd{Labels: map[string]string{"default": "x"}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil,
+		"--label", "a=1,2", "--label", "b=x=y", "--limit=cpu=2", "--limit=mem=4"); err != nil {
+		t.Fatal(err)
+	}
+	// the first occurrence replaces the default
+	if len(c.Labels) != 2 || c.Labels["a"] != "1,2" || c.Labels["b"] != "x=y" {
+		t.Fatalf("unexpected labels: %v", c.Labels)
+	}
+	if len(c.Limits) != 2 || c.Limits["mem"] != 4 {
+		t.Fatalf("unexpected limits: %v", c.Limits)
+	}
+	for _, arg := range []string{"--label=novalue", "--limit=cpu=x"} {
+		if _, err := cmd.Execute(context.Background(), nil, arg); !IsUsageErr(err) {
+			t.Fatalf("%s: expected usage error, got: %v", arg, err)
+		}
+	}
+}
+
+type MACCmd struct {
+	Iface net.HardwareAddr   `ask:"--iface"`
+	Peers []net.HardwareAddr `ask:"--peers"`
+}
+
+func (c *MACCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestMACFlags(t *testing.T) {
+	def, _ := net.ParseMAC("00:00:5e:00:53:01")
+	c := MACCmd{Iface: def}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false); !strings.Contains(usage, "(default: 00:00:5e:00:53:01) (type: mac)") {
+		t.Fatalf("expected mac default, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--iface=00-00-5E-00-53-02", "--peers=02:00:5e:10:00:00,0000.5e00.5303"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Iface.String() != "00:00:5e:00:53:02" {
+		t.Fatalf("unexpected iface: %s", c.Iface)
+	}
+	if len(c.Peers) != 2 || c.Peers[1].String() != "00:00:5e:00:53:03" {
+		t.Fatalf("unexpected peers: %v", c.Peers)
+	}
+}
+
+type SecretListCmd struct {
+	Keys SecretListValue `ask:"--keys"`
+}
+
+func (c *SecretListCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestSecretListFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ASK_TEST_KEY", "from-env")
+	var c SecretListCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--keys=current,file:"+path+",env:ASK_TEST_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cmd.Config().Values["keys"]; got != "***,file:"+path+",env:ASK_TEST_KEY" {
+		t.Fatalf("expected redacted config, got: %q", got)
+	}
+	fl, _ := cmd.Lookup("keys")
+	if got, err := fl.Canonical(); err != nil || got != c.Keys.String() {
+		t.Fatalf("unexpected canonical value %q: %v", got, err)
+	}
+	// the values round-trip through a reset, without losing the literal secrets
+	values := cmd.Values()
+	if err := cmd.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Keys.Secrets()) != 0 {
+		t.Fatalf("expected no secrets after reset, got: %q", c.Keys.Secrets())
+	}
+	if err := cmd.SetFromMap(values, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.Keys.Secrets(), " "); got != "current from-file from-env" {
+		t.Fatalf("unexpected secrets after round-trip: %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// jsonValue loads a value that is decoded as JSON, see the `format:"json"` struct tag.
+func jsonValue(val reflect.Value) (flag.Value, error) {
+	return &JSONValue{Dest: val.Addr().Interface()}, nil
+}
+
+// JSONValue decodes the flag value as JSON into the destination, e.g. to pass nested options as one flag.
+// The value is decoded into a copy of the current value, so fields and keys that are not in the JSON keep their default.
+// The destination is only changed if the whole value decodes successfully.
+type JSONValue struct {
+	// Dest is a pointer to the destination value
+	Dest interface{}
+}
+
+func (j *JSONValue) Set(val string) error {
+	v := deepCopy(reflect.ValueOf(j.Dest).Elem()).Addr()
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v.Interface()); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	reflect.ValueOf(j.Dest).Elem().Set(v.Elem())
+	return nil
+}
+
+func (j *JSONValue) Type() string {
+	return "json"
+}
+
+func (j *JSONValue) String() string {
+	if j.Dest == nil {
+		return ""
+	}
+	data, err := json.Marshal(j.Dest)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
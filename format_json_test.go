@@ -0,0 +1,70 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type RetryOptions struct {
+	Attempts int           `json:"attempts"`
+	Backoff  time.Duration `json:"backoff"`
+	Codes    []int         `json:"codes,omitempty"`
+}
+
+type JSONCmd struct {
+	Retry    RetryOptions      `ask:"--retry" format:"json"`
+	Labels   map[string]string `ask:"--labels" format:"json"`
+	Fallback *RetryOptions     `ask:"--fallback" format:"json"`
+}
+
+func (c *JSONCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestJSONValue(t *testing.T) {
+	c := JSONCmd{Retry: RetryOptions{Attempts: 3}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("retry"); pf.Default != `{"attempts":3,"backoff":0}` {
+		t.Fatalf("unexpected default: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, `--retry={"attempts":5,"backoff":1000,"codes":[502,503]}`, `--labels={"a":"b,c"}`); err != nil {
+		t.Fatal(err)
+	}
+	if c.Retry.Attempts != 5 || c.Retry.Backoff != 1000 || len(c.Retry.Codes) != 2 || c.Labels["a"] != "b,c" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	for _, v := range []string{`{"attempts":"x"}`, `{"unknown":1}`, `{} {}`} {
+		if _, err := cmd.Execute(context.Background(), nil, "--retry="+v); !IsUsageErr(err) {
+			t.Fatalf("%s: expected usage error, got: %v", v, err)
+		}
+	}
+	if c.Retry.Attempts != 5 {
+		t.Fatalf("invalid value must not change the destination, got: %+v", c.Retry)
+	}
+	// fields that are not in the JSON keep their default
+	c = JSONCmd{Retry: RetryOptions{Attempts: 3}, Labels: map[string]string{"x": "y"}}
+	if cmd, err = Load(&c); err != nil {
+		t.Fatal(err)
+	}
+	if usage := cmd.Usage(false, WithoutZeroDefaults()); strings.Contains(usage, "null") {
+		t.Fatalf("expected nil default to be hidden, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, `--retry={"backoff":1000}`, `--labels={"a":"b"}`); err != nil {
+		t.Fatal(err)
+	}
+	if c.Retry.Attempts != 3 || c.Retry.Backoff != 1000 || c.Labels["x"] != "y" || c.Labels["a"] != "b" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := Load(&struct {
+		X int `ask:"--x" format:"yaml"`
+	}{}); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
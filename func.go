@@ -0,0 +1,157 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// funcCmd is a Command that calls a function, with the parameters loaded as flags and args.
+type funcCmd struct {
+	fn reflect.Value
+	// struct type with a field per parameter, declared with the names as ask tags
+	paramsType reflect.Type
+	// pointer to the parameters of the last load, the flags are bound to them
+	params reflect.Value
+	flags  []*Flag
+	// if the first parameter of the function is a context.Context
+	ctx bool
+	// writer to print the results of the function to, other than the error
+	out io.Writer
+}
+
+// Func wraps a function into a Command, for quick scripting-style sub-commands without defining a struct.
+// Each parameter is declared by the name at the same index, like an ask struct tag, e.g. "<src>", "[dst]" or "--force -f".
+// Without names, the parameters are required positional args named "<arg1>", "<arg2>", etc.
+// The parameter types can be any of the supported flag value types.
+//
+// The function may take a context.Context as first parameter, which is not named,
+// and a variadic ...string as last parameter, which is not named either, to receive the remaining args.
+// The function may return an error, which is returned by Run.
+func Func(fn interface{}, names ...string) (Command, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("expected a function, got %T", fn)
+	}
+	return newFuncCmd(v, nil, names)
+}
+
+// newFuncCmd loads the parameters of the function. If out is not nil, the function may return results before the error,
+// which are printed to out.
+func newFuncCmd(v reflect.Value, out io.Writer, names []string) (*funcCmd, error) {
+	typ := v.Type()
+	if out == nil && (typ.NumOut() > 1 || (typ.NumOut() == 1 && typ.Out(0) != errorType)) {
+		return nil, fmt.Errorf("function %s may only return an error", typ)
+	}
+	if out != nil && typ.NumOut() > 0 && typ.Out(typ.NumOut()-1) != errorType {
+		return nil, fmt.Errorf("function %s must return an error as last result", typ)
+	}
+	c := &funcCmd{fn: v, out: out}
+	first, count := 0, typ.NumIn()
+	if count > 0 && typ.In(0) == contextType {
+		c.ctx = true
+		first = 1
+	}
+	if typ.IsVariadic() {
+		if typ.In(count-1).Elem().Kind() != reflect.String {
+			return nil, fmt.Errorf("function %s may only have a variadic ...string parameter", typ)
+		}
+		count -= 1
+	}
+	if len(names) != 0 && len(names) != count-first {
+		return nil, fmt.Errorf("function %s has %d parameters to name, but got %d names", typ, count-first, len(names))
+	}
+	fields := make([]reflect.StructField, 0, count-first)
+	for i := first; i < count; i++ {
+		name := "<arg" + strconv.Itoa(i-first+1) + ">"
+		if len(names) != 0 {
+			name = names[i-first]
+		}
+		fields = append(fields, reflect.StructField{
+			Name: "Param" + strconv.Itoa(i),
+			Type: typ.In(i),
+			Tag:  reflect.StructTag(`ask:` + strconv.Quote(name)),
+		})
+	}
+	c.paramsType = reflect.StructOf(fields)
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load allocates fresh parameters, and loads the flags of them.
+func (c *funcCmd) load() error {
+	typ := c.fn.Type()
+	params := reflect.New(c.paramsType)
+	grp, err := LoadGroup("", params, NewChangedMarkers())
+	if err != nil {
+		return fmt.Errorf("failed to load parameters of function %s: %w", typ, err)
+	}
+	if len(grp.Flags) != c.paramsType.NumField() {
+		return fmt.Errorf("function %s can only have flag or arg parameters", typ)
+	}
+	c.params, c.flags = params, grp.Flags
+	return nil
+}
+
+// ExtraFlags loads the parameters again, so every load of the command starts with fresh parameters.
+func (c *funcCmd) ExtraFlags() []*Flag {
+	if err := c.load(); err != nil {
+		// the same parameters loaded when the command was created
+		panic(err)
+	}
+	return c.flags
+}
+
+func (c *funcCmd) ArgSpec() (min int, max int) {
+	if c.fn.Type().IsVariadic() {
+		return 0, -1
+	}
+	return 0, 0
+}
+
+func (c *funcCmd) Run(ctx context.Context, args ...string) error {
+	in := make([]reflect.Value, 0, c.fn.Type().NumIn()+len(args))
+	if c.ctx {
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+	}
+	params := c.params.Elem()
+	for i := 0; i < params.NumField(); i++ {
+		arg := reflect.New(params.Field(i).Type()).Elem()
+		arg.Set(params.Field(i))
+		in = append(in, arg)
+	}
+	// reset the parameters, so the next execution does not see the flags of this one
+	for _, f := range c.flags {
+		if err := f.reset(); err != nil {
+			return err
+		}
+	}
+	if c.fn.Type().IsVariadic() {
+		elem := c.fn.Type().In(c.fn.Type().NumIn() - 1).Elem()
+		for _, a := range args {
+			in = append(in, reflect.ValueOf(a).Convert(elem))
+		}
+	}
+	results := c.fn.Call(in)
+	if len(results) == 0 {
+		return nil
+	}
+	if err := results[len(results)-1]; !err.IsNil() {
+		return err.Interface().(error)
+	}
+	for _, r := range results[:len(results)-1] {
+		if _, err := fmt.Fprintln(c.out, r.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
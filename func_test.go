@@ -0,0 +1,87 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFunc(t *testing.T) {
+	var got []string
+	fn := func(ctx context.Context, src string, timeout time.Duration, force bool, rest ...string) error {
+		if ctx == nil {
+			return errors.New("missing context")
+		}
+		got = append([]string{src, timeout.String(), strings.Repeat("!", len(rest))}, rest...)
+		if force {
+			return errors.New("forced")
+		}
+		return nil
+	}
+	cmd, err := Func(fn, "<src>", "--timeout", "--force -f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	descr, err := Load(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := descr.Usage(false); !strings.Contains(usage, "--timeout") || !strings.Contains(usage, "<src>") {
+		t.Fatalf("expected parameters in usage, got: %s", usage)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "--timeout=3s", "foo", "x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "foo 3s !! x y" {
+		t.Fatalf("unexpected call: %v", got)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "-f", "bar"); err == nil || err.Error() != "forced" {
+		t.Fatalf("expected function error, got: %v", err)
+	}
+	// the parameters of an execution do not leak into the next one
+	if _, err := descr.Execute(context.Background(), nil, "baz"); err != nil || strings.Join(got, " ") != "baz 0s " {
+		t.Fatalf("unexpected call: %v, %v", got, err)
+	}
+	if descr, err = Load(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "--timeout=1s", "qux"); err != nil || strings.Join(got, " ") != "qux 1s " {
+		t.Fatalf("unexpected call: %v, %v", got, err)
+	}
+
+	plain, err := Func(func(a uint16, b string) {
+		got = []string{b}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descr, err = Load(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "1", "two"); err != nil || got[0] != "two" {
+		t.Fatalf("unexpected call: %v, %v", got, err)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "1", "two", "three"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error for extra args, got: %v", err)
+	}
+	if _, err := descr.Execute(context.Background(), nil, "x", "two"); !IsUsageErr(err) {
+		t.Fatalf("expected usage error for invalid arg, got: %v", err)
+	}
+
+	for _, fn := range []interface{}{
+		"not a function",
+		func() int { return 0 },
+		func(a ...int) {},
+		func(a struct{}) {},
+	} {
+		if _, err := Func(fn); err == nil {
+			t.Fatalf("expected error for %T", fn)
+		}
+	}
+	if _, err := Func(func(a, b string) {}, "<a>"); err == nil {
+		t.Fatal("expected error for missing name")
+	}
+}
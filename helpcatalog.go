@@ -0,0 +1,64 @@
+package ask
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// HelpCatalog looks up help text by key, for fields and groups tagged with `helpkey:"some.key"`,
+// so long or localized help text can be maintained outside of struct tags.
+type HelpCatalog interface {
+	HelpText(key string) (text string, ok bool)
+}
+
+// HelpMessages is a HelpCatalog of help text by key.
+type HelpMessages map[string]string
+
+func (m HelpMessages) HelpText(key string) (string, bool) {
+	text, ok := m[key]
+	return text, ok
+}
+
+// ReadHelpMessages reads help messages from a JSON object of help text by key.
+func ReadHelpMessages(r io.Reader) (HelpMessages, error) {
+	var m HelpMessages
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode help messages: %w", err)
+	}
+	return m, nil
+}
+
+var helpCatalog HelpCatalog
+
+// RegisterHelpCatalog registers the catalog to look up help keys with, if LoadOptions.HelpCatalog is not set.
+// The catalog is not safe to register concurrently with Load, register it during initialization.
+func RegisterHelpCatalog(catalog HelpCatalog) {
+	helpCatalog = catalog
+}
+
+// getHelp gets the help of a field: the text of the `helpkey` tag in the help catalog,
+// or else the `help` tag, which is the fallback for keys missing in the catalog.
+// A key without fallback is only an error if there is a catalog to miss it, so commands load without a catalog.
+func (l *loader) getHelp(f *reflect.StructField) (help string, ok bool, err error) {
+	help, ok = f.Tag.Lookup("help")
+	key, hasKey := f.Tag.Lookup("helpkey")
+	if !hasKey {
+		return help, ok, nil
+	}
+	catalog := helpCatalog
+	if l.opts != nil && l.opts.HelpCatalog != nil {
+		catalog = l.opts.HelpCatalog
+	}
+	if catalog == nil {
+		return help, ok, nil
+	}
+	if text, found := catalog.HelpText(key); found {
+		return text, true, nil
+	}
+	if ok {
+		return help, true, nil
+	}
+	return "", false, fmt.Errorf("field %q help key %q is not in the help catalog", f.Name, key)
+}
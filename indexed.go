@@ -0,0 +1,172 @@
+package ask
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxIndexedElements limits how many elements an indexed group can be expanded to with flags.
+const maxIndexedElements = 1024
+
+// indexedGroup is a slice of structs, loaded as group with a sub-group per element.
+// The sub-groups are named by index, e.g. "--upstream.0.addr" and "--upstream.1.addr".
+// Elements are added when flags refer to them by index.
+type indexedGroup struct {
+	// addressable slice value
+	slice  reflect.Value
+	loader *loader
+	// number of elements when loaded, to restore on reset
+	initial int
+}
+
+func isIndexedGroup(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Struct
+}
+
+func (l *loader) loadIndexedGroup(name string, val reflect.Value, initDefaults bool) (*FlagGroup, error) {
+	grp := &FlagGroup{
+		GroupName: name,
+		indexed:   &indexedGroup{slice: val, loader: l, initial: val.Len()},
+	}
+	if err := grp.indexed.loadElements(grp, val.Len(), initDefaults); err != nil {
+		return nil, err
+	}
+	return grp, nil
+}
+
+// loadElements loads the sub-group of each element.
+// The defaults of elements after the given index are initialized, if initDefaults is true.
+// Elements that were loaded before keep their original defaults.
+func (ig *indexedGroup) loadElements(grp *FlagGroup, from int, initDefaults bool) error {
+	prev := grp.Entries
+	grp.Entries = make([]*FlagGroup, 0, ig.slice.Len())
+	for i := 0; i < ig.slice.Len(); i++ {
+		sub, err := ig.loader.loadGroup(strconv.Itoa(i), ig.slice.Index(i).Addr(), initDefaults && i >= from)
+		if err != nil {
+			return fmt.Errorf("failed to load element %d of group %q: %v", i, grp.GroupName, err)
+		}
+		if i < len(prev) {
+			copyDefaults(prev[i], sub)
+		}
+		grp.Entries = append(grp.Entries, sub)
+	}
+	return nil
+}
+
+// copyDefaults copies the defaults of the flags of a group to the flags with the same path of another group,
+// including the values to restore with Reset.
+func copyDefaults(from *FlagGroup, to *FlagGroup) {
+	defaults := make(map[string]*Flag)
+	for _, pf := range from.All("") {
+		defaults[pf.Path] = pf.Flag
+	}
+	for _, pf := range to.All("") {
+		if d, ok := defaults[pf.Path]; ok {
+			pf.Default = d.Default
+			if pf.dest.IsValid() && d.initial.IsValid() {
+				pf.initial = d.initial
+			}
+		}
+	}
+}
+
+// grow increases the number of elements to n, if there are less elements.
+// Since the elements may move in memory, the flags of all elements are loaded again.
+func (ig *indexedGroup) grow(grp *FlagGroup, n int) error {
+	count := ig.slice.Len()
+	if n <= count {
+		return nil
+	}
+	ig.forgetElements()
+	out := reflect.MakeSlice(ig.slice.Type(), n, n)
+	reflect.Copy(out, ig.slice)
+	ig.slice.Set(out)
+	return ig.loadElements(grp, count, true)
+}
+
+// forgetElements removes the changed markers of the elements, before the elements move in memory.
+func (ig *indexedGroup) forgetElements() {
+	if count := ig.slice.Len(); count > 0 {
+		start := ig.slice.Pointer()
+		ig.loader.changes.removeWithin(start, start+uintptr(count)*ig.slice.Type().Elem().Size())
+	}
+}
+
+// reset removes the elements that were added after loading.
+func (ig *indexedGroup) reset(grp *FlagGroup) error {
+	if ig.slice.Len() <= ig.initial {
+		return nil
+	}
+	ig.forgetElements()
+	// copy to a new slice, to not retain the memory of the removed elements
+	out := reflect.MakeSlice(ig.slice.Type(), ig.initial, ig.initial)
+	reflect.Copy(out, ig.slice)
+	ig.slice.Set(out)
+	return ig.loadElements(grp, ig.initial, false)
+}
+
+// resetIndexed removes the elements that were added to indexed groups after loading.
+func (g *FlagGroup) resetIndexed() error {
+	if g.indexed != nil {
+		if err := g.indexed.reset(g); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Entries {
+		if err := e.resetIndexed(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// template loads the flags of a new element, to describe the flags of the group in usage info.
+func (ig *indexedGroup) template() (*FlagGroup, error) {
+	l := &loader{changes: NewChangedMarkers(), opts: ig.loader.opts}
+	return l.loadGroup("<index>", reflect.New(ig.slice.Type().Elem()), true)
+}
+
+// expandIndexed adds the elements to indexed groups that the given flag paths refer to.
+func (g *FlagGroup) expandIndexed(prefix string, paths []string) error {
+	path := g.path(prefix)
+	if g.indexed != nil {
+		n := 0
+		for _, p := range paths {
+			if !strings.HasPrefix(p, path+".") {
+				continue
+			}
+			index := p[len(path)+1:]
+			if i := strings.IndexByte(index, '.'); i >= 0 {
+				index = index[:i]
+			}
+			if i, err := strconv.Atoi(index); err == nil && i >= 0 && i < maxIndexedElements && i >= n {
+				n = i + 1
+			}
+		}
+		if err := g.indexed.grow(g, n); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Entries {
+		if err := e.expandIndexed(path, paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// argPaths returns the paths of the long flags in the arguments.
+func argPaths(args []string) []string {
+	var out []string
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if strings.HasPrefix(a, "--") {
+			out = append(out, strings.SplitN(a[2:], "=", 2)[0])
+		}
+	}
+	return out
+}
@@ -0,0 +1,20 @@
+package ask
+
+import "context"
+
+// injectKey is the context key of an injected value, unique per type.
+type injectKey[T any] struct{}
+
+// Inject returns a copy of the context with the value, to pass a typed dependency (e.g. a logger or client)
+// to commands through the context of Execute. The value is retrieved by type with From.
+// A value injected later replaces a value of the same type.
+func Inject[T any](ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, injectKey[T]{}, v)
+}
+
+// From retrieves the value of type T that was injected into the context with Inject.
+// The zero value and false are returned if no value of that type was injected.
+func From[T any](ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(injectKey[T]{}).(T)
+	return v, ok
+}
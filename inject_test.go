@@ -0,0 +1,51 @@
+package ask
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+type Greeter struct {
+	Prefix string
+}
+
+type InjectCmd struct {
+	Name string `ask:"--name"`
+	out  string
+}
+
+func (c *InjectCmd) Run(ctx context.Context, args ...string) error {
+	g, ok := From[*Greeter](ctx)
+	if !ok {
+		return io.ErrUnexpectedEOF
+	}
+	c.out = g.Prefix + c.Name
+	return nil
+}
+
+func TestInject(t *testing.T) {
+	ctx := Inject(context.Background(), &Greeter{Prefix: "hello "})
+	ctx = Inject[io.Writer](ctx, &strings.Builder{})
+	var c InjectCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(ctx, nil, "--name=world"); err != nil {
+		t.Fatal(err)
+	}
+	if c.out != "hello world" {
+		t.Fatalf("unexpected output: %q", c.out)
+	}
+	if _, ok := From[io.Writer](ctx); !ok {
+		t.Fatal("expected injected writer")
+	}
+	if _, ok := From[*strings.Builder](ctx); ok {
+		t.Fatal("values are retrieved by the injected type, not the dynamic type")
+	}
+	if _, err := cmd.Execute(context.Background(), nil); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected missing dependency, got: %v", err)
+	}
+}
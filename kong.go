@@ -0,0 +1,60 @@
+package ask
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// kongDecl derives the flag declaration of a field from kong-style tags, for fields without an ask tag.
+// Like kong, every exported field is a flag, unless tagged with `kong:"-"`.
+// Fields tagged with `embed:""` are squashed, and `cmd:""` fields are skipped: routes are declared with CommandRoute.
+func kongDecl(f *reflect.StructField) (v string, ok bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	if k, ok := f.Tag.Lookup("kong"); ok && k == "-" {
+		return "", false
+	}
+	if _, ok := f.Tag.Lookup("cmd"); ok {
+		return "", false
+	}
+	if _, ok := f.Tag.Lookup("embed"); ok {
+		return ".", true
+	}
+	name, ok := f.Tag.Lookup("name")
+	if !ok || name == "" {
+		name = kebabCase(f.Name)
+	}
+	if _, ok := f.Tag.Lookup("arg"); ok {
+		_, optional := f.Tag.Lookup("optional")
+		_, hasDefault := f.Tag.Lookup("default")
+		if optional || hasDefault {
+			return "[" + name + "]", true
+		}
+		return "<" + name + ">", true
+	}
+	v = "--" + name
+	if s, ok := f.Tag.Lookup("short"); ok && s != "" {
+		v += " -" + s
+	}
+	return v, true
+}
+
+// kebabCase converts a Go field name to a flag name, e.g. "HTTPAddr" becomes "http-addr".
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var out strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// start a new word after a lowercase letter, or at the last capital of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))) {
+				out.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
@@ -0,0 +1,84 @@
+package ask
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintIssue is a problem found in a command description by a LintRule.
+type LintIssue struct {
+	// Path of the flag the issue is about
+	Path string
+	// Message explains the issue
+	Message string
+}
+
+func (v LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// LintRule checks a flag, and returns a message for each issue that is found.
+type LintRule func(fl PrefixedFlag) []string
+
+// Lint checks all flags of the command with the given rules, and returns the issues that are found.
+// Hidden flags are checked too.
+func (descr *CommandDescription) Lint(rules ...LintRule) []LintIssue {
+	var out []LintIssue
+	for _, fl := range descr.All("") {
+		for _, rule := range rules {
+			for _, msg := range rule(fl) {
+				out = append(out, LintIssue{Path: fl.Path, Message: msg})
+			}
+		}
+	}
+	return out
+}
+
+// HelpLintRules is the rule set to enforce consistent help copy:
+// help is present, does not end with a period, is at most maxLen chars,
+// and has a placeholder for flags that are not boolean.
+func HelpLintRules(maxLen int) []LintRule {
+	return []LintRule{HelpPresentRule, HelpNoPeriodRule, HelpMaxLenRule(maxLen), HelpPlaceholderRule}
+}
+
+// HelpPresentRule checks that the flag has help information.
+func HelpPresentRule(fl PrefixedFlag) []string {
+	if strings.TrimSpace(fl.Help) == "" {
+		return []string{"help is missing"}
+	}
+	return nil
+}
+
+// HelpNoPeriodRule checks that the help does not end with a period.
+func HelpNoPeriodRule(fl PrefixedFlag) []string {
+	if strings.HasSuffix(strings.TrimSpace(fl.Help), ".") {
+		return []string{"help ends with a period"}
+	}
+	return nil
+}
+
+// HelpMaxLenRule checks that the help is not longer than the given number of characters.
+func HelpMaxLenRule(maxLen int) LintRule {
+	return func(fl PrefixedFlag) []string {
+		if n := len([]rune(fl.Help)); n > maxLen {
+			return []string{fmt.Sprintf("help is %d chars, longer than %d", n, maxLen)}
+		}
+		return nil
+	}
+}
+
+// HelpPlaceholderRule checks that the help of a flag that is not boolean names its value with a placeholder,
+// quoted in back-ticks, e.g. "connect to `addr`". Positional args are named already, and are not checked.
+func HelpPlaceholderRule(fl PrefixedFlag) []string {
+	if fl.IsArg {
+		return nil
+	}
+	if _, ok := fl.Value.(ImplicitValue); ok {
+		return nil
+	}
+	start := strings.IndexByte(fl.Help, '`')
+	if start < 0 || strings.IndexByte(fl.Help[start+1:], '`') <= 0 {
+		return []string{"help has no `placeholder` for the flag value"}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package ask
+
+import (
+	"strings"
+	"testing"
+)
+
+type LintCmd struct {
+	Addr    string `ask:"--addr" help:"Address to connect to."`
+	Port    uint16 `ask:"--port" help:"Listen on \x60port\x60"`
+	Verbose bool   `ask:"--verbose" help:"Log everything, and then some more"`
+	Name    string `ask:"<name>"`
+}
+
+func TestLint(t *testing.T) {
+	cmd, err := Load(&LintCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []string
+	for _, issue := range cmd.Lint(HelpLintRules(20)...) {
+		issues = append(issues, issue.String())
+	}
+	expected := []string{
+		"addr: help ends with a period",
+		"addr: help is 22 chars, longer than 20",
+		"addr: help has no `placeholder` for the flag value",
+		"verbose: help is 34 chars, longer than 20",
+		"name: help is missing",
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(issues, "\n"))
+	}
+}
@@ -0,0 +1,318 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/protolambda/ask/askparse"
+)
+
+// PanicErr is returned by Main when the command panicked during execution.
+type PanicErr struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (p *PanicErr) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// MainOptions configures how Main runs a command and reports errors.
+type MainOptions struct {
+	// VerboseErrors renders the full chain of wrapped errors, and the stack trace of a recovered panic.
+	VerboseErrors bool
+	// VerboseErrorsFlag is the name of a flag (without "--" prefix) that enables VerboseErrors.
+	// The flag is removed from the arguments before the command executes. Empty to disable.
+	VerboseErrorsFlag string
+	// HelpFilter only includes the flags and groups that contain the given text in the usage, see WithFilter.
+	HelpFilter string
+	// HelpFilterFlag is the name of a flag (without "--" prefix) that sets HelpFilter, and asks for help.
+	// The flag is removed from the arguments before the command executes. Empty to disable.
+	HelpFilterFlag string
+	// FullDefaults renders long defaults in full in the usage, see WithFullDefaults.
+	FullDefaults bool
+	// FullDefaultsFlag is the name of a flag (without "--" prefix) that sets FullDefaults, and asks for help.
+	// The flag is removed from the arguments before the command executes. Empty to disable.
+	FullDefaultsFlag string
+	// PrintConfig makes Main print the effective configuration of the command in the format, instead of running it.
+	// See DumpConfig. Empty to run the command.
+	PrintConfig ConfigFormat
+	// PrintConfigFlag is the name of a flag (without "--" prefix) that sets PrintConfig to YAML,
+	// or to the format of its value, e.g. "--print-config=json".
+	// The flag is removed from the arguments before the command executes. Empty to disable.
+	PrintConfigFlag string
+	// App is the name of the application in the usage footer. Defaults to the name of the executable.
+	App string
+	// Footer is rendered at the end of the usage of every command, see WithFooter. Empty to disable.
+	Footer string
+	// AliasesFile is the path of the aliases file of the end user, see ReadAliases and DefaultAliasesPath.
+	// The first argument is expanded if it is an alias, before routing.
+	// The aliases are listed with `alias list`. Empty to disable.
+	AliasesFile string
+}
+
+// DefaultMainOptions enables the Main-level flags with their conventional names, e.g. "--verbose-errors".
+// Without options, Main takes no flags out of the arguments, like Run.
+func DefaultMainOptions() *MainOptions {
+	return &MainOptions{VerboseErrorsFlag: "verbose-errors", HelpFilterFlag: "help-filter", FullDefaultsFlag: "help-full-defaults",
+		PrintConfigFlag: "print-config"}
+}
+
+// Usage renders the usage of the command, following the options.
+func (opts *MainOptions) Usage(cmd *CommandDescription) string {
+	var options []UsageOption
+	if opts.HelpFilter != "" {
+		options = append(options, WithFilter(opts.HelpFilter))
+	}
+	if opts.FullDefaults {
+		options = append(options, WithFullDefaults())
+	}
+	if opts.Footer != "" {
+		app := opts.App
+		if app == "" {
+			app = filepath.Base(os.Args[0])
+		}
+		options = append(options, WithFooter(app, opts.Footer))
+	}
+	return cmd.Usage(os.Getenv("HIDDEN_OPTIONS") != "", options...)
+}
+
+// RenderErr formats an error to print to the user.
+// The error message is brief by default, see VerboseErrors.
+func (opts *MainOptions) RenderErr(err error) string {
+	if !opts.VerboseErrors {
+		return err.Error()
+	}
+	var out strings.Builder
+	out.WriteString(err.Error())
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		out.WriteString("\n  caused by: ")
+		out.WriteString(cause.Error())
+	}
+	var panicErr *PanicErr
+	if errors.As(err, &panicErr) {
+		out.WriteString("\n\n")
+		out.Write(panicErr.Stack)
+	}
+	return out.String()
+}
+
+// parseArgs takes the Main-level flags out of the arguments,
+// and returns the remaining arguments for the command to execute.
+// Main-level flags that the command defines itself are left to the command,
+// and so are the arguments of a RawArgs sub-command, from its route on.
+// A Main-level flag without its required value is a usage error.
+func (opts *MainOptions) parseArgs(descr *CommandDescription, args []string) ([]string, error) {
+	name := func(flagName string) string {
+		if _, ok := descr.Lookup(flagName); ok {
+			return ""
+		}
+		return flagName
+	}
+	verboseErrorsFlag, fullDefaultsFlag := name(opts.VerboseErrorsFlag), name(opts.FullDefaultsFlag)
+	printConfigFlag, helpFilterFlag := name(opts.PrintConfigFlag), name(opts.HelpFilterFlag)
+	out := make([]string, 0, len(args))
+	var rest []string
+	fullDefaults := false
+	// the routes are followed like Execute does: through the early flags, up to the first other flag or argument
+	execOpts := &ExecutionOptions{}
+	cmd := descr
+	routing := true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = args[i:]
+			break
+		}
+		if routing && !strings.HasPrefix(a, "-") {
+			if sub := cmd.routeEarly(execOpts, a); sub != nil {
+				if _, raw := sub.Command.(RawArgs); raw {
+					rest = args[i:]
+					break
+				}
+				cmd = sub
+				out = append(out, a)
+				continue
+			}
+			routing = false
+		}
+		if verboseErrorsFlag != "" && a == "--"+verboseErrorsFlag {
+			opts.VerboseErrors = true
+			continue
+		}
+		if fullDefaultsFlag != "" && a == "--"+fullDefaultsFlag {
+			opts.FullDefaults = true
+			fullDefaults = true
+			continue
+		}
+		if printConfigFlag != "" {
+			if a == "--"+printConfigFlag {
+				opts.PrintConfig = ConfigYAML
+				continue
+			}
+			if strings.HasPrefix(a, "--"+printConfigFlag+"=") {
+				opts.PrintConfig = ConfigFormat(a[len(printConfigFlag)+3:])
+				continue
+			}
+		}
+		if helpFilterFlag != "" {
+			if a == "--"+helpFilterFlag {
+				if i+1 == len(args) || args[i+1] == "--" {
+					return nil, &UsageErr{fmt.Errorf("flag --%s requires a value", helpFilterFlag)}
+				}
+				opts.HelpFilter = args[i+1]
+				i++
+				continue
+			}
+			if strings.HasPrefix(a, "--"+helpFilterFlag+"=") {
+				opts.HelpFilter = a[len(helpFilterFlag)+3:]
+				continue
+			}
+		}
+		if tok, err := execOpts.syntax().Tokenize(a); routing && err == nil && tok.Kind == askparse.Long {
+			// an early flag and its value may come before the route, of the root or of the routed command
+			early := descr.earlyFlags()
+			if !hasLongFlag(early, tok.Name) {
+				early = cmd.earlyFlags()
+			}
+			if hasLongFlag(early, tok.Name) {
+				next, err := execOpts.ParseOptions.ParseLongArg(early, a, args[i+1:], func(fl PrefixedFlag, value string) error {
+					return nil
+				})
+				if err == nil {
+					end := len(args) - len(next)
+					out = append(out, args[i:end]...)
+					i = end - 1
+					continue
+				}
+			}
+		}
+		routing = false
+		out = append(out, a)
+	}
+	if opts.HelpFilter != "" || fullDefaults {
+		out = append(out, "--help")
+	}
+	return append(out, rest...), nil
+}
+
+type start struct {
+	cmd *CommandDescription
+	err error
+}
+
+// Run is a convenience-method to run a command: it handles long-running commands and shuts down on os.Interrupt signal.
+// Arguments are read from os.Args[1:] (i.e. program name is skipped).
+// Set the "HIDDEN_OPTIONS" env var to show hidden CLI options.
+// Run is the same as Main without options.
+func Run(cmd interface{}) {
+	Main(cmd, nil)
+}
+
+// Main runs a command like Run, with options to configure error reporting.
+// Panics during execution are recovered and reported as PanicErr.
+// Usage information is only printed if help was requested or if the command was used incorrectly (see UsageErr),
+// errors of the command itself are printed without usage.
+func Main(cmd interface{}, opts *MainOptions) {
+	// work on a copy, the Main-level flags change the options
+	if opts == nil {
+		opts = &MainOptions{}
+	} else {
+		o := *opts
+		opts = &o
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	descr, err := Load(cmd)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to load main command: %v", opts.RenderErr(err))
+		os.Exit(1)
+	}
+	onDeprecated := func(fl PrefixedFlag) error {
+		fmt.Fprintf(os.Stderr, "warning: flag %q is deprecated: %s", fl.Path, fl.Deprecated)
+		return nil
+	}
+
+	args, done, err := opts.expandAliases(os.Args[1:], os.Stdout)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, opts.RenderErr(err))
+		os.Exit(1)
+	}
+	if done {
+		os.Exit(0)
+	}
+	args, err = opts.parseArgs(descr, args)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, opts.RenderErr(err))
+		os.Exit(1)
+	}
+
+	starter := make(chan start)
+
+	// run command in the background, so we can stop it at any time
+	go func() {
+		defer func() {
+			if x := recover(); x != nil {
+				starter <- start{nil, &PanicErr{Value: x, Stack: debug.Stack()}}
+			}
+		}()
+		cmd, err := descr.Execute(ctx, &ExecutionOptions{OnDeprecated: onDeprecated, ParseOnly: opts.PrintConfig != ""}, args...)
+		starter <- start{cmd, err}
+	}()
+
+	for {
+		select {
+		case start := <-starter:
+			if cmd, err := start.cmd, start.err; err == nil && opts.PrintConfig != "" {
+				if err := cmd.DumpConfig(os.Stdout, opts.PrintConfig); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, opts.RenderErr(err))
+					os.Exit(1)
+				}
+				os.Exit(0)
+			} else if err == nil {
+				// if the command is long-running and closeable later on, then have the interrupt close it.
+				if _, ok := cmd.Command.(io.Closer); ok {
+					<-interrupt
+				}
+				// close the command and the flag values that hold resources
+				err := cmd.Close()
+				cancel()
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "failed to close gracefully. Exiting in 5 seconds. %v", opts.RenderErr(err))
+					<-time.After(time.Second * 5)
+					os.Exit(1)
+				}
+				os.Exit(0)
+			} else if err == UnrecognizedErr {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			} else if err == HelpErr {
+				_, _ = fmt.Fprintln(os.Stderr, opts.Usage(cmd))
+				os.Exit(0)
+			} else if IsUsageErr(err) && cmd != nil {
+				_, _ = fmt.Fprintln(os.Stderr, opts.RenderErr(err))
+				_, _ = fmt.Fprintln(os.Stderr)
+				_, _ = fmt.Fprintln(os.Stderr, opts.Usage(cmd))
+				os.Exit(1)
+			} else {
+				_, _ = fmt.Fprintln(os.Stderr, opts.RenderErr(err))
+				os.Exit(1)
+			}
+		case <-interrupt: // if interrupted during start, then we try to cancel
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type MainCmd struct{}
+
+func (c *MainCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+// MainFlagsCmd defines a flag with the name of a Main-level flag
+type MainFlagsCmd struct {
+	PrintConfig bool `ask:"--print-config"`
+}
+
+func (c *MainFlagsCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+// MainRouteCmd routes to a RawArgs command
+type MainRouteCmd struct {
+	Env string `ask:"--env" early:"true"`
+}
+
+func (c *MainRouteCmd) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "exec":
+		return &ExecCmd{}, nil
+	case "run":
+		return &MainCmd{}, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+func mainCmd(t *testing.T, cmd interface{}) *CommandDescription {
+	descr, err := Load(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return descr
+}
+
+func TestRenderErr(t *testing.T) {
+	opts := DefaultMainOptions()
+	args, err := opts.parseArgs(mainCmd(t, &MainCmd{}), []string{"foo", "--verbose-errors", "--", "--verbose-errors"})
+	if err != nil || strings.Join(args, " ") != "foo -- --verbose-errors" || !opts.VerboseErrors {
+		t.Fatalf("unexpected args: %v (verbose: %v)", args, opts.VerboseErrors)
+	}
+	err = fmt.Errorf("outer: %w", &PanicErr{Value: "oops", Stack: []byte("stack here")})
+	if out := opts.RenderErr(err); out != "outer: panic: oops\n  caused by: panic: oops\n\nstack here" {
+		t.Fatalf("unexpected verbose error: %q", out)
+	}
+	opts.VerboseErrors = false
+	if out := opts.RenderErr(err); out != "outer: panic: oops" {
+		t.Fatalf("unexpected brief error: %q", out)
+	}
+}
+
+func TestHelpFilterArgs(t *testing.T) {
+	opts := DefaultMainOptions()
+	args, err := opts.parseArgs(mainCmd(t, &MainCmd{}), []string{"connect", "--help-filter", "peer", "--", "x"})
+	if err != nil || strings.Join(args, " ") != "connect --help -- x" || opts.HelpFilter != "peer" {
+		t.Fatalf("unexpected args: %v (filter: %q)", args, opts.HelpFilter)
+	}
+	for _, args := range [][]string{{"connect", "--help-filter"}, {"connect", "--help-filter", "--", "x"}} {
+		if _, err := DefaultMainOptions().parseArgs(mainCmd(t, &MainCmd{}), args); !IsUsageErr(err) {
+			t.Fatalf("%v: expected usage error for missing filter, got: %v", args, err)
+		}
+	}
+}
+
+func TestFullDefaultsArgs(t *testing.T) {
+	opts := DefaultMainOptions()
+	args, err := opts.parseArgs(mainCmd(t, &MainCmd{}), []string{"connect", "--help-full-defaults"})
+	if err != nil || strings.Join(args, " ") != "connect --help" || !opts.FullDefaults {
+		t.Fatalf("unexpected args: %v (full defaults: %v)", args, opts.FullDefaults)
+	}
+}
+
+func TestPrintConfigArgs(t *testing.T) {
+	opts := DefaultMainOptions()
+	args, err := opts.parseArgs(mainCmd(t, &MainCmd{}), []string{"connect", "--print-config=json", "--port=1"})
+	if err != nil || strings.Join(args, " ") != "connect --port=1" || opts.PrintConfig != ConfigJSON {
+		t.Fatalf("unexpected args: %v (print config: %q)", args, opts.PrintConfig)
+	}
+}
+
+func TestMainFlagsOptIn(t *testing.T) {
+	opts := &MainOptions{}
+	args, err := opts.parseArgs(mainCmd(t, &MainCmd{}), []string{"connect", "--verbose-errors", "--help-filter", "peer"})
+	if err != nil || strings.Join(args, " ") != "connect --verbose-errors --help-filter peer" || opts.VerboseErrors || opts.HelpFilter != "" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	// the command defines --print-config itself
+	opts = DefaultMainOptions()
+	args, err = opts.parseArgs(mainCmd(t, &MainFlagsCmd{}), []string{"--print-config", "--verbose-errors"})
+	if err != nil || strings.Join(args, " ") != "--print-config" || opts.PrintConfig != "" || !opts.VerboseErrors {
+		t.Fatalf("unexpected args: %v (print config: %q)", args, opts.PrintConfig)
+	}
+}
+
+func TestMainFlagsRawArgs(t *testing.T) {
+	opts := DefaultMainOptions()
+	args, err := opts.parseArgs(mainCmd(t, &MainRouteCmd{}), []string{"--verbose-errors", "--env", "prod", "exec", "alpine", "--help-filter", "peer", "--verbose-errors"})
+	if err != nil || strings.Join(args, " ") != "--env prod exec alpine --help-filter peer --verbose-errors" || !opts.VerboseErrors || opts.HelpFilter != "" {
+		t.Fatalf("unexpected args: %v (filter: %q)", args, opts.HelpFilter)
+	}
+	// other commands keep having the Main-level flags taken out
+	opts = DefaultMainOptions()
+	args, err = opts.parseArgs(mainCmd(t, &MainRouteCmd{}), []string{"run", "--verbose-errors", "exec"})
+	if err != nil || strings.Join(args, " ") != "run exec" || !opts.VerboseErrors {
+		t.Fatalf("unexpected args: %v (verbose: %v)", args, opts.VerboseErrors)
+	}
+}
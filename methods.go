@@ -0,0 +1,67 @@
+package ask
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// MethodRoutes is a CommandRoute with a sub-command per method of an interface, see Methods.
+type MethodRoutes struct {
+	impl reflect.Value
+	// method name by route
+	methods map[string]string
+	routes  []string
+	names   map[string][]string
+	// Out is where the results of the methods are printed to, other than the error. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// Methods derives sub-commands from the methods of interface T, e.g. a service API, to call the methods of impl.
+// The routes are the method names in kebab-case, e.g. "PeerCount" is routed as "peer-count".
+// The parameters of each method are loaded like Func, with optional names per method name, e.g.
+// {"AddPeer": {"<addr>", "--trusted"}}. Methods may return results before the last error result,
+// which are printed to Out.
+func Methods[T any](impl T, names map[string][]string) (*MethodRoutes, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("expected an interface type, got %s", typ)
+	}
+	v := reflect.ValueOf(impl)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil implementation of %s", typ)
+	}
+	m := &MethodRoutes{impl: v, methods: make(map[string]string), names: names}
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := typ.Method(i).Name
+		route := kebabCase(name)
+		if _, err := newFuncCmd(v.MethodByName(name), io.Discard, names[name]); err != nil {
+			return nil, fmt.Errorf("method %s: %w", name, err)
+		}
+		m.methods[route] = name
+		m.routes = append(m.routes, route)
+	}
+	for name := range names {
+		if _, ok := typ.MethodByName(name); !ok {
+			return nil, fmt.Errorf("names for unknown method %s", name)
+		}
+	}
+	return m, nil
+}
+
+func (m *MethodRoutes) Cmd(route string) (cmd interface{}, err error) {
+	name, ok := m.methods[route]
+	if !ok {
+		return nil, UnrecognizedErr
+	}
+	out := m.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	return newFuncCmd(m.impl.MethodByName(name), out, m.names[name])
+}
+
+func (m *MethodRoutes) Routes() []string {
+	return m.routes
+}
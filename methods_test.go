@@ -0,0 +1,80 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type AdminAPI interface {
+	AddPeer(ctx context.Context, addr string, trusted bool) error
+	PeerCount() (int, error)
+	RemovePeer(addr string) error
+}
+
+type adminServer struct {
+	peers map[string]bool
+}
+
+func (s *adminServer) AddPeer(ctx context.Context, addr string, trusted bool) error {
+	s.peers[addr] = trusted
+	return nil
+}
+
+func (s *adminServer) PeerCount() (int, error) {
+	return len(s.peers), nil
+}
+
+func (s *adminServer) RemovePeer(addr string) error {
+	if _, ok := s.peers[addr]; !ok {
+		return errors.New("unknown peer")
+	}
+	delete(s.peers, addr)
+	return nil
+}
+
+func TestMethods(t *testing.T) {
+	srv := &adminServer{peers: make(map[string]bool)}
+	routes, err := Methods[AdminAPI](srv, map[string][]string{"AddPeer": {"<addr>", "--trusted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	routes.Out = &out
+	if got := strings.Join(routes.Routes(), " "); got != "add-peer peer-count remove-peer" {
+		t.Fatalf("unexpected routes: %s", got)
+	}
+	cmd, err := Load(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "add-peer", "--trusted", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.peers["foo"] {
+		t.Fatalf("expected trusted peer, got: %v", srv.peers)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "peer-count"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "remove-peer", "bar"); err == nil || err.Error() != "unknown peer" {
+		t.Fatalf("expected method error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "stop"); err != UnrecognizedErr {
+		t.Fatalf("expected unrecognized route, got: %v", err)
+	}
+
+	if _, err := Methods[*adminServer](srv, nil); err == nil {
+		t.Fatal("expected error for non-interface type")
+	}
+	if _, err := Methods[AdminAPI](srv, map[string][]string{"Stop": nil}); err == nil {
+		t.Fatal("expected error for names of unknown method")
+	}
+	if _, err := Methods[AdminAPI](nil, nil); err == nil {
+		t.Fatal("expected error for nil implementation")
+	}
+}
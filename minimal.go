@@ -0,0 +1,81 @@
+//go:build ask_minimal
+
+package ask
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// The ask_minimal build tag compiles out optional subsystems and their dependencies, for minimal binaries:
+// execution recording (see ExecutionOptions.Record), the `format:"json"` struct tag, user aliases
+// (see MainOptions.AliasesFile), config files (see UserConfig), completion (see Suggest) and docs (see Markdown).
+// Using them results in an error, or in no completions and empty docs.
+//
+// The methods of a command are kept in every binary that loads commands, since method commands are looked up by name,
+// so the subsystems cannot be left out by the linker alone. A switch at runtime would not make a binary smaller,
+// so the subsystems are only gated by the build tag.
+
+func minimalErr(feature string) error {
+	return fmt.Errorf("%s is not available in builds with the ask_minimal tag", feature)
+}
+
+func recordExecution(w io.Writer, opts *ParseOptions, start time.Time, root, final *CommandDescription, args []string, err error) error {
+	return minimalErr("execution recording")
+}
+
+func jsonValue(val reflect.Value) (flag.Value, error) {
+	return nil, minimalErr("the json format")
+}
+
+func (opts *MainOptions) expandAliases(args []string, out io.Writer) (expanded []string, done bool, err error) {
+	if opts.AliasesFile != "" {
+		return nil, false, minimalErr("aliases")
+	}
+	return args, false, nil
+}
+
+func UserConfigPath(app string) (string, error) {
+	return "", minimalErr("config files")
+}
+
+func LoadConfigFile(path string) (map[string]string, error) {
+	return nil, minimalErr("config files")
+}
+
+func UserConfig(app string) func(cmd *CommandDescription) error {
+	return func(cmd *CommandDescription) error {
+		return minimalErr("config files")
+	}
+}
+
+func StrictUserConfig(app string) func(cmd *CommandDescription) error {
+	return UserConfig(app)
+}
+
+func ReadConfigYAML(r io.Reader) (map[string]string, error) {
+	return nil, minimalErr("config files")
+}
+
+// Suggest has no completions in minimal builds.
+func (descr *CommandDescription) Suggest(partialArgs []string) []Suggestion {
+	return nil
+}
+
+// Candidates has no completions in minimal builds.
+func (p *Parser) Candidates(partial string) []string {
+	return nil
+}
+
+// Markdown renders no docs in minimal builds.
+func (descr *CommandDescription) Markdown(name string, options ...UsageOption) string {
+	return ""
+}
+
+// Man renders no docs in minimal builds.
+func (descr *CommandDescription) Man(name string, section int, options ...UsageOption) string {
+	return ""
+}
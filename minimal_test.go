@@ -0,0 +1,38 @@
+//go:build ask_minimal
+
+package ask
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMinimal(t *testing.T) {
+	if _, err := Load(&struct {
+		X map[string]int `ask:"--x" format:"json"`
+	}{}); err == nil || !strings.Contains(err.Error(), "ask_minimal") {
+		t.Fatalf("expected json format to be unavailable, got: %v", err)
+	}
+	cmd, err := Load(&RelationsCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), &ExecutionOptions{Record: io.Discard}); err == nil {
+		t.Fatal("expected recording to be unavailable")
+	}
+	if _, _, err := (&MainOptions{AliasesFile: "aliases"}).expandAliases([]string{"x"}, io.Discard); err == nil {
+		t.Fatal("expected aliases to be unavailable")
+	}
+	if args, _, err := (&MainOptions{}).expandAliases([]string{"x"}, io.Discard); err != nil || args[0] != "x" {
+		t.Fatalf("expected args without aliases, got: %v, %v", args, err)
+	}
+	opts := &ExecutionOptions{Configure: UserConfig("myapp")}
+	if _, err := cmd.Execute(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "ask_minimal") {
+		t.Fatalf("expected config files to be unavailable, got: %v", err)
+	}
+	if cmd.Suggest([]string{"--"}) != nil || cmd.Markdown("app") != "" || cmd.Man("app", 1) != "" {
+		t.Fatal("expected no completions and docs")
+	}
+}
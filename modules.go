@@ -0,0 +1,141 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Module is a reusable part of an application, e.g. a database client or a metrics server,
+// with its own flags and lifecycle. The module is a pointer to a struct with flag fields, like a command.
+type Module interface {
+	// Setup starts the module, after the flags are parsed.
+	Setup(ctx context.Context) error
+	// Close stops the module. It is only called if Setup succeeded.
+	Close(ctx context.Context) error
+}
+
+// DefaultModuleCloseTimeout limits the time to close the modules of a set, if ModuleSet.CloseTimeout is zero.
+const DefaultModuleCloseTimeout = 10 * time.Second
+
+// ModuleSet loads the flags of modules under a prefix each, and sets up and closes the modules around a command.
+// Add the modules in InitDefault of the command, and declare the set as field with `ask:"."`,
+// or with `ask:".name"` to prefix the flags of all modules.
+//
+// Execute sets up the modules before the command runs, and closes them in reverse order when Run returns.
+// The modules of a long-running command, that implements io.Closer, are closed with CommandDescription.Close instead.
+// Modules are closed with a fresh context with the CloseTimeout, since the context of the execution may be canceled.
+type ModuleSet struct {
+	// CloseTimeout limits the time to close the modules, defaults to DefaultModuleCloseTimeout.
+	CloseTimeout time.Duration
+	prefixes     []string
+	modules      []Module
+	// the modules that were set up, in order of setup
+	started []Module
+}
+
+var moduleSetType = reflect.TypeOf((*ModuleSet)(nil))
+
+// Add a module, with its flags prefixed by name. An empty name adds the flags without prefix.
+// Modules are set up in the order they are added, and closed in reverse order.
+func (s *ModuleSet) Add(name string, m Module) {
+	s.prefixes = append(s.prefixes, name)
+	s.modules = append(s.modules, m)
+}
+
+// Modules lists the modules, in order of setup.
+func (s *ModuleSet) Modules() []Module {
+	return s.modules
+}
+
+// setup sets up the modules in order, and stops at the first error.
+func (s *ModuleSet) setup(ctx context.Context) error {
+	for _, m := range s.modules {
+		if err := m.Setup(ctx); err != nil {
+			return fmt.Errorf("failed to set up module %s: %w", s.name(m), err)
+		}
+		s.started = append(s.started, m)
+	}
+	return nil
+}
+
+// close closes the modules that were set up, in reverse order, with a fresh context that is limited by CloseTimeout.
+func (s *ModuleSet) close(ctx context.Context) error {
+	if len(s.started) == 0 {
+		return nil
+	}
+	timeout := s.CloseTimeout
+	if timeout == 0 {
+		timeout = DefaultModuleCloseTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	var errs []error
+	for i := len(s.started) - 1; i >= 0; i-- {
+		if err := s.started[i].Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close module %s: %w", s.name(s.started[i]), err))
+		}
+	}
+	s.started = nil
+	return errors.Join(errs...)
+}
+
+// setupModules sets up the module sets of the command, in order.
+// If a module fails to set up, the modules that were set up are closed.
+func (descr *CommandDescription) setupModules(ctx context.Context) error {
+	for _, s := range descr.modules {
+		if err := s.setup(ctx); err != nil {
+			if closeErr := descr.closeModules(ctx); closeErr != nil {
+				return errors.Join(err, closeErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// closeModules closes the modules of the command that were set up, in reverse order.
+func (descr *CommandDescription) closeModules(ctx context.Context) error {
+	var errs []error
+	for i := len(descr.modules) - 1; i >= 0; i-- {
+		if err := descr.modules[i].close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// name describes the module in errors, by its prefix, or its type if it has no prefix.
+func (s *ModuleSet) name(m Module) string {
+	for i, v := range s.modules {
+		if v == m && s.prefixes[i] != "" {
+			return s.prefixes[i]
+		}
+	}
+	return fmt.Sprintf("%T", m)
+}
+
+// fillModules loads the flags of the modules of the set into the group, each in a sub-group named after its prefix.
+func (l *loader) fillModules(grp *FlagGroup, s *ModuleSet, initDefaults bool) error {
+	l.modules = append(l.modules, s)
+	for i, m := range s.modules {
+		val := reflect.ValueOf(m)
+		if val.Kind() != reflect.Ptr || val.IsNil() {
+			return fmt.Errorf("module %T must be a non-nil pointer", m)
+		}
+		if s.prefixes[i] == "" {
+			if err := l.fillGroup(grp, val, initDefaults); err != nil {
+				return fmt.Errorf("failed to load module %T: %w", m, err)
+			}
+			continue
+		}
+		subGrp, err := l.loadGroup(s.prefixes[i], val, initDefaults)
+		if err != nil {
+			return fmt.Errorf("failed to load module %s: %w", s.prefixes[i], err)
+		}
+		grp.Entries = append(grp.Entries, subGrp)
+	}
+	return nil
+}
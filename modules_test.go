@@ -0,0 +1,82 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testModule struct {
+	Addr string `ask:"--addr" help:"Address of the module"`
+	fail bool
+	log  *[]string
+}
+
+func (m *testModule) Setup(ctx context.Context) error {
+	if m.fail {
+		return errors.New("unavailable")
+	}
+	*m.log = append(*m.log, "setup "+m.Addr)
+	return nil
+}
+
+func (m *testModule) Close(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok || ctx.Err() != nil {
+		return errors.New("expected a fresh context with a timeout")
+	}
+	*m.log = append(*m.log, "close "+m.Addr)
+	return nil
+}
+
+type ModulesCmd struct {
+	Modules ModuleSet `ask:"."`
+	DB      *testModule
+	Metrics *testModule
+	log     []string
+	// cancels the context of the execution during Run, to check that the modules are still closed properly
+	cancel context.CancelFunc
+}
+
+func (c *ModulesCmd) Default() {
+	c.DB = &testModule{Addr: "localhost:5432", log: &c.log}
+	c.Metrics = &testModule{Addr: "localhost:9090", log: &c.log}
+	c.Modules.Add("db", c.DB)
+	c.Modules.Add("metrics", c.Metrics)
+}
+
+func (c *ModulesCmd) Run(ctx context.Context, args ...string) error {
+	c.log = append(c.log, "run")
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestModuleSet(t *testing.T) {
+	var c ModulesCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, ok := cmd.Lookup("metrics.addr"); !ok || pf.Default != "localhost:9090" {
+		t.Fatalf("expected module flag, got: %+v", pf)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	if _, err := cmd.Execute(ctx, nil, "--db.addr=db:5432"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.log, ", "); got != "setup db:5432, setup localhost:9090, run, close localhost:9090, close db:5432" {
+		t.Fatalf("unexpected lifecycle: %s", got)
+	}
+
+	c.log = nil
+	c.Metrics.fail = true
+	if _, err := cmd.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "failed to set up module metrics: unavailable") {
+		t.Fatalf("expected setup error, got: %v", err)
+	}
+	if got := strings.Join(c.log, ", "); got != "setup db:5432, close db:5432" {
+		t.Fatalf("expected started modules to close, got: %s", got)
+	}
+}
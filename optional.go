@@ -0,0 +1,77 @@
+package ask
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// Optional is a flag value that tells if the flag was provided, to distinguish an omitted flag from an explicit zero value,
+// e.g. `Port Optional[uint16] ask:"--port"`. The value is parsed like a field of type T would be.
+// An unset Optional has no default in the usage. For booleans TriState is available, which can be used without value.
+type Optional[T any] struct {
+	Value T
+	set   bool
+}
+
+// inner returns the flag value of the wrapped value.
+func (o *Optional[T]) inner() (flag.Value, error) {
+	v := reflect.ValueOf(&o.Value).Elem()
+	return FlagValue(v.Type(), v)
+}
+
+func (o *Optional[T]) Set(s string) error {
+	v, err := o.inner()
+	if err != nil {
+		return err
+	}
+	if err := v.Set(s); err != nil {
+		return err
+	}
+	o.set = true
+	return nil
+}
+
+func (o *Optional[T]) String() string {
+	if !o.set {
+		return ""
+	}
+	v, err := o.inner()
+	if err != nil {
+		return ""
+	}
+	return v.String()
+}
+
+// Canonical renders the value like a field of type T would be, if the flag was provided.
+func (o *Optional[T]) Canonical() (string, error) {
+	if !o.set {
+		return "", fmt.Errorf("optional value is not set")
+	}
+	v, err := o.inner()
+	if err != nil {
+		return "", err
+	}
+	return (&Flag{Value: v}).Canonical()
+}
+
+func (o *Optional[T]) Type() string {
+	v, err := o.inner()
+	if err != nil {
+		return ""
+	}
+	if typed, ok := v.(TypedValue); ok {
+		return typed.Type()
+	}
+	return ""
+}
+
+// IsSet returns true if the flag was provided.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// Get returns the value, and if the flag was provided.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.set
+}
@@ -0,0 +1,40 @@
+package ask
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type OptionalCmd struct {
+	Port    Optional[uint16]        `ask:"--port"`
+	Timeout Optional[time.Duration] `ask:"--timeout"`
+	Name    Optional[string]        `ask:"--name"`
+}
+
+func (c *OptionalCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestOptional(t *testing.T) {
+	var c OptionalCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("timeout"); pf.Default != "" || pf.Value.(TypedValue).Type() != "duration" {
+		t.Fatalf("unexpected timeout flag: %q", pf.Default)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port=0", "--timeout=5s"); err != nil {
+		t.Fatal(err)
+	}
+	if port, ok := c.Port.Get(); !ok || port != 0 {
+		t.Fatalf("expected explicit zero port, got %d (set: %v)", port, ok)
+	}
+	if !c.Timeout.IsSet() || c.Timeout.Value != 5*time.Second || c.Name.IsSet() {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port=70000"); !IsUsageErr(err) {
+		t.Fatalf("expected range error, got: %v", err)
+	}
+}
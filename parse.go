@@ -4,37 +4,242 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/protolambda/ask/askflag"
+	"github.com/protolambda/ask/askparse"
 )
 
 type ApplyArg func(fl PrefixedFlag, value string) error
 
+// RedactedValue replaces the value of a secret flag in messages.
+const RedactedValue = "***"
+
+// FlagValueErr formats an error of applying the value to the flag.
+// The value of a secret flag is redacted, also from the message of the error itself,
+// including the elements of comma-separated values.
+// A number out of range is returned as RangeError, with the flag name and the valid range.
+func FlagValueErr(fl PrefixedFlag, value string, err error) error {
+	// a number out of range is described with the flag and its valid range
+	var re *askflag.RangeError
+	if errors.As(err, &re) {
+		out := *re
+		out.Flag = "--" + fl.Path
+		if fl.IsArg {
+			out.Flag = fl.Path
+		}
+		if fl.Secret {
+			out.Value = RedactedValue
+		}
+		return &out
+	}
+	if !fl.Secret {
+		return fmt.Errorf("failed to apply flag %s: %q, err: %w", fl.Path, value, err)
+	}
+	msg := err.Error()
+	for _, v := range append([]string{value}, strings.Split(value, ",")...) {
+		if v = strings.TrimSpace(v); v != "" {
+			msg = strings.ReplaceAll(msg, strconv.Quote(v), RedactedValue)
+			msg = strings.ReplaceAll(msg, v, RedactedValue)
+		}
+	}
+	return fmt.Errorf("failed to apply flag %s: %s, err: %w", fl.Path, RedactedValue, &redactedErr{msg: msg, err: err})
+}
+
+// redactedErr is an error with a redacted message, that still matches the original error with errors.Is and errors.As.
+// It does not unwrap to the original error, so rendering the chain of causes does not reveal the value.
+type redactedErr struct {
+	msg string
+	err error
+}
+
+func (e *redactedErr) Error() string {
+	return e.msg
+}
+
+func (e *redactedErr) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func (e *redactedErr) As(target interface{}) bool {
+	return errors.As(e.err, target)
+}
+
+// ParseOptions configures the flag syntax that is accepted, in addition to the default syntax.
+type ParseOptions struct {
+	// ColonValues accepts `--flag:value` in addition to `--flag=value`.
+	ColonValues bool
+	// StrictShorthand rejects values attached to a shorthand flag, like `-p9000`, unless the flag opts in with Attached.
+	// Otherwise a new shorthand may silently change the meaning of what users meant as grouped boolean flags.
+	// The `-p 9000` and `-p=9000` forms are always accepted.
+	StrictShorthand bool
+	// NumberSeparators accepts digit separators in integer and float values, e.g. `1,000,000` or `1_000_000`.
+	// The separators are removed before the value is set. Slices of numbers are not affected, as they are comma-separated.
+	NumberSeparators bool
+	// ResponseFiles expands an `@path` argument into the arguments in the file, see ReadResponseFile.
+	// E.g. for long invocations generated by build systems, that would otherwise exceed the OS argument limits.
+	// Arguments in a response file are not expanded again, and values of flags are never expanded.
+	ResponseFiles bool
+
+	// unknown collects unrecognized long flags instead of failing, see ExecutionOptions.CollectUnknown.
+	unknown func(name string, value string)
+}
+
+// syntax is the syntax of the arguments to tokenize them with.
+func (opts *ParseOptions) syntax() *askparse.Syntax {
+	if opts == nil {
+		return nil
+	}
+	return &askparse.Syntax{ColonValues: opts.ColonValues}
+}
+
+// ReadResponseFile is the same as askparse.ReadResponseFile.
+func ReadResponseFile(path string) ([]string, error) {
+	return askparse.ReadResponseFile(path)
+}
+
+// numberTypes are the types of the flag values that NumberSeparators applies to.
+var numberTypes = map[string]struct{}{
+	"int": {}, "int8": {}, "int16": {}, "int32": {}, "int64": {},
+	"uint": {}, "uint8": {}, "uint16": {}, "uint32": {}, "uint64": {},
+	"float32": {}, "float64": {},
+}
+
+// normalizeNumber removes the digit separators from the value of a number flag, see NumberSeparators.
+// Other values are returned as-is.
+func (opts *ParseOptions) normalizeNumber(fl PrefixedFlag, value string) string {
+	if opts == nil || !opts.NumberSeparators {
+		return value
+	}
+	typed, ok := fl.Value.(TypedValue)
+	if !ok {
+		return value
+	}
+	if _, ok := numberTypes[typed.Type()]; !ok {
+		return value
+	}
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(value) && value[i] >= '0' && value[i] <= '9'
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		// only separators in between digits are removed
+		if c := value[i]; (c == ',' || c == '_') && isDigit(i-1) && isDigit(i+1) {
+			continue
+		}
+		out.WriteByte(value[i])
+	}
+	return out.String()
+}
+
 // ParseArgs parses arguments as flags (long and short format).
 // Not all arguments may be consumed as flags, the remaining arguments are returned.
 // Unrecognized flags result in an error.
 // A HelpErr is returned if a flag like `--help` or `-h` is detected.
 func ParseArgs(sortedShort []PrefixedFlag, sortedLong []PrefixedFlag,
 	args []string, set ApplyArg) (remaining []string, err error) {
+	return (*ParseOptions)(nil).ParseArgs(sortedShort, sortedLong, args, set)
+}
+
+// ParseArgs is the same as the ParseArgs function, but with the syntax of the options. Nil options are the default.
+func (opts *ParseOptions) ParseArgs(sortedShort []PrefixedFlag, sortedLong []PrefixedFlag,
+	args []string, set ApplyArg) (remaining []string, err error) {
+	// arguments are from a response file while more than the tail of the original arguments remain
+	tail := len(args)
 	for len(args) > 0 {
 		s := args[0]
 		args = args[1:]
-		if len(s) == 0 || s[0] != '-' || len(s) == 1 {
+		if opts != nil && opts.ResponseFiles && len(args) < tail && len(s) > 1 && s[0] == '@' {
+			var expanded []string
+			if expanded, err = ReadResponseFile(s[1:]); err != nil {
+				return
+			}
+			tail = len(args)
+			args = append(expanded, args...)
+			continue
+		}
+		var tok askparse.Token
+		if tok, err = opts.syntax().Tokenize(s); err != nil {
+			return
+		}
+		switch tok.Kind {
+		case askparse.Positional:
 			remaining = append(remaining, s)
 			continue
+		case askparse.Terminator: // "--" terminates the flags
+			remaining = append(remaining, args...)
+			return
+		case askparse.Long:
+			args, err = opts.ParseLongArg(sortedLong, s, args, set)
+		case askparse.Short:
+			args, err = opts.ParseShortArg(sortedShort, s, args, set)
 		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Diagnostic is the error of an argument, see ParseArgsTolerant.
+type Diagnostic struct {
+	// Index of the argument in the parsed arguments
+	Index int
+	// Arg is the argument, e.g. "--port=x"
+	Arg string
+	// Err is the error of the argument, like ParseArgs would return
+	Err error
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("argument %d (%s): %v", d.Index, d.Arg, d.Err)
+}
 
-		if s[1] == '-' {
-			if len(s) == 2 { // "--" terminates the flags
-				remaining = append(remaining, args...)
-				break
+// ParseArgsTolerant is the same as ParseArgs, but does not stop at the first error:
+// the error of every invalid argument is returned as diagnostic, and parsing continues with the next argument.
+// E.g. to highlight the invalid arguments in an editor or REPL, and still complete the arguments after them.
+// A flag like `--help` is not a diagnostic, and is skipped.
+func ParseArgsTolerant(sortedShort []PrefixedFlag, sortedLong []PrefixedFlag,
+	args []string, set ApplyArg) (remaining []string, diagnostics []Diagnostic) {
+	return (*ParseOptions)(nil).ParseArgsTolerant(sortedShort, sortedLong, args, set)
+}
+
+// ParseArgsTolerant is the same as the ParseArgsTolerant function, but with the syntax of the options.
+// Nil options are the default.
+func (opts *ParseOptions) ParseArgsTolerant(sortedShort []PrefixedFlag, sortedLong []PrefixedFlag,
+	args []string, set ApplyArg) (remaining []string, diagnostics []Diagnostic) {
+	total := len(args)
+	for len(args) > 0 {
+		index := total - len(args)
+		s := args[0]
+		next := args[1:]
+		tok, err := opts.syntax().Tokenize(s)
+		if err == nil {
+			switch tok.Kind {
+			case askparse.Positional:
+				remaining = append(remaining, s)
+				args = next
+				continue
+			case askparse.Terminator: // "--" terminates the flags
+				remaining = append(remaining, next...)
+				return
+			case askparse.Long:
+				next, err = opts.ParseLongArg(sortedLong, s, next, set)
+			case askparse.Short:
+				next, err = opts.ParseShortArg(sortedShort, s, next, set)
 			}
-			args, err = ParseLongArg(sortedLong, s, args, set)
-		} else {
-			args, err = ParseShortArg(sortedShort, s, args, set)
 		}
 		if err != nil {
-			return
+			if err != HelpErr {
+				diagnostics = append(diagnostics, Diagnostic{Index: index, Arg: s, Err: err})
+			}
+			// syntax errors do not return the next arguments, continue after the invalid argument
+			if next == nil {
+				next = args[1:]
+			}
 		}
+		args = next
 	}
 	return
 }
@@ -45,26 +250,47 @@ func ParseArgs(sortedShort []PrefixedFlag, sortedLong []PrefixedFlag,
 //
 // The sortedFlags slice is ordered from low to high long string.
 func ParseLongArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn ApplyArg) (nextArgs []string, err error) {
+	return (*ParseOptions)(nil).ParseLongArg(sortedFlags, firstArg, args, fn)
+}
+
+// ParseLongArg is the same as the ParseLongArg function, but with the syntax of the options.
+// Nil options are the default.
+func (opts *ParseOptions) ParseLongArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn ApplyArg) (nextArgs []string, err error) {
 	nextArgs = args
 	if len(firstArg) < 2 {
 		return nil, fmt.Errorf("long-format flag to short: %q", firstArg)
 	}
-	name := firstArg[2:]
-	if len(name) == 0 || name[0] == '-' || name[0] == '=' {
+	tok, err := opts.syntax().Tokenize(firstArg)
+	if err != nil {
+		return nil, err
+	}
+	if tok.Kind != askparse.Long {
 		return nil, fmt.Errorf("bad flag syntax: %s", firstArg)
 	}
-
-	split := strings.SplitN(name, "=", 2)
-	name = split[0]
+	name := tok.Name
 
 	flagIndex := sort.Search(len(sortedFlags), func(i int) bool {
 		return sortedFlags[i].Path >= name
 	})
 
 	if flagIndex == len(sortedFlags) || sortedFlags[flagIndex].Path != name {
+		// '--no-flag' sets a boolean flag to false
+		if fl, ok := negatedFlag(sortedFlags, name); ok && !tok.HasValue {
+			if err := fn(fl, "false"); err != nil {
+				return nextArgs, FlagValueErr(fl, "false", err)
+			}
+			return nextArgs, nil
+		}
 		// unrecognized
 		if name == "help" {
 			return nextArgs, HelpErr
+		} else if opts != nil && opts.unknown != nil {
+			value := "true"
+			if tok.HasValue {
+				value = tok.Value
+			}
+			opts.unknown(name, value)
+			return nextArgs, nil
 		} else {
 			return nextArgs, fmt.Errorf("unrecognized flag: %s", name)
 		}
@@ -73,9 +299,9 @@ func ParseLongArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn
 	fl := sortedFlags[flagIndex]
 
 	var value string
-	if len(split) == 2 {
-		// '--flag=arg'
-		value = split[1]
+	if tok.HasValue {
+		// '--flag=arg' or '--flag:arg'
+		value = tok.Value
 	} else if flv, ok := fl.Value.(ImplicitValue); ok {
 		// '--flag' (arg was optional)
 		value = flv.Implicit()
@@ -89,14 +315,29 @@ func ParseLongArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn
 	}
 
 	if err := fn(fl, value); err != nil {
-		return nextArgs, fmt.Errorf("failed to apply flag %s: %q, err: %v", name, value, err)
+		return nextArgs, FlagValueErr(fl, value, err)
 	}
 
 	return nextArgs, nil
 }
 
+// negatedFlag finds the boolean flag that the name negates, e.g. "no-awesome" for "awesome".
+func negatedFlag(sortedFlags []PrefixedFlag, name string) (PrefixedFlag, bool) {
+	if !strings.HasPrefix(name, "no-") {
+		return PrefixedFlag{}, false
+	}
+	name = name[3:]
+	flagIndex := sort.Search(len(sortedFlags), func(i int) bool {
+		return sortedFlags[i].Path >= name
+	})
+	if flagIndex == len(sortedFlags) || sortedFlags[flagIndex].Path != name || !sortedFlags[flagIndex].isNegatable() {
+		return PrefixedFlag{}, false
+	}
+	return sortedFlags[flagIndex], true
+}
+
 // sortedFlags is ordered from low to high shorthand string
-func parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []string, fn ApplyArg) (remainingShorthands string, nextArgs []string, err error) {
+func (opts *ParseOptions) parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []string, fn ApplyArg) (remainingShorthands string, nextArgs []string, err error) {
 	if len(shorthands) == 0 {
 		return "", nil, errors.New("no shorthand flags to parse")
 	}
@@ -106,10 +347,10 @@ func parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []s
 	c := shorthands[0]
 
 	flagIndex := sort.Search(len(sortedFlags), func(i int) bool {
-		return sortedFlags[i].Shorthand < c
+		return sortedFlags[i].Shorthand >= c
 	})
 
-	if flagIndex == len(sortedFlags) {
+	if flagIndex == len(sortedFlags) || sortedFlags[flagIndex].Shorthand != c {
 		switch {
 		case c == 'h':
 			return "", nil, HelpErr
@@ -130,6 +371,9 @@ func parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []s
 		value = flv.Implicit()
 	} else if len(shorthands) > 1 {
 		// '-farg'
+		if opts != nil && opts.StrictShorthand && !fl.Attached {
+			return "", nil, fmt.Errorf("ambiguous shorthand -%s: use -%c %s or -%c=%s", shorthands, c, shorthands[1:], c, shorthands[1:])
+		}
 		value = shorthands[1:]
 		remainingShorthands = ""
 	} else if len(args) > 0 {
@@ -142,7 +386,7 @@ func parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []s
 	}
 
 	if err := fn(fl, value); err != nil {
-		return "", nil, fmt.Errorf("failed to apply flag %s: %v", string(c), value)
+		return "", nextArgs, FlagValueErr(fl, value, err)
 	}
 
 	return remainingShorthands, nextArgs, nil
@@ -154,6 +398,12 @@ func parseSingleShortArg(sortedFlags []PrefixedFlag, shorthands string, args []s
 //
 // The sortedFlags slice is ordered from low to high shorthand string
 func ParseShortArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn ApplyArg) (nextArgs []string, err error) {
+	return (*ParseOptions)(nil).ParseShortArg(sortedFlags, firstArg, args, fn)
+}
+
+// ParseShortArg is the same as the ParseShortArg function, but with the syntax of the options.
+// Nil options are the default.
+func (opts *ParseOptions) ParseShortArg(sortedFlags []PrefixedFlag, firstArg string, args []string, fn ApplyArg) (nextArgs []string, err error) {
 	if len(firstArg) == 0 {
 		return nil, errors.New("no shorthand flags to parse")
 	}
@@ -163,7 +413,7 @@ func ParseShortArg(sortedFlags []PrefixedFlag, firstArg string, args []string, f
 
 	// "shorthands" can be a series of shorthand letters of flags (e.g. "-vvv").
 	for len(shorthands) > 0 {
-		shorthands, nextArgs, err = parseSingleShortArg(sortedFlags, shorthands, args, fn)
+		shorthands, nextArgs, err = opts.parseSingleShortArg(sortedFlags, shorthands, nextArgs, fn)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,430 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SecretCmd struct {
+	Duration      time.Duration   `ask:"--duration" secret:"true"`
+	IP            net.IP          `ask:"--ip" secret:"true"`
+	IPNet         net.IPNet       `ask:"--ipnet" secret:"true"`
+	IPMask        net.IPMask      `ask:"--ipmask" secret:"true"`
+	Uint          uint            `ask:"--uint" secret:"true"`
+	Uint8         uint8           `ask:"--uint8" secret:"true"`
+	Uint16        uint16          `ask:"--uint16" secret:"true"`
+	Uint32        uint32          `ask:"--uint32" secret:"true"`
+	Uint64        uint64          `ask:"--uint64" secret:"true"`
+	Int           int             `ask:"--int" secret:"true"`
+	Int8          int8            `ask:"--int8" secret:"true"`
+	Int16         int16           `ask:"--int16" secret:"true"`
+	Int32         int32           `ask:"--int32" secret:"true"`
+	Int64         int64           `ask:"--int64" secret:"true"`
+	Bool          bool            `ask:"--bool" secret:"true"`
+	Float32       float32         `ask:"--float32" secret:"true"`
+	Float64       float64         `ask:"--float64" secret:"true"`
+	DurationSlice []time.Duration `ask:"--duration-slice" secret:"true"`
+	IPSlice       []net.IP        `ask:"--ip-slice" secret:"true"`
+	UintSlice     []uint          `ask:"--uint-slice" secret:"true"`
+	Uint16Slice   []uint16        `ask:"--uint16-slice" secret:"true"`
+	Uint32Slice   []uint32        `ask:"--uint32-slice" secret:"true"`
+	Uint64Slice   []uint64        `ask:"--uint64-slice" secret:"true"`
+	IntSlice      []int           `ask:"--int-slice" secret:"true"`
+	Int8Slice     []int8          `ask:"--int8-slice" secret:"true"`
+	Int16Slice    []int16         `ask:"--int16-slice" secret:"true"`
+	Int32Slice    []int32         `ask:"--int32-slice" secret:"true"`
+	Int64Slice    []int64         `ask:"--int64-slice" secret:"true"`
+	Float32Slice  []float32       `ask:"--float32-slice" secret:"true"`
+	Float64Slice  []float64       `ask:"--float64-slice" secret:"true"`
+	StringSlice   []string        `ask:"--string-slice" secret:"true"`
+	BoolSlice     []bool          `ask:"--bool-slice" secret:"true"`
+	Bytes         []byte          `ask:"--bytes" secret:"true"`
+	Bytes4        [4]byte         `ask:"--bytes4" secret:"true"`
+	Bytes4Slice   [][4]byte       `ask:"--bytes4-slice" secret:"true"`
+	Arg           uint64          `ask:"[arg]" secret:"true"`
+
+	StringMap map[string]string `ask:"--string-map" secret:"true"`
+}
+
+func (c *SecretCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestSecretValueErr(t *testing.T) {
+	const secret = `hunter2"x`
+	cmd, err := Load(&SecretCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pf := range cmd.All("") {
+		var args []string
+		if pf.IsArg {
+			args = []string{secret}
+		} else {
+			args = []string{"--" + pf.Path + "=" + secret + ",0"}
+		}
+		_, err := cmd.Execute(context.Background(), nil, args...)
+		if err == nil {
+			t.Fatalf("%s: expected error", pf.Path)
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Fatalf("%s: secret value leaked: %v", pf.Path, err)
+		}
+		if !strings.Contains(err.Error(), RedactedValue) {
+			t.Fatalf("%s: expected redacted value: %v", pf.Path, err)
+		}
+		// the causes are redacted too, while the error still matches the original
+		for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+			if strings.Contains(cause.Error(), "hunter2") {
+				t.Fatalf("%s: secret value leaked in cause: %v", pf.Path, cause)
+			}
+		}
+	}
+	_, err = cmd.Execute(context.Background(), nil, "--int="+secret)
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected the original error to match, got: %v", err)
+	}
+}
+
+type ColonCmd struct {
+	Addr string `ask:"--addr"`
+	Port uint16 `ask:"--port"`
+}
+
+func (c *ColonCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestColonValues(t *testing.T) {
+	var c ColonCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port:9000"); err == nil {
+		t.Fatal("expected colon syntax to be rejected by default")
+	}
+	opts := &ExecutionOptions{ParseOptions: ParseOptions{ColonValues: true}}
+	if _, err := cmd.Execute(context.Background(), opts, "--addr:http://localhost:8080/?a=b", "--port=9000"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != "http://localhost:8080/?a=b" || c.Port != 9000 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+}
+
+type ShortCmd struct {
+	Port    uint16 `ask:"--port -p"`
+	Level   uint8  `ask:"--level -l" attached:"true"`
+	Verbose bool   `ask:"--verbose -v"`
+	Quiet   bool   `ask:"--quiet -q"`
+}
+
+func (c *ShortCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestShorthand(t *testing.T) {
+	var c ShortCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "-vqp9000", "-l", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Verbose || !c.Quiet || c.Port != 9000 || c.Level != 3 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	strict := &ExecutionOptions{ParseOptions: ParseOptions{StrictShorthand: true}}
+	if _, err := cmd.Execute(context.Background(), strict, "-vp9001"); err == nil || !strings.Contains(err.Error(), "ambiguous shorthand -p9001") {
+		t.Fatalf("expected ambiguous shorthand error, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), strict, "-vp", "9001", "-l4"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 9001 || c.Level != 4 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+}
+
+type CountCmd struct {
+	Verbose int        `ask:"--verbose -v" format:"count"`
+	Quiet   CountValue `ask:"--quiet -q"`
+	Port    uint16     `ask:"--port -p"`
+}
+
+func (c *CountCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestCountValue(t *testing.T) {
+	var c CountCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final, err := cmd.Execute(context.Background(), nil, "-vvv", "arg", "--verbose", "-qvp", "9000", "-q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Verbose != 5 || c.Quiet != 2 || c.Port != 9000 || strings.Join(final.Remaining, " ") != "arg" {
+		t.Fatalf("unexpected values: %+v, remaining: %q", c, final.Remaining)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--verbose=1", "-v"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Verbose != 2 {
+		t.Fatalf("expected explicit count to be incremented, got %d", c.Verbose)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--verbose=-1"); !IsUsageErr(err) {
+		t.Fatalf("expected negative count error, got: %v", err)
+	}
+	if _, err := Load(&struct {
+		V uint8 `ask:"-v" format:"count"`
+	}{}); err == nil {
+		t.Fatal("expected error for count on non-int field")
+	}
+}
+
+type NegateCmd struct {
+	Awesome bool     `ask:"--awesome"`
+	Color   TriState `ask:"--color"`
+	NoCache bool     `ask:"--no-cache"`
+	Cache   bool     `ask:"--cache"`
+	Name    string   `ask:"--name"`
+	Tracing struct {
+		Enabled bool `ask:"--enabled"`
+	} `ask:".tracing"`
+}
+
+func (c *NegateCmd) Default() {
+	c.Awesome = true
+	c.Tracing.Enabled = true
+}
+
+func (c *NegateCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestNegatedBool(t *testing.T) {
+	var c NegateCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := cmd.Usage(false)
+	if !strings.Contains(usage, "(default: true) (disable with --no-awesome)") ||
+		!strings.Contains(usage, "(disable with --no-tracing.enabled)") || strings.Contains(usage, "--no-name") {
+		t.Fatalf("expected negated forms in usage, got: %s", usage)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--no-awesome", "--no-color", "--no-tracing.enabled", "--cache", "--no-cache"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Awesome || c.Color != TriStateFalse || c.Tracing.Enabled || !c.Cache || !c.NoCache {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	for _, args := range [][]string{{"--no-name"}, {"--no-awesome=true"}, {"--no-unknown"}} {
+		if _, err := cmd.Execute(context.Background(), nil, args...); err == nil || !strings.Contains(err.Error(), "unrecognized flag") {
+			t.Fatalf("%q: expected unrecognized flag, got: %v", args, err)
+		}
+	}
+}
+
+// levelByte is a custom flag value that wraps the RangeError of its parser.
+type levelByte uint8
+
+func (l *levelByte) Set(s string) error {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("invalid level: %w", &RangeError{Value: s, Type: "level", Min: "0", Max: "255"})
+	}
+	*l = levelByte(v)
+	return err
+}
+
+func (l *levelByte) String() string {
+	return strconv.FormatUint(uint64(*l), 10)
+}
+
+func (l *levelByte) Type() string {
+	return "level"
+}
+
+type RangeCmd struct {
+	Port   uint16    `ask:"--port"`
+	Offset int8      `ask:"--offset"`
+	Ratios []float32 `ask:"--ratios"`
+	Level  levelByte `ask:"--level"`
+	Key    uint8     `ask:"<key>" secret:"true"`
+}
+
+func (c *RangeCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestRangeErr(t *testing.T) {
+	c := RangeCmd{Port: 8080}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{"--port=70000"}, "value 70000 out of range for uint16 flag --port [0..65535]"},
+		{[]string{"--offset=-129"}, "value -129 out of range for int8 flag --offset [-128..127]"},
+		{[]string{"--ratios=1,1e39"}, "value 1e39 out of range for float32 flag --ratios [-3.4028235e+38..3.4028235e+38]"},
+		{[]string{"300"}, "value *** out of range for uint8 flag key [0..255]"},
+		{[]string{"--level=999"}, "value 999 out of range for level flag --level [0..255]"},
+	} {
+		_, err := cmd.Execute(context.Background(), nil, tc.args...)
+		var re *RangeError
+		if !IsUsageErr(err) || !errors.As(err, &re) || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%v: expected range error %q, got: %v", tc.args, tc.msg, err)
+		}
+	}
+	if c.Port != 8080 {
+		t.Fatalf("value out of range must not be set, got %d", c.Port)
+	}
+}
+
+type NumberCmd struct {
+	GasLimit uint64  `ask:"--gas-limit"`
+	Price    float64 `ask:"--price"`
+	Name     string  `ask:"--name"`
+	Slot     int     `ask:"<slot>"`
+	Slots    []int   `ask:"--slots"`
+}
+
+func (c *NumberCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestNumberSeparators(t *testing.T) {
+	var c NumberCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--gas-limit=30,000,000", "--price=1,234.5", "--name=a,b", "--slots=1,000", "8_000_000"}
+	if _, err := cmd.Execute(context.Background(), nil, args...); !IsUsageErr(err) {
+		t.Fatalf("expected separators to be rejected by default, got: %v", err)
+	}
+	opts := &ExecutionOptions{ParseOptions: ParseOptions{NumberSeparators: true}}
+	if _, err := cmd.Execute(context.Background(), opts, args...); err != nil {
+		t.Fatal(err)
+	}
+	if c.GasLimit != 30_000_000 || c.Price != 1234.5 || c.Name != "a,b" || c.Slot != 8_000_000 || len(c.Slots) != 2 {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+}
+
+func TestParseArgsTolerant(t *testing.T) {
+	var c ShortCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, long, _, _ := cmd.sortedFlags()
+	set := func(fl PrefixedFlag, value string) error {
+		return fl.Set(value)
+	}
+	args := []string{"--port=x", "a", "--unknown", "--help", "-p", "y", "b", "--port", "9000", "-", "---bad", "--", "--port=z"}
+	remaining, diags := ParseArgsTolerant(short, long, args, set)
+	if strings.Join(remaining, " ") != "a b - --port=z" {
+		t.Fatalf("unexpected remaining: %q", remaining)
+	}
+	var indices []int
+	for _, d := range diags {
+		indices = append(indices, d.Index)
+	}
+	if fmt.Sprint(indices) != "[0 2 4 10]" {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !strings.Contains(diags[1].Error(), "argument 2 (--unknown): unrecognized flag: unknown") {
+		t.Fatalf("unexpected diagnostic: %v", diags[1])
+	}
+	if c.Port != 9000 {
+		t.Fatalf("expected valid flags to be applied, got %d", c.Port)
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "args.txt")
+	if err := os.WriteFile(path, []byte("# generated\n--port\n9000\r\n\n  -v  \n@nested\na b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var c ShortCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "@"+path); err != nil || c.Port != 0 {
+		t.Fatalf("expected response files to be disabled by default, got: %v, %d", err, c.Port)
+	}
+	execOpts := &ExecutionOptions{ParseOptions: ParseOptions{ResponseFiles: true}}
+	if sub, err := cmd.Execute(context.Background(), execOpts, "@"+path); err != nil || c.Port != 9000 {
+		t.Fatalf("expected response file to be expanded, got: %v, %d", err, c.Port)
+	} else if strings.Join(sub.Remaining, "|") != "@nested|a b" {
+		t.Fatalf("unexpected remaining: %q", sub.Remaining)
+	}
+	c = ShortCmd{}
+	short, long, _, _ := cmd.sortedFlags()
+	set := func(fl PrefixedFlag, value string) error {
+		return fl.Set(value)
+	}
+	opts := &ParseOptions{ResponseFiles: true}
+	remaining, err := opts.ParseArgs(short, long, []string{"-q", "@" + path, "x", "--", "@" + path}, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(remaining, "|") != "@nested|a b|x|@"+path {
+		t.Fatalf("unexpected remaining: %q", remaining)
+	}
+	if c.Port != 9000 || !c.Verbose || !c.Quiet {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if _, err := opts.ParseArgs(short, long, []string{"@" + filepath.Join(dir, "missing")}, set); err == nil {
+		t.Fatal("expected missing response file to fail")
+	}
+}
+
+func TestCollectUnknown(t *testing.T) {
+	var c ShortCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--http.port=8545", "-p", "9000", "--syncmode=snap", "--mainnet", "x", "--syncmode=full"}
+	if _, err := cmd.Execute(context.Background(), nil, args...); !IsUsageErr(err) {
+		t.Fatalf("expected unknown flags to be rejected by default, got: %v", err)
+	}
+	final, err := cmd.Execute(context.Background(), &ExecutionOptions{CollectUnknown: true}, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(final.Unknown) != "map[http.port:8545 mainnet:true syncmode:full]" {
+		t.Fatalf("unexpected unknown flags: %v", final.Unknown)
+	}
+	if c.Port != 9000 || strings.Join(final.Remaining, " ") != "x" {
+		t.Fatalf("unexpected values: %+v, remaining: %q", c, final.Remaining)
+	}
+	if _, err := cmd.Execute(context.Background(), &ExecutionOptions{CollectUnknown: true}, "-z"); !IsUsageErr(err) {
+		t.Fatalf("expected unknown shorthand to be rejected, got: %v", err)
+	}
+	if _, err := cmd.Execute(context.Background(), &ExecutionOptions{CollectUnknown: true}, "--help"); err != HelpErr {
+		t.Fatalf("expected help, got: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package ask
+
+import (
+	"sort"
+	"strings"
+)
+
+// ParserState is what a Parser expects as next argument.
+type ParserState uint8
+
+const (
+	// ExpectAny is the state when the next argument may be a flag or a positional argument.
+	ExpectAny ParserState = iota
+	// ExpectValue is the state when the previous flag needs a value, see Parser.Pending.
+	ExpectValue
+	// ExpectPositional is the state after the "--" terminator: all next arguments are positional.
+	ExpectPositional
+)
+
+// Parser consumes arguments one at a time, like ParseArgs, to query the state of the parsing in between,
+// e.g. for completion in an editor or REPL.
+type Parser struct {
+	opts  *ParseOptions
+	short []PrefixedFlag
+	long  []PrefixedFlag
+	set   ApplyArg
+	// flag argument that is waiting for its value
+	pending string
+	// flag of the pending argument
+	pendingFlag PrefixedFlag
+	terminated  bool
+	remaining   []string
+	seen        []string
+}
+
+// Parser starts an incremental parser of the arguments of the command, with the syntax of the options (may be nil).
+// The values are applied with set, if not nil. With a nil set the arguments are only tokenized and matched to flags.
+func (descr *CommandDescription) Parser(opts *ParseOptions, set ApplyArg) *Parser {
+	short, long, _, _ := descr.sortedFlags()
+	return NewParser(opts, short, long, set)
+}
+
+// NewParser starts an incremental parser of arguments, like ParseArgs does all at once.
+// See CommandDescription.Parser to parse the arguments of a command.
+func NewParser(opts *ParseOptions, sortedShort []PrefixedFlag, sortedLong []PrefixedFlag, set ApplyArg) *Parser {
+	return &Parser{opts: opts, short: sortedShort, long: sortedLong, set: set}
+}
+
+// Feed consumes the next argument. Errors are the same as those of ParseArgs, e.g. an unrecognized flag.
+// The argument is not consumed if there is an error.
+func (p *Parser) Feed(arg string) error {
+	if p.terminated {
+		p.remaining = append(p.remaining, arg)
+		return nil
+	}
+	if p.pending != "" {
+		return p.parse(p.pending, arg)
+	}
+	if arg == "--" {
+		p.terminated = true
+		return nil
+	}
+	if fl, ok := p.needsValue(arg); ok {
+		p.pending, p.pendingFlag = arg, fl
+		return nil
+	}
+	return p.parse(arg)
+}
+
+func (p *Parser) parse(args ...string) error {
+	set := func(fl PrefixedFlag, value string) error {
+		if p.set != nil {
+			if err := p.set(fl, value); err != nil {
+				return err
+			}
+		}
+		p.seen = append(p.seen, fl.Path)
+		return nil
+	}
+	remaining, err := p.opts.ParseArgs(p.short, p.long, args, set)
+	if err != nil {
+		return err
+	}
+	p.pending, p.pendingFlag = "", PrefixedFlag{}
+	p.remaining = append(p.remaining, remaining...)
+	return nil
+}
+
+// needsValue checks if the argument is a flag that takes the next argument as value.
+func (p *Parser) needsValue(arg string) (PrefixedFlag, bool) {
+	if len(arg) < 2 || arg[0] != '-' {
+		return PrefixedFlag{}, false
+	}
+	if arg[1] == '-' {
+		if strings.Contains(arg, "=") || (p.opts != nil && p.opts.ColonValues && strings.Contains(arg, ":")) {
+			return PrefixedFlag{}, false
+		}
+		i := sort.Search(len(p.long), func(i int) bool { return p.long[i].Path >= arg[2:] })
+		if i == len(p.long) || p.long[i].Path != arg[2:] {
+			return PrefixedFlag{}, false
+		}
+		_, implicit := p.long[i].Value.(ImplicitValue)
+		return p.long[i], !implicit
+	}
+	// only the last of the shorthands takes the next argument, if the others are implicit
+	for j := 1; j < len(arg); j++ {
+		c := arg[j]
+		i := sort.Search(len(p.short), func(i int) bool { return p.short[i].Shorthand >= c })
+		if i == len(p.short) || p.short[i].Shorthand != c {
+			return PrefixedFlag{}, false
+		}
+		if _, implicit := p.short[i].Value.(ImplicitValue); !implicit {
+			return p.short[i], j == len(arg)-1
+		}
+	}
+	return PrefixedFlag{}, false
+}
+
+// State returns what the parser expects as next argument.
+func (p *Parser) State() ParserState {
+	if p.terminated {
+		return ExpectPositional
+	}
+	if p.pending != "" {
+		return ExpectValue
+	}
+	return ExpectAny
+}
+
+// Pending returns the flag that is waiting for its value, if the state is ExpectValue.
+func (p *Parser) Pending() (PrefixedFlag, bool) {
+	return p.pendingFlag, p.pending != ""
+}
+
+// Seen returns the paths of the flags that were parsed so far, in order. Flags may be repeated.
+func (p *Parser) Seen() []string {
+	return p.seen
+}
+
+// Remaining returns the arguments that were not consumed as flags so far, i.e. positional arguments.
+func (p *Parser) Remaining() []string {
+	return p.remaining
+}
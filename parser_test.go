@@ -0,0 +1,62 @@
+package ask
+
+import (
+	"strings"
+	"testing"
+)
+
+type ParserCmd struct {
+	Verbose bool   `ask:"--verbose -v"`
+	Port    uint16 `ask:"--port -p"`
+	Peer    string `ask:"--peer"`
+	Mode    string `enum:"fast,slow"`
+	Secret  string `ask:"--secret" hidden:"true"`
+}
+
+func TestParser(t *testing.T) {
+	var c ParserCmd
+	cmd, err := LoadWithOptions(&c, &LoadOptions{Kong: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cmd.Parser(nil, func(fl PrefixedFlag, value string) error {
+		return fl.Set(value)
+	})
+	for _, arg := range []string{"-vp", "9000", "--mode"} {
+		if err := p.Feed(arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.State() != ExpectValue {
+		t.Fatalf("expected value, got state %d", p.State())
+	}
+	if fl, ok := p.Pending(); !ok || fl.Path != "mode" {
+		t.Fatalf("unexpected pending flag: %v", fl.Path)
+	}
+	if err := p.Feed("medium"); err == nil {
+		t.Fatal("expected invalid enum value")
+	}
+	if p.State() != ExpectValue {
+		t.Fatal("expected invalid value not to be consumed")
+	}
+	for _, arg := range []string{"fast", "foo", "--", "--peer"} {
+		if err := p.Feed(arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.State() != ExpectPositional {
+		t.Fatalf("expected positional args, got state %d", p.State())
+	}
+	if got := strings.Join(p.Remaining(), " "); got != "foo --peer" {
+		t.Fatalf("unexpected remaining args: %s", got)
+	}
+	if got := strings.Join(p.Seen(), " "); got != "verbose port mode" {
+		t.Fatalf("unexpected seen flags: %s", got)
+	}
+	if !c.Verbose || c.Port != 9000 || c.Mode != "fast" || c.Peer != "" {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if err := cmd.Parser(nil, nil).Feed("--unknown"); err == nil {
+		t.Fatal("expected unrecognized flag")
+	}
+}
@@ -0,0 +1,112 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// PathFlags are the filesystem layout flags of an application, to embed in commands with `ask:"."`,
+// so every command of the application accepts the same --datadir, --cache-dir and --tmp-dir flags.
+// The defaults are the platform directories for user data, cache and temporary files, in a sub-directory named after App.
+type PathFlags struct {
+	DataDir  string `ask:"--datadir" help:"Directory to store persistent data in"`
+	CacheDir string `ask:"--cache-dir" help:"Directory to cache data in, that may be removed"`
+	TmpDir   string `ask:"--tmp-dir" help:"Directory for temporary files"`
+
+	// App names the sub-directories of the default paths. Defaults to the name of the executable.
+	App string `ask:"-"`
+	// Create makes Setup create the directories that do not exist yet.
+	Create bool `ask:"-"`
+	// DirMode is the permission of created directories. Defaults to 0700.
+	DirMode os.FileMode `ask:"-"`
+}
+
+// Default sets the platform defaults of the paths that are not set yet.
+// Paths are left empty if the platform directory cannot be determined, e.g. without a home directory.
+func (p *PathFlags) Default() {
+	app := p.App
+	if app == "" {
+		app = filepath.Base(os.Args[0])
+	}
+	if p.DataDir == "" {
+		if dir, err := userDataDir(); err == nil {
+			p.DataDir = filepath.Join(dir, app)
+		}
+	}
+	if p.CacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			p.CacheDir = filepath.Join(dir, app)
+		}
+	}
+	if p.TmpDir == "" {
+		p.TmpDir = filepath.Join(os.TempDir(), app)
+	}
+}
+
+// userDataDir returns the platform directory for persistent user data:
+// $XDG_DATA_HOME or ~/.local/share on Unix, ~/Library/Application Support on macOS, and %LocalAppData% on Windows.
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return "", errors.New("%LocalAppData% is not defined")
+	case "darwin", "ios":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Application Support"), nil
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+			return dir, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share"), nil
+	}
+}
+
+// MkdirAll creates the directories that do not exist yet, with DirMode. Empty paths are skipped.
+func (p *PathFlags) MkdirAll() error {
+	mode := p.DirMode
+	if mode == 0 {
+		mode = 0o700
+	}
+	for _, dir := range []string{p.DataDir, p.CacheDir, p.TmpDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirTemp creates a new temporary directory in TmpDir, see os.MkdirTemp.
+func (p *PathFlags) MkdirTemp(pattern string) (string, error) {
+	return os.MkdirTemp(p.TmpDir, pattern)
+}
+
+// Setup creates the directories if Create is enabled, and returns a copy of the context with the paths,
+// to retrieve in sub-routines with PathsFrom. Call it at the start of Run.
+func (p *PathFlags) Setup(ctx context.Context) (context.Context, error) {
+	if p.Create {
+		if err := p.MkdirAll(); err != nil {
+			return ctx, err
+		}
+	}
+	return Inject(ctx, p), nil
+}
+
+// PathsFrom retrieves the paths that were added to the context with PathFlags.Setup.
+func PathsFrom(ctx context.Context) (*PathFlags, bool) {
+	return From[*PathFlags](ctx)
+}
@@ -0,0 +1,66 @@
+package ask
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type PathsCmd struct {
+	PathFlags `ask:"."`
+	Ran       bool
+}
+
+func (c *PathsCmd) Run(ctx context.Context, args ...string) error {
+	ctx, err := c.Setup(ctx)
+	if err != nil {
+		return err
+	}
+	paths, ok := PathsFrom(ctx)
+	if !ok || paths != &c.PathFlags {
+		return os.ErrNotExist
+	}
+	c.Ran = true
+	return nil
+}
+
+func TestPathFlags(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	c := PathsCmd{PathFlags: PathFlags{App: "myapp", CacheDir: "/custom/cache"}}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CacheDir != "/custom/cache" || c.TmpDir != filepath.Join(os.TempDir(), "myapp") {
+		t.Fatalf("unexpected defaults: %+v", c.PathFlags)
+	}
+	if d, _ := userDataDir(); c.DataDir != filepath.Join(d, "myapp") {
+		t.Fatalf("unexpected data dir: %s", c.DataDir)
+	}
+	for _, name := range []string{"datadir", "cache-dir", "tmp-dir"} {
+		if _, ok := cmd.Lookup(name); !ok {
+			t.Fatalf("missing flag %s", name)
+		}
+	}
+
+	root := t.TempDir()
+	c.Create = true
+	if _, err := cmd.Execute(context.Background(), nil, "--datadir="+filepath.Join(root, "data"),
+		"--cache-dir="+filepath.Join(root, "cache"), "--tmp-dir="+filepath.Join(root, "tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Ran {
+		t.Fatal("expected paths in context")
+	}
+	for _, dir := range []string{"data", "cache", "tmp"} {
+		if info, err := os.Stat(filepath.Join(root, dir)); err != nil || !info.IsDir() || info.Mode().Perm() != 0o700 {
+			t.Fatalf("expected %s directory: %v", dir, err)
+		}
+	}
+	tmp, err := c.MkdirTemp("job-*")
+	if err != nil || !strings.HasPrefix(tmp, filepath.Join(root, "tmp", "job-")) {
+		t.Fatalf("unexpected temp dir %q: %v", tmp, err)
+	}
+}
@@ -0,0 +1,187 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExecutionRecord describes an execution of a command, see ExecutionOptions.Record.
+type ExecutionRecord struct {
+	Time time.Time `json:"time"`
+	// Args of the execution. The values of secret flags are replaced with RedactedValue.
+	Args []string `json:"args"`
+	// Route to the command that executed, see CommandDescription.Route.
+	Route []string `json:"route,omitempty"`
+	// Duration of the execution, including running the command.
+	Duration time.Duration `json:"duration"`
+	// Error message of the execution. Empty if successful.
+	Error string `json:"error,omitempty"`
+}
+
+// recordExecution writes the record of an execution as line of JSON.
+// The arguments of an execution that failed to route are redacted with the flags of the root command.
+func recordExecution(w io.Writer, opts *ParseOptions, start time.Time, root, final *CommandDescription, args []string, err error) error {
+	rec := ExecutionRecord{Time: start, Duration: time.Since(start)}
+	if final != nil {
+		rec.Args = final.redactArgs(opts, args)
+		rec.Route = final.Route
+	} else {
+		rec.Args = root.redactArgs(opts, args)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to record execution: %w", err)
+	}
+	return nil
+}
+
+// redactArgs replaces the values of secret flags and positional args in the arguments.
+// The arguments are tokenized like Execute does, so secrets are redacted by flag, in any syntax:
+// attached to a shorthand (`-kVALUE`), after combined shorthands (`-vk VALUE`), or as positional arg.
+// The arguments before each route are tokenized with the flags of the command that routes,
+// and the early flags of the commands before it are recognized after the route too, like Execute does.
+// Arguments that cannot be parsed are kept as-is, and the arguments of a RawArgs command are not parsed.
+func (descr *CommandDescription) redactArgs(opts *ParseOptions, args []string) []string {
+	var chain []*CommandDescription
+	for cmd := descr; cmd != nil; cmd = cmd.parent {
+		chain = append([]*CommandDescription{cmd}, chain...)
+	}
+	type applied struct {
+		fl    PrefixedFlag
+		value string
+	}
+	var calls []applied
+	record := func(fl PrefixedFlag, value string) error {
+		calls = append(calls, applied{fl, value})
+		return nil
+	}
+	// the early flags of the commands that routed to the current command
+	var early []PrefixedFlag
+	level := 0
+	short, long, positionalRequired, positionalOptional := chain[0].sortedFlags()
+	out := append([]string(nil), args...)
+	// indices of the positional arguments of the command
+	var positional []int
+	for i := 0; i < len(out); i++ {
+		if _, raw := chain[level].Command.(RawArgs); raw {
+			break
+		}
+		a := out[i]
+		if a == "--" {
+			for j := i + 1; j < len(out); j++ {
+				positional = append(positional, j)
+			}
+			break
+		}
+		if len(a) < 2 || a[0] != '-' {
+			if level+1 < len(chain) {
+				// the route to the next command
+				for _, pf := range chain[level].All("") {
+					if pf.Early && !pf.IsArg {
+						early = append(early, pf)
+					}
+				}
+				sort.Slice(early, func(i, j int) bool {
+					return early[i].Path < early[j].Path
+				})
+				level++
+				short, long, positionalRequired, positionalOptional = chain[level].sortedFlags()
+				continue
+			}
+			positional = append(positional, i)
+			continue
+		}
+		calls = calls[:0]
+		rest := out[i+1:]
+		var next []string
+		var err error
+		if a[1] == '-' {
+			next, err = opts.ParseLongArg(long, a, rest, record)
+			if err != nil && len(early) > 0 {
+				calls = calls[:0]
+				next, err = opts.ParseLongArg(early, a, rest, record)
+			}
+		} else {
+			next, err = opts.ParseShortArg(short, a, rest, record)
+		}
+		if err != nil {
+			continue
+		}
+		consumedNext := len(next) < len(rest)
+		for k, c := range calls {
+			if !c.fl.Secret {
+				continue
+			}
+			if consumedNext && k == len(calls)-1 && c.value == rest[0] {
+				out[i+1] = RedactedValue
+			} else if c.value != "" && strings.HasSuffix(out[i], c.value) {
+				out[i] = out[i][:len(out[i])-len(c.value)] + RedactedValue
+			}
+		}
+		if consumedNext {
+			i++
+		}
+	}
+	for k, pf := range append(positionalRequired, positionalOptional...) {
+		if k >= len(positional) {
+			break
+		}
+		if pf.Secret {
+			out[positional[k]] = RedactedValue
+		}
+	}
+	return out
+}
+
+// Replay executes the recorded executions again, each with a new command, to reproduce a session.
+// An error is returned for every execution with a different outcome than recorded.
+// Executions with redacted secrets cannot be reproduced faithfully.
+func Replay(ctx context.Context, log io.Reader, newCmd func() interface{}, opts *ExecutionOptions) error {
+	var o ExecutionOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.Record = nil
+	var errs []error
+	scanner := bufio.NewScanner(log)
+	for i := 1; scanner.Scan(); i++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec ExecutionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("failed to decode record %d: %w", i, err)
+		}
+		descr, err := Load(newCmd())
+		if err != nil {
+			return err
+		}
+		got := ""
+		if _, err := descr.Execute(ctx, &o, rec.Args...); err != nil {
+			got = err.Error()
+		}
+		if got != rec.Error {
+			errs = append(errs, fmt.Errorf("record %d (%s): recorded error %q, got %q",
+				i, strings.Join(rec.Args, " "), rec.Error, got))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,154 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type RecordCmd struct {
+	Name  string `ask:"--name"`
+	Token string `ask:"--token -t" secret:"true"`
+	Fail  bool   `ask:"--fail"`
+}
+
+func (c *RecordCmd) Run(ctx context.Context, args ...string) error {
+	if c.Fail {
+		return errors.New("failed on purpose")
+	}
+	return nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	var log strings.Builder
+	opts := &ExecutionOptions{Record: &log}
+	for _, args := range [][]string{
+		{"--name=foo", "--token=abc"},
+		{"-t", "abc", "--fail"},
+		{"--unknown"},
+	} {
+		cmd, err := Load(&RecordCmd{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = cmd.Execute(context.Background(), opts, args...)
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got: %s", log.String())
+	}
+	if strings.Contains(log.String(), "abc") {
+		t.Fatalf("secret was recorded: %s", log.String())
+	}
+	if !strings.Contains(lines[0], `"args":["--name=foo","--token=***"]`) ||
+		!strings.Contains(lines[1], `"args":["-t","***","--fail"]`) ||
+		!strings.Contains(lines[1], `"error":"failed on purpose"`) {
+		t.Fatalf("unexpected records: %s", log.String())
+	}
+
+	if err := Replay(context.Background(), strings.NewReader(log.String()), func() interface{} { return &RecordCmd{} }, opts); err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(log.String(), "failed on purpose", "other", 1)
+	err := Replay(context.Background(), strings.NewReader(changed), func() interface{} { return &RecordCmd{} }, nil)
+	if err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Fatalf("expected different outcome, got: %v", err)
+	}
+}
+
+type RedactCmd struct {
+	Verbose bool   `ask:"--verbose -v"`
+	Key     string `ask:"--key -k" secret:"true"`
+	Name    string `ask:"--name -n"`
+	Seed    string `ask:"<seed>" secret:"true"`
+	Label   string `ask:"[label]"`
+}
+
+func (c *RedactCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestRedactArgs(t *testing.T) {
+	cmd, err := Load(&RedactCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-khunter2", "s33d", "public"}, "-k*** *** public"},
+		{[]string{"-vk", "hunter2", "s33d", "public"}, "-vk *** *** public"},
+		{[]string{"-vkhunter2", "s33d", "public"}, "-vk*** *** public"},
+		{[]string{"--key", "hunter2", "-n", "bob", "s33d"}, "--key *** -n bob ***"},
+		{[]string{"--key=hunter2", "s33d", "label"}, "--key=*** *** label"},
+		{[]string{"-n", "hunter2", "--", "s33d"}, "-n hunter2 -- ***"},
+		{[]string{"--unknown", "s33d"}, "--unknown ***"},
+	} {
+		if got := strings.Join(cmd.redactArgs(nil, tc.args), " "); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+}
+
+type RedactRoot struct {
+	Token  string `ask:"--token" secret:"true" early:"true"`
+	Secret string `ask:"--secret" secret:"true"`
+}
+
+func (c *RedactRoot) Cmd(route string) (cmd interface{}, err error) {
+	if route == "sub" {
+		return &RedactCmd{}, nil
+	}
+	return nil, UnrecognizedErr
+}
+
+func TestRedactArgsRoute(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--token", "hunter2", "sub", "-k", "hunter3", "s33d"}, `["--token","***","sub","-k","***","***"]`},
+		{[]string{"sub", "--token=hunter2", "-n", "bob", "s33d"}, `["sub","--token=***","-n","bob","***"]`},
+		{[]string{"--token", "hunter2", "unknown"}, `["--token","***","unknown"]`},
+		{[]string{"--secret", "hunter2", "sub"}, `["--secret","***","sub"]`},
+	} {
+		var log strings.Builder
+		cmd, err := Load(&RedactRoot{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = cmd.Execute(context.Background(), &ExecutionOptions{Record: &log}, tc.args...)
+		if !strings.Contains(log.String(), `"args":`+tc.expected) {
+			t.Errorf("%q: expected args %s, got: %s", tc.args, tc.expected, log.String())
+		}
+	}
+}
+
+func TestExecuteAllRecord(t *testing.T) {
+	var sum int64
+	cmd, err := Load(&BatchRoute{Sum: &sum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	invocations := make([][]string, 20)
+	for i := range invocations {
+		invocations[i] = []string{"add", "1"}
+	}
+	if err := cmd.ExecuteAll(context.Background(), invocations, &BatchOptions{Workers: 4, ExecutionOptions: ExecutionOptions{Record: &log}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != len(invocations) {
+		t.Fatalf("expected a record per invocation, got: %s", log.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+			t.Fatalf("unexpected record: %s", line)
+		}
+	}
+}
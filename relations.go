@@ -0,0 +1,207 @@
+package ask
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlagRelation is a kind of relation between two flags.
+type FlagRelation string
+
+const (
+	// RelationRequires is declared with `requires:"a,b"`: if the flag is set, the other flags must be set too.
+	RelationRequires FlagRelation = "requires"
+	// RelationConflicts is declared with `conflicts:"a,b"`: if the flag is set, the other flags must not be set.
+	RelationConflicts FlagRelation = "conflicts"
+	// RelationDefaultFrom is declared with `default-from:"a"`: if the flag is not set, it takes the value of the other flag.
+	RelationDefaultFrom FlagRelation = "default-from"
+	// RelationOnlyIf is declared with `onlyif:"a"`: the flag can only be set if the other boolean flag is true.
+	// Flags in a group with a toggle (see FlagGroup.Toggle) have this relation with the toggle flag.
+	RelationOnlyIf FlagRelation = "onlyif"
+)
+
+// FlagEdge is a relation from one flag to another, by flag path.
+type FlagEdge struct {
+	From     string
+	To       string
+	Relation FlagRelation
+}
+
+// FlagGraph describes the relations between the flags of a command.
+type FlagGraph struct {
+	// Nodes are the flag paths, in order of All.
+	Nodes []string
+	// Edges are the relations between flags, in order of the flag they are from.
+	Edges []FlagEdge
+}
+
+// relatedPath resolves the name of a related flag, declared relative to the group of the flag, to a full path.
+func relatedPath(pf PrefixedFlag, name string) string {
+	return strings.TrimSuffix(pf.Path, pf.Name) + name
+}
+
+// relations lists the relations declared by the flag, including the toggle of its group.
+func (pf PrefixedFlag) relations(toggle string) []FlagEdge {
+	var out []FlagEdge
+	for _, r := range pf.Requires {
+		out = append(out, FlagEdge{From: pf.Path, To: relatedPath(pf, r), Relation: RelationRequires})
+	}
+	for _, c := range pf.Conflicts {
+		out = append(out, FlagEdge{From: pf.Path, To: relatedPath(pf, c), Relation: RelationConflicts})
+	}
+	if pf.DefaultFrom != "" {
+		out = append(out, FlagEdge{From: pf.Path, To: relatedPath(pf, pf.DefaultFrom), Relation: RelationDefaultFrom})
+	}
+	if pf.OnlyIf != "" {
+		out = append(out, FlagEdge{From: pf.Path, To: relatedPath(pf, pf.OnlyIf), Relation: RelationOnlyIf})
+	}
+	if toggle != "" {
+		out = append(out, FlagEdge{From: pf.Path, To: toggle, Relation: RelationOnlyIf})
+	}
+	return out
+}
+
+// edges lists the relations of all flags in the group, with the toggles that the flags are subject to.
+func (g *FlagGroup) edges(prefix string, toggle string, out *[]FlagEdge) {
+	if g.Toggle != nil {
+		toggle = g.togglePath(prefix)
+	}
+	path := g.path(prefix)
+	for _, f := range g.Flags {
+		k := f.Name
+		if path != "" {
+			k = path + "." + f.Name
+		}
+		*out = append(*out, PrefixedFlag{Path: k, Flag: f}.relations(toggle)...)
+	}
+	for _, e := range g.Entries {
+		e.edges(path, toggle, out)
+	}
+}
+
+// Graph exports the relations between the flags of the command.
+func (descr *CommandDescription) Graph() *FlagGraph {
+	var graph FlagGraph
+	for _, pf := range descr.All("") {
+		graph.Nodes = append(graph.Nodes, pf.Path)
+	}
+	descr.FlagGroup.edges("", "", &graph.Edges)
+	return &graph
+}
+
+// checkRelations checks that the relations refer to existing flags.
+func (descr *CommandDescription) checkRelations() error {
+	for _, e := range descr.Graph().Edges {
+		if _, ok := descr.Lookup(e.To); !ok {
+			return fmt.Errorf("flag %s has %s relation with unknown flag %s", e.From, e.Relation, e.To)
+		}
+	}
+	return nil
+}
+
+// isSet checks if the flag with the given path was set, by any source.
+func (descr *CommandDescription) isSet(path string) bool {
+	return descr.Source(path) != SourceDefault
+}
+
+// applyRelations applies the default-from relations, and validates the other relations of the flags that are set.
+func (descr *CommandDescription) applyRelations() error {
+	edges := descr.Graph().Edges
+	for _, e := range edges {
+		if e.Relation != RelationDefaultFrom || descr.isSet(e.From) || !descr.isSet(e.To) {
+			continue
+		}
+		from, _ := descr.Lookup(e.From)
+		to, _ := descr.Lookup(e.To)
+		v, err := to.Canonical()
+		if err != nil {
+			return fmt.Errorf("flag %s cannot take the value of %s: %w", e.From, e.To, err)
+		}
+		if err := from.Set(v); err != nil {
+			return fmt.Errorf("flag %s cannot take the value of %s: %w", e.From, e.To, err)
+		}
+	}
+	var errs []string
+	for _, e := range edges {
+		if !descr.isSet(e.From) {
+			continue
+		}
+		switch e.Relation {
+		case RelationRequires:
+			if !descr.isSet(e.To) {
+				errs = append(errs, fmt.Sprintf("flag %s requires flag %s", e.From, e.To))
+			}
+		case RelationConflicts:
+			if descr.isSet(e.To) {
+				errs = append(errs, fmt.Sprintf("flag %s conflicts with flag %s", e.From, e.To))
+			}
+		case RelationOnlyIf:
+			to, _ := descr.Lookup(e.To)
+			if v, err := strconv.ParseBool(to.Value.String()); err != nil || !v {
+				errs = append(errs, fmt.Sprintf("flag %s can only be used if flag %s is true", e.From, e.To))
+			}
+		}
+	}
+	errs = append(errs, descr.checkTogether()...)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid flag combination: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checkTogether checks that the flags of every set declared with `and` are set together, or not at all.
+func (descr *CommandDescription) checkTogether() []string {
+	sets := make(map[string][]string)
+	var names []string
+	for _, pf := range descr.All("") {
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		for _, name := range pf.Together {
+			if _, ok := sets[name]; !ok {
+				names = append(names, name)
+			}
+			sets[name] = append(sets[name], pf.Path)
+		}
+	}
+	var errs []string
+	for _, name := range names {
+		var missing []string
+		for _, path := range sets[name] {
+			if !descr.isSet(path) {
+				missing = append(missing, path)
+			}
+		}
+		if len(missing) > 0 && len(missing) < len(sets[name]) {
+			errs = append(errs, fmt.Sprintf("flags %s must be set together, missing %s",
+				strings.Join(sets[name], ", "), strings.Join(missing, ", ")))
+		}
+	}
+	return errs
+}
+
+// DOT renders the graph in the DOT language of Graphviz.
+func (graph *FlagGraph) DOT() string {
+	var out strings.Builder
+	out.WriteString("digraph flags {\n")
+	for _, n := range graph.Nodes {
+		out.WriteString("  ")
+		out.WriteString(strconv.Quote(n))
+		out.WriteString(";\n")
+	}
+	for _, e := range graph.Edges {
+		style := ""
+		switch e.Relation {
+		case RelationConflicts:
+			style = ", color=red, dir=both"
+		case RelationDefaultFrom:
+			style = ", style=dashed"
+		case RelationOnlyIf:
+			style = ", style=dotted"
+		}
+		fmt.Fprintf(&out, "  %s -> %s [label=%q%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), e.Relation, style)
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
@@ -0,0 +1,130 @@
+package ask
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type RelationsCmd struct {
+	Cert    string         `ask:"--cert" requires:"key"`
+	Key     string         `ask:"--key"`
+	Quiet   bool           `ask:"--quiet" conflicts:"verbose"`
+	Verbose bool           `ask:"--verbose"`
+	Listen  string         `ask:"--listen"`
+	Public  string         `ask:"--public" default-from:"listen"`
+	Cache   bool           `ask:"--cache"`
+	TTL     uint64         `ask:"--ttl" onlyif:"cache"`
+	Metrics MetricsOptions `ask:".metrics" toggle:"--metrics"`
+}
+
+func (c *RelationsCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestRelations(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--cert=a"}, "flag cert requires flag key"},
+		{[]string{"--cert=a", "--key=b"}, ""},
+		{[]string{"--quiet", "--verbose"}, "flag quiet conflicts with flag verbose"},
+		{[]string{"--ttl=10"}, "flag ttl can only be used if flag cache is true"},
+		{[]string{"--ttl=10", "--cache"}, ""},
+		{[]string{"--cert=a", "--quiet", "--verbose"}, "invalid flag combination: flag cert requires flag key; flag quiet conflicts with flag verbose"},
+	} {
+		cmd, err := Load(&RelationsCmd{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = cmd.Execute(context.Background(), nil, tc.args...)
+		if tc.err == "" {
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v", tc.args, err)
+			}
+		} else if err == nil || !IsUsageErr(err) || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%v: expected error %q, got: %v", tc.args, tc.err, err)
+		}
+	}
+
+	var c RelationsCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--listen=0.0.0.0:80"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Public != "0.0.0.0:80" {
+		t.Fatalf("expected public to default to listen, got %q", c.Public)
+	}
+
+	graph := cmd.Graph()
+	expected := []FlagEdge{
+		{From: "cert", To: "key", Relation: RelationRequires},
+		{From: "quiet", To: "verbose", Relation: RelationConflicts},
+		{From: "public", To: "listen", Relation: RelationDefaultFrom},
+		{From: "ttl", To: "cache", Relation: RelationOnlyIf},
+		{From: "metrics.port", To: "metrics", Relation: RelationOnlyIf},
+	}
+	if len(graph.Edges) != len(expected) {
+		t.Fatalf("unexpected edges: %v", graph.Edges)
+	}
+	for i, e := range expected {
+		if graph.Edges[i] != e {
+			t.Fatalf("edge %d: expected %v, got %v", i, e, graph.Edges[i])
+		}
+	}
+	dot := graph.DOT()
+	if !strings.HasPrefix(dot, "digraph flags {\n") ||
+		!strings.Contains(dot, `  "quiet" -> "verbose" [label="conflicts", color=red, dir=both];`) {
+		t.Fatalf("unexpected DOT output:\n%s", dot)
+	}
+
+	type BadCmd struct {
+		A string `ask:"--a" requires:"b"`
+	}
+	if _, err := Load(&BadCmd{}); err == nil || !strings.Contains(err.Error(), "unknown flag b") {
+		t.Fatalf("expected unknown flag error, got: %v", err)
+	}
+}
+
+type TogetherCmd struct {
+	TLS struct {
+		Cert string `ask:"--cert" and:"tls"`
+		Key  string `ask:"--key" and:"tls"`
+		CA   string `ask:"--ca" and:"tls,auth"`
+	} `ask:".tls"`
+	User string `ask:"--user" and:"auth"`
+}
+
+func (c *TogetherCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestTogether(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{nil, ""},
+		{[]string{"--tls.cert=a", "--tls.key=b", "--tls.ca=c", "--user=d"}, ""},
+		{[]string{"--tls.cert=a"}, "invalid flag combination: flags tls.cert, tls.key, tls.ca must be set together, missing tls.key, tls.ca"},
+		{[]string{"--user=d"}, "invalid flag combination: flags user, tls.ca must be set together, missing tls.ca"},
+		{[]string{"--tls.cert=a", "--tls.key=b", "--tls.ca=c"}, "flags user, tls.ca must be set together, missing user"},
+	} {
+		cmd, err := Load(&TogetherCmd{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = cmd.Execute(context.Background(), nil, tc.args...)
+		if tc.err == "" {
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v", tc.args, err)
+			}
+		} else if err == nil || !IsUsageErr(err) || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%v: expected error %q, got: %v", tc.args, tc.err, err)
+		}
+	}
+}
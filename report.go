@@ -0,0 +1,57 @@
+package ask
+
+// DefaultOrigin tells where the default value of a flag came from.
+type DefaultOrigin string
+
+const (
+	// DefaultZero is the origin of a default that is the zero value of the field.
+	DefaultZero DefaultOrigin = "zero"
+	// DefaultValue is the origin of a default that the field had before it was loaded, e.g. set by a parent command.
+	DefaultValue DefaultOrigin = "value"
+	// DefaultInit is the origin of a default that was set by InitDefault of the command or group of the flag.
+	DefaultInit DefaultOrigin = "InitDefault"
+	// DefaultTag is the origin of a default that was set by the `default` struct tag.
+	DefaultTag DefaultOrigin = "tag"
+)
+
+// DefaultReport describes the default of a flag, and whether a source overrode it.
+type DefaultReport struct {
+	Path string `json:"path"`
+	// Default is the default value, redacted for secret flags.
+	Default string `json:"default"`
+	// Origin of the default, empty for flags that are not struct fields.
+	Origin DefaultOrigin `json:"origin,omitempty"`
+	// InitDefault is true if InitDefault changed the default.
+	InitDefault bool `json:"initDefault"`
+	// Value is the current value, redacted for secret flags.
+	Value string `json:"value"`
+	// Source of the current value, see CommandDescription.Source.
+	Source string `json:"source"`
+	// Overridden is true if a source other than the default set the value.
+	Overridden bool `json:"overridden"`
+}
+
+// DefaultsReport describes, for every flag, the default that was in effect, where the default came from,
+// and whether a source overrode it. The report can be encoded as JSON, e.g. for a support bundle.
+func (descr *CommandDescription) DefaultsReport() []DefaultReport {
+	var out []DefaultReport
+	for _, pf := range descr.All("") {
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		r := DefaultReport{
+			Path:        pf.Path,
+			Default:     pf.Default,
+			Origin:      pf.DefaultOrigin,
+			InitDefault: pf.DefaultOrigin == DefaultInit,
+			Value:       pf.Value.String(),
+			Source:      descr.Source(pf.Path),
+		}
+		r.Overridden = r.Source != SourceDefault
+		if pf.Secret {
+			r.Default, r.Value = RedactedValue, RedactedValue
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type ReportOptions struct {
+	Retries int `ask:"--retries"`
+}
+
+type ReportCmd struct {
+	Port    uint16        `ask:"--port"`
+	Host    string        `ask:"--host"`
+	Name    string        `ask:"--name" default:"node"`
+	Token   string        `ask:"--token" secret:"true"`
+	Debug   bool          `ask:"--debug"`
+	Options ReportOptions `ask:".opts"`
+}
+
+func (c *ReportCmd) Default() {
+	c.Port = 9000
+	c.Options.Retries = 3
+}
+
+func (c *ReportCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestDefaultsReport(t *testing.T) {
+	c := ReportCmd{Host: "localhost", Token: "abc"}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--port=8000"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"port":         "{port 9000 InitDefault true 8000 flag true}",
+		"host":         "{host localhost value false localhost default false}",
+		"name":         "{name node tag false node default false}",
+		"token":        "{token *** value false *** default false}",
+		"debug":        "{debug false zero false false default false}",
+		"opts.retries": "{opts.retries 3 InitDefault true 3 default false}",
+	}
+	report := cmd.DefaultsReport()
+	if len(report) != len(expected) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, r := range report {
+		if got := fmt.Sprint(r); got != expected[r.Path] {
+			t.Errorf("%s: expected %s, got %s", r.Path, expected[r.Path], got)
+		}
+	}
+}
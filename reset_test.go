@@ -0,0 +1,156 @@
+package ask
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// AllValuesCmd has a field of every built-in flag value type, at its zero value or with a default.
+type AllValuesCmd struct {
+	Duration    time.Duration            `ask:"--duration"`
+	IP          net.IP                   `ask:"--ip"`
+	IPNet       net.IPNet                `ask:"--ipnet"`
+	IPMask      net.IPMask               `ask:"--ipmask"`
+	TCPAddr     net.TCPAddr              `ask:"--tcp"`
+	UDPAddr     net.UDPAddr              `ask:"--udp"`
+	MAC         net.HardwareAddr         `ask:"--mac"`
+	Regexp      *regexp.Regexp           `ask:"--regexp"`
+	Level       slog.Level               `ask:"--level"`
+	FileMode    fs.FileMode              `ask:"--filemode"`
+	Uint        uint                     `ask:"--uint"`
+	Uint8       uint8                    `ask:"--uint8"`
+	Uint16      uint16                   `ask:"--uint16"`
+	Uint32      uint32                   `ask:"--uint32"`
+	Uint64      uint64                   `ask:"--uint64"`
+	Int         int                      `ask:"--int"`
+	Int8        int8                     `ask:"--int8"`
+	Int16       int16                    `ask:"--int16"`
+	Int32       int32                    `ask:"--int32"`
+	Int64       int64                    `ask:"--int64"`
+	Float32     float32                  `ask:"--float32"`
+	Float64     float64                  `ask:"--float64"`
+	String      string                   `ask:"--string"`
+	Bool        bool                     `ask:"--bool"`
+	Bytes       []byte                   `ask:"--bytes"`
+	TriState    TriState                 `ask:"--tristate"`
+	Count       int                      `ask:"--count" format:"count"`
+	Rune        rune                     `ask:"--rune" format:"rune"`
+	Percent     float64                  `ask:"--percent" format:"percent"`
+	Enum        string                   `ask:"--enum" enum:"fast,slow"`
+	Bounded     time.Duration            `ask:"--bounded" min:"1s" max:"1h"`
+	Port        PortValue                `ask:"--port"`
+	PortRange   PortRangeValue           `ask:"--port-range"`
+	Rate        RateValue                `ask:"--rate"`
+	UUID        UUIDValue                `ask:"--uuid"`
+	Secrets     SecretListValue          `ask:"--secrets"`
+	Hosts       HostListValue            `ask:"--hosts"`
+	Weighted    WeightedEndpointsValue   `ask:"--weighted"`
+	Optional    Optional[uint16]         `ask:"--optional"`
+	Big         big.Int                  `ask:"--big"`
+	Durations   []time.Duration          `ask:"--durations"`
+	IPs         []net.IP                 `ask:"--ips"`
+	IPNets      []net.IPNet              `ask:"--ipnets"`
+	MACs        []net.HardwareAddr       `ask:"--macs"`
+	Uint64s     []uint64                 `ask:"--uint64s"`
+	Ints        []int                    `ask:"--ints"`
+	Float64s    []float64                `ask:"--float64s"`
+	Strings     []string                 `ask:"--strings"`
+	Bools       []bool                   `ask:"--bools"`
+	Labels      map[string]string        `ask:"--labels"`
+	Weights     map[string]int           `ask:"--weights"`
+	Timeouts    map[string]time.Duration `ask:"--timeouts"`
+	WithDefault string                   `ask:"--with-default"`
+}
+
+func (c *AllValuesCmd) Default() {
+	c.WithDefault = "default"
+	c.Strings = []string{"a", "b"}
+	c.Labels = map[string]string{"env": "dev"}
+}
+
+func (c *AllValuesCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestResetAllValueTypes(t *testing.T) {
+	var c AllValuesCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := deepCopy(reflect.ValueOf(c)).Interface()
+	args := []string{
+		"--duration=5s", "--ip=1.2.3.4", "--ipnet=10.0.0.0/8", "--ipmask=255.255.255.0", "--tcp=1.2.3.4:80",
+		"--udp=1.2.3.4:53", "--mac=00:11:22:33:44:55", "--regexp=a+", "--level=debug", "--filemode=0644",
+		"--uint=1", "--uint8=2", "--uint16=3", "--uint32=4", "--uint64=5", "--int=-1", "--int8=-2", "--int16=-3",
+		"--int32=-4", "--int64=-5", "--float32=1.5", "--float64=2.5", "--string=s", "--bool", "--bytes=0xab",
+		"--tristate=true", "--count", "--count", "--rune=x", "--percent=50%", "--enum=fast", "--bounded=1m",
+		"--port=80", "--port-range=80-90", "--rate=10/s", "--uuid=123e4567-e89b-12d3-a456-426614174000",
+		"--secrets=s3cret", "--hosts=example.com", "--weighted=a:80", "--optional=8", "--big=12345678901234567890",
+		"--durations=1s,2s", "--ips=1.2.3.4", "--ipnets=10.0.0.0/8", "--macs=00:11:22:33:44:55",
+		"--uint64s=1,2", "--ints=1,-2", "--float64s=1.5", "--strings=x", "--bools=true,false",
+		"--labels=a=b", "--weights=a=1", "--timeouts=a=1s", "--with-default=changed",
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cmd.Execute(context.Background(), nil, args...); err != nil {
+			t.Fatal(err)
+		}
+		lv, cv := reflect.ValueOf(loaded), reflect.ValueOf(c)
+		for f := 0; f < lv.NumField(); f++ {
+			if reflect.DeepEqual(lv.Field(f).Interface(), cv.Field(f).Interface()) {
+				t.Fatalf("expected %s to change", lv.Type().Field(f).Name)
+			}
+		}
+		if err := cmd.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, c) {
+			t.Fatalf("expected loaded values after reset:\n%+v\ngot:\n%+v", loaded, c)
+		}
+	}
+	for _, pf := range cmd.All("") {
+		if !pf.dest.IsValid() {
+			t.Errorf("flag %s is not bound to its field", pf.Path)
+		}
+	}
+}
+
+type PointerResetCmd struct {
+	Port *int     `ask:"--port"`
+	Name **string `ask:"--name"`
+}
+
+func (c *PointerResetCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestResetPointerField(t *testing.T) {
+	var c PointerResetCmd
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := c.Port
+	for _, p := range []int{3, 5} {
+		if _, err := cmd.Execute(context.Background(), nil, "--port", strconv.Itoa(p), "--name=x"); err != nil {
+			t.Fatal(err)
+		}
+		if *c.Port != p || **c.Name != "x" {
+			t.Fatalf("expected port %d and name x, got %d and %q", p, *c.Port, **c.Name)
+		}
+		if err := cmd.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != port || *c.Port != 0 || **c.Name != "" {
+			t.Fatalf("expected the same pointee with the zero value after reset, got %d and %q", *c.Port, **c.Name)
+		}
+	}
+}
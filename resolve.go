@@ -0,0 +1,50 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ResolvableValue is implemented by flag values that complete their value after parsing,
+// e.g. with a DNS lookup or by fetching a remote resource. See Resolve.
+type ResolvableValue interface {
+	// Resolve completes the value. It should return early when the context is canceled.
+	Resolve(ctx context.Context) error
+}
+
+// Resolve resolves the values of all flags and positional args that implement ResolvableValue, concurrently.
+// Execute resolves the values after parsing, before the command runs.
+// The timeout applies to the resolution of all values together, zero for no timeout.
+// The errors of all failing flags are joined, each naming the flag.
+func (descr *CommandDescription) Resolve(ctx context.Context, timeout time.Duration) error {
+	var resolvable []PrefixedFlag
+	for _, pf := range descr.All("") {
+		if _, ok := pf.Value.(ResolvableValue); ok && pf.ReplacedBy == nil {
+			resolvable = append(resolvable, pf)
+		}
+	}
+	if len(resolvable) == 0 {
+		return nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	errs := make([]error, len(resolvable))
+	var wg sync.WaitGroup
+	for i, pf := range resolvable {
+		wg.Add(1)
+		go func(i int, pf PrefixedFlag) {
+			defer wg.Done()
+			if err := pf.Value.(ResolvableValue).Resolve(ctx); err != nil {
+				errs[i] = fmt.Errorf("failed to resolve %s: %w", flagDecl(pf), err)
+			}
+		}(i, pf)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// HostValue resolves a host name to an address, with a fake lookup.
+type HostValue struct {
+	Name, Addr string
+	delay      time.Duration
+	resolving  *int32
+}
+
+func (v *HostValue) String() string {
+	return v.Name
+}
+
+func (v *HostValue) Set(s string) error {
+	v.Name, v.Addr = s, ""
+	return nil
+}
+
+func (v *HostValue) Resolve(ctx context.Context) error {
+	if v.resolving != nil {
+		atomic.AddInt32(v.resolving, 1)
+	}
+	select {
+	case <-time.After(v.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if strings.HasSuffix(v.Name, ".invalid") {
+		return errors.New("no such host")
+	}
+	v.Addr = "10.0.0." + string(rune('0'+len(v.Name)%10))
+	return nil
+}
+
+type ResolveCmd struct {
+	Primary HostValue `ask:"--primary"`
+	Backup  HostValue `ask:"--backup"`
+	Ran     bool
+}
+
+func (c *ResolveCmd) Run(ctx context.Context, args ...string) error {
+	c.Ran = true
+	return nil
+}
+
+func TestResolve(t *testing.T) {
+	var resolving int32
+	c := ResolveCmd{
+		Primary: HostValue{delay: 50 * time.Millisecond, resolving: &resolving},
+		Backup:  HostValue{delay: 50 * time.Millisecond, resolving: &resolving},
+	}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.Execute(context.Background(), &ExecutionOptions{ParseOnly: true}, "--primary=a", "--backup=bb"); err != nil {
+		t.Fatal(err)
+	}
+	if resolving != 0 {
+		t.Fatal("expected values not to be resolved when only parsing")
+	}
+	start := time.Now()
+	if _, err := cmd.Execute(context.Background(), nil, "--primary=a", "--backup=bb"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Primary.Addr != "10.0.0.1" || c.Backup.Addr != "10.0.0.2" || !c.Ran {
+		t.Fatalf("unexpected values: %+v", c)
+	}
+	if time.Since(start) >= 100*time.Millisecond {
+		t.Fatal("expected values to be resolved concurrently")
+	}
+
+	c.Ran = false
+	_, err = cmd.Execute(context.Background(), nil, "--primary=a.invalid", "--backup=b.invalid")
+	if err == nil || c.Ran {
+		t.Fatal("expected resolution to fail before running")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "failed to resolve --primary: no such host") ||
+		!strings.Contains(msg, "failed to resolve --backup: no such host") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Primary.delay, c.Backup.delay = time.Second, 0
+	_, err = cmd.Execute(context.Background(), &ExecutionOptions{ResolveTimeout: 10 * time.Millisecond}, "--primary=a", "--backup=bb")
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "backup") {
+		t.Fatalf("expected only the primary to time out, got: %v", err)
+	}
+}
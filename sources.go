@@ -0,0 +1,191 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// SourceDefault is the source of a flag value that was not changed after loading the command.
+	SourceDefault = "default"
+	// SourceFlag is the source of a flag value that was set by a command-line argument.
+	SourceFlag = "flag"
+	// SourceEnv is the kind of source of a flag value from an environment variable, e.g. "env:APP_PORT".
+	SourceEnv = "env"
+	// SourceConfig is the kind of source of a flag value from a config file, e.g. "config:~/.app.yaml".
+	SourceConfig = "config"
+)
+
+// DefaultPrecedence ranks the sources of flag values when CommandDescription.Precedence is nil:
+// command-line arguments override environment variables, which override config files, which override defaults.
+var DefaultPrecedence = []string{SourceFlag, SourceEnv, SourceConfig}
+
+// sourceKind returns the kind of a source, the part before the colon, e.g. "env" for "env:APP_PORT".
+func sourceKind(source string) string {
+	kind, _, _ := strings.Cut(source, ":")
+	return kind
+}
+
+// outranks checks if a value from source a has precedence over a value from source b.
+// Sources of kinds that are not ranked do not outrank, and are not outranked by, other sources.
+func (descr *CommandDescription) outranks(a, b string) bool {
+	precedence := descr.Precedence
+	if precedence == nil {
+		precedence = DefaultPrecedence
+	}
+	rank := func(source string) int {
+		kind := sourceKind(source)
+		for i, k := range precedence {
+			if k == kind {
+				return i
+			}
+		}
+		return -1
+	}
+	ra, rb := rank(a), rank(b)
+	return ra >= 0 && rb >= 0 && ra < rb
+}
+
+// shadow records that the value from the source is not the current value of the flag, see Explain.
+func (descr *CommandDescription) shadow(path string, source string) {
+	if descr.Shadowed == nil {
+		descr.Shadowed = make(map[string][]string)
+	}
+	for _, s := range descr.Shadowed[path] {
+		if s == source {
+			return
+		}
+	}
+	descr.Shadowed[path] = append(descr.Shadowed[path], source)
+}
+
+// Source returns where the current value of the flag with the given path came from,
+// e.g. "default", "flag", or the source name given to SetFromMap, like "config:~/.app.yaml".
+func (descr *CommandDescription) Source(path string) string {
+	if src, ok := descr.Sources[path]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// SetFromMap sets the flags by path to the given values, and records the source of the values.
+// The source describes where the values came from, e.g. "config:~/.app.yaml" or "env:APP_PORT".
+// Flags are set in order of path. Locked flags cannot be set.
+// Invalid values do not stop the other values from being set: the errors of all invalid keys and values are joined.
+func (descr *CommandDescription) SetFromMap(values map[string]string, source string) error {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if err := descr.FlagGroup.expandIndexed("", paths); err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range paths {
+		pf, ok := descr.Lookup(path)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown flag %q (from %s)", path, source))
+			continue
+		}
+		if err := descr.setFlag(context.Background(), pf, values[path], source); err != nil {
+			errs = append(errs, fmt.Errorf("%w (from %s)", FlagValueErr(pf, values[path], err), source))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setFlag sets the value of a flag, marks it as changed, and records the source of the value.
+// The context is used to set a CtxValue.
+// A deprecated positional arg sets the flag that replaces it.
+// The value is ignored if the current value is from a source of higher precedence.
+func (descr *CommandDescription) setFlag(ctx context.Context, fl PrefixedFlag, value string, source string) error {
+	path := fl.Path
+	if fl.ReplacedBy != nil {
+		path = replacementPath(fl)
+	}
+	if _, ok := descr.Locked[path]; ok {
+		return fmt.Errorf("flag %s is locked by the application and cannot be changed", path)
+	}
+	prev, hasPrev := descr.Sources[path]
+	if hasPrev && descr.outranks(prev, source) {
+		descr.shadow(path, source)
+		return nil
+	}
+	if err := fl.Flag.SetCtx(ctx, value); err != nil {
+		return err
+	}
+	if hasPrev && prev != source {
+		descr.shadow(path, prev)
+	}
+	for _, ptr := range descr.ChangedMarkers[path] {
+		*ptr = true
+	}
+	// a deprecated arg is changed too, if it is set instead of its replacement
+	if fl.ReplacedBy != nil && fl.Path != path {
+		for _, ptr := range descr.ChangedMarkers[fl.Path] {
+			*ptr = true
+		}
+	}
+	if descr.Sources == nil {
+		descr.Sources = make(map[string]string)
+	}
+	descr.Sources[path] = source
+	return nil
+}
+
+// Reset restores every flag to the value it had after loading, resets the changed markers and forgets the sources of the values,
+// so a long-lived command description can be executed and configured again without retaining previous state.
+// Elements that were added to indexed groups are removed. Locked flags keep their value.
+func (descr *CommandDescription) Reset() error {
+	if err := descr.FlagGroup.resetIndexed(); err != nil {
+		return err
+	}
+	for _, pf := range descr.All("") {
+		if _, ok := descr.Locked[pf.Path]; ok || pf.ReplacedBy != nil {
+			continue
+		}
+		if err := pf.reset(); err != nil {
+			return fmt.Errorf("failed to reset flag %s: %w", pf.Path, err)
+		}
+	}
+	for _, ptrs := range descr.ChangedMarkers {
+		for _, ptr := range ptrs {
+			*ptr = false
+		}
+	}
+	for path := range descr.Sources {
+		if _, ok := descr.Locked[path]; !ok {
+			delete(descr.Sources, path)
+		}
+	}
+	descr.Shadowed = nil
+	return nil
+}
+
+// Explain lists every flag with its current value and the source it came from,
+// and the sources of values that were overridden or ignored by precedence, one flag per line,
+// e.g. "port = 123 (flag, over env:PORT, config:app.json)". Secret values are redacted.
+// This helps to debug which of the configuration sources won, e.g. for an --explain-config flag.
+func (descr *CommandDescription) Explain() string {
+	var out strings.Builder
+	for _, pf := range descr.All("") {
+		if pf.ReplacedBy != nil {
+			continue
+		}
+		value := pf.Value.String()
+		if pf.Secret {
+			value = pf.redacted()
+		}
+		out.WriteString(fmt.Sprintf("%s = %s (%s", pf.Path, value, descr.Source(pf.Path)))
+		if shadowed := descr.Shadowed[pf.Path]; len(shadowed) > 0 {
+			out.WriteString(", over ")
+			out.WriteString(strings.Join(shadowed, ", "))
+		}
+		out.WriteString(")\n")
+	}
+	return out.String()
+}
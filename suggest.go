@@ -0,0 +1,33 @@
+package ask
+
+import "flag"
+
+// SuggestionKind is the kind of token that a Suggestion completes.
+type SuggestionKind uint8
+
+const (
+	// SuggestRoute is a sub-command.
+	SuggestRoute SuggestionKind = iota
+	// SuggestFlag is a flag name, including the "--" or "-" prefix.
+	SuggestFlag
+	// SuggestValue is a value of a flag or positional argument.
+	SuggestValue
+	// SuggestFile hints that a free-form value is expected, like a file path, to complete outside of the command.
+	// The Value is the partial input.
+	SuggestFile
+)
+
+// ValueCompleter is implemented by values that can list candidate values, for completion,
+// e.g. true and false for a bool, or the addresses of the network interfaces for an IP.
+// The candidates are filtered by the partial value.
+type ValueCompleter interface {
+	flag.Value
+	Complete(partial string) []string
+}
+
+// Suggestion is a candidate for the next token of the command-line.
+type Suggestion struct {
+	Value       string
+	Description string
+	Kind        SuggestionKind
+}
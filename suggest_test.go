@@ -0,0 +1,121 @@
+//go:build !ask_minimal
+
+package ask
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func suggestionValues(suggestions []Suggestion) string {
+	out := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		out[i] = fmt.Sprintf("%d:%s", s.Kind, s.Value)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestSuggest(t *testing.T) {
+	cmd, err := Load(&Peer{ActorState: &ActorState{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "0:connect"},
+		{[]string{"c"}, "0:connect"},
+		{[]string{"x"}, ""},
+		{[]string{"connect", "--p"}, "1:--peer.tag 1:--port"},
+		{[]string{"connect", "--port", ""}, ""},
+		{[]string{"connect", "--peer.tag", "a"}, "3:a"},
+		{[]string{"connect", "--port", "1", "my"}, "3:my"},
+		{[]string{"connect", "--bogus", "id", ""}, ""},
+		{[]string{"connect", "id", "1", "--", "m"}, "3:m"},
+	} {
+		if got := suggestionValues(cmd.Suggest(tc.args)); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+	if s := cmd.Suggest([]string{""}); len(s) != 1 || s[0].Description != "Connect to a peer" {
+		t.Fatalf("expected route description, got: %+v", s)
+	}
+
+	kong, err := LoadWithOptions(&ParserCmd{}, &LoadOptions{Kong: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := suggestionValues(kong.Suggest([]string{"-v", "--mode", "s"})); got != "2:slow" {
+		t.Fatalf("expected enum value, got %q", got)
+	}
+}
+
+type CompleteCmd struct {
+	Timeout time.Duration `ask:"--timeout"`
+	Level   LevelValue    `ask:"--level"`
+	Bind    net.IP        `ask:"--bind"`
+	Force   bool          `ask:"<force>"`
+}
+
+func (c *CompleteCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestSuggestValues(t *testing.T) {
+	cmd, err := Load(&CompleteCmd{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--timeout", "10"}, "2:10ms 2:10s 2:10m 2:10h"},
+		{[]string{"--timeout", "10m"}, "2:10ms 2:10m"},
+		{[]string{"--timeout", "x"}, ""},
+		{[]string{"--level", "w"}, "2:warn"},
+		{[]string{"--bind", "127.0.0."}, "2:127.0.0.1"},
+		{[]string{"f"}, "2:false"},
+	} {
+		if got := suggestionValues(cmd.Suggest(tc.args)); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+	p := cmd.Parser(nil, nil)
+	if err := p.Feed("--level"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.Candidates(""), " "); got != "trace debug info warn error" {
+		t.Fatalf("unexpected candidates: %q", got)
+	}
+}
+
+func TestParserCandidates(t *testing.T) {
+	cmd, err := LoadWithOptions(&ParserCmd{}, &LoadOptions{Kong: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cmd.Parser(nil, func(fl PrefixedFlag, value string) error {
+		return fl.Set(value)
+	})
+	if got := strings.Join(p.Candidates("--p"), " "); got != "--peer --port" {
+		t.Fatalf("unexpected flag candidates: %s", got)
+	}
+	if got := strings.Join(p.Candidates("-"), " "); !strings.Contains(got, "--mode") || strings.Contains(got, "--secret") || !strings.HasSuffix(got, "-p -v") {
+		t.Fatalf("unexpected candidates: %s", got)
+	}
+	if p.Candidates("x") != nil {
+		t.Fatal("expected no candidates for positional arg")
+	}
+	if err := p.Feed("--mode"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.Candidates("f"), " "); got != "fast" {
+		t.Fatalf("unexpected value candidates: %s", got)
+	}
+}
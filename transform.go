@@ -0,0 +1,83 @@
+package ask
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transform changes the raw string value of a flag before it is set.
+type Transform func(v string) (string, error)
+
+var transforms = map[string]Transform{
+	"expandenv": func(v string) (string, error) {
+		return os.ExpandEnv(v), nil
+	},
+	"home": ExpandHome,
+	"abs":  filepath.Abs,
+	"lower": func(v string) (string, error) {
+		return strings.ToLower(v), nil
+	},
+	"upper": func(v string) (string, error) {
+		return strings.ToUpper(v), nil
+	},
+	"trim": func(v string) (string, error) {
+		return strings.TrimSpace(v), nil
+	},
+	"file": ReadFileRef,
+}
+
+// RegisterTransform registers a named transform, to use with the `transform` struct tag,
+// e.g. `transform:"expandenv,abs"`. Built-in transforms are:
+// "expandenv", "home", "abs", "lower", "upper", "trim" and "file" (see ReadFileRef).
+// Transforms are not safe to register concurrently with Load, register them during initialization.
+func RegisterTransform(name string, fn Transform) {
+	transforms[name] = fn
+}
+
+// ExpandHome replaces a leading "~" with the home directory of the user.
+func ExpandHome(v string) (string, error) {
+	if v != "~" && !strings.HasPrefix(v, "~/") {
+		return v, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return home + v[1:], nil
+}
+
+// ReadFileRef reads the value from a file if it starts with "@", e.g. "@/run/secrets/token",
+// so secrets do not have to appear in the shell history or the process list. Trailing newlines are trimmed.
+// A value starting with "@@" is taken literally, without the first "@".
+func ReadFileRef(v string) (string, error) {
+	if !strings.HasPrefix(v, "@") {
+		return v, nil
+	}
+	if strings.HasPrefix(v, "@@") {
+		return v[1:], nil
+	}
+	data, err := os.ReadFile(v[1:])
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// loadTransforms loads the transforms of a comma-separated list of names.
+func loadTransforms(names string) ([]Transform, error) {
+	var out []Transform
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fn, ok := transforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+		out = append(out, fn)
+	}
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// unionCommand runs one of multiple commands, selected by a discriminator flag.
+//
+// A union is declared by tagging the commands with `union:"name"`, and a string flag with `discriminator:"true"`:
+//
+//	type NodeCmd struct {
+//		Mode   string    `ask:"--mode" discriminator:"true" help:"Mode to run: server or client"`
+//		Server ServerCmd `ask:".server" union:"server"`
+//		Client ClientCmd `ask:".client" union:"client"`
+//	}
+type unionCommand struct {
+	// string field of the discriminator flag
+	discriminator reflect.Value
+	// flag declaration of the discriminator, for errors
+	flag    string
+	names   []string
+	members map[string]Command
+	// path prefix of the flags of every member, empty if the member is squashed
+	prefixes map[string]string
+}
+
+// check validates that the discriminator selects exactly one member,
+// and that no flags of the other members are set, see checkToggles.
+func (u *unionCommand) check(seen map[string]struct{}) error {
+	name := u.discriminator.String()
+	if _, ok := u.members[name]; !ok {
+		return fmt.Errorf("%s must be one of: %s, got %q", u.flag, strings.Join(u.names, ", "), name)
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, other := range u.names {
+		prefix := u.prefixes[other]
+		if other == name || prefix == "" {
+			continue
+		}
+		for _, path := range paths {
+			if strings.HasPrefix(path, prefix+".") {
+				return fmt.Errorf("flag %s is of %s, but %s selects %s", path, other, u.flag, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (u *unionCommand) Run(ctx context.Context, args ...string) error {
+	name := u.discriminator.String()
+	cmd, ok := u.members[name]
+	if !ok {
+		return &UsageErr{fmt.Errorf("%s must be one of: %s, got %q", u.flag, strings.Join(u.names, ", "), name)}
+	}
+	return cmd.Run(ctx, args...)
+}
+
+// loadUnion loads the union of commands declared by the fields of the struct.
+// Nil is returned if the struct does not declare a union.
+func (l *loader) loadUnion(val reflect.Value) (*unionCommand, error) {
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	typ := val.Type()
+	u := &unionCommand{members: make(map[string]Command), prefixes: make(map[string]string)}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if _, ok := f.Tag.Lookup("discriminator"); ok {
+			if u.discriminator.IsValid() {
+				return nil, fmt.Errorf("field %q cannot be a second union discriminator", f.Name)
+			}
+			if f.Type.Kind() != reflect.String {
+				return nil, fmt.Errorf("union discriminator field %q must be a string", f.Name)
+			}
+			u.discriminator = val.Field(i)
+			u.flag = f.Name
+			if tag, ok := l.getAsk(&f); ok {
+				if decl := strings.Fields(tag); len(decl) > 0 {
+					u.flag = decl[0]
+				}
+			}
+			continue
+		}
+		name, ok := f.Tag.Lookup("union")
+		if !ok {
+			continue
+		}
+		if _, exists := u.members[name]; exists {
+			return nil, fmt.Errorf("field %q cannot be union member %q, the name is already used", f.Name, name)
+		}
+		v := val.Field(i)
+		if v.Kind() != reflect.Ptr {
+			v = v.Addr()
+		}
+		cmd, ok := v.Interface().(Command)
+		if !ok || v.IsNil() {
+			return nil, fmt.Errorf("union member field %q must be a Command", f.Name)
+		}
+		u.names = append(u.names, name)
+		u.members[name] = cmd
+		if tag, ok := l.getAsk(&f); ok && strings.HasPrefix(tag, ".") {
+			u.prefixes[name] = strings.TrimPrefix(strings.Fields(tag)[0], ".")
+		}
+	}
+	if len(u.members) == 0 {
+		if u.discriminator.IsValid() {
+			return nil, fmt.Errorf("type %s has a union discriminator, but no union members", typ)
+		}
+		return nil, nil
+	}
+	if !u.discriminator.IsValid() {
+		return nil, fmt.Errorf("type %s has union members, but no union discriminator", typ)
+	}
+	return u, nil
+}
@@ -0,0 +1,416 @@
+package ask
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// UsageOptions configures what is included in the usage information of a command.
+type UsageOptions struct {
+	// ShowHidden includes hidden flags.
+	ShowHidden bool
+	// Filter only includes the flags and groups with a path or help that matches.
+	// Everything is included if nil.
+	Filter func(v string) bool
+	// ShowSources annotates each flag with where its current value came from, see CommandDescription.Source.
+	ShowSources bool
+	// HideZeroDefaults omits defaults that are the zero value of the flag type, like "0" and "false".
+	HideZeroDefaults bool
+	// FullDefaults renders long defaults in full. Otherwise defaults are truncated to MaxDefaultLength characters.
+	FullDefaults bool
+
+	// Footer is a text/template rendered at the end of the usage, with FooterData. Nothing is rendered if empty.
+	Footer string
+	// App is the name of the application, to render the footer with.
+	App string
+
+	// source of the flag value by path, provided by the command description
+	source func(path string) string
+}
+
+// UsageOption changes the UsageOptions.
+type UsageOption func(opts *UsageOptions)
+
+// WithFilter only includes the flags and groups with a path or help that contains the given substring,
+// case-insensitive.
+func WithFilter(substr string) UsageOption {
+	substr = strings.ToLower(substr)
+	return func(opts *UsageOptions) {
+		opts.Filter = func(v string) bool {
+			return strings.Contains(strings.ToLower(v), substr)
+		}
+	}
+}
+
+// WithSources annotates each flag with where its current value came from, e.g. "default", "flag" or "env:APP_PORT".
+func WithSources() UsageOption {
+	return func(opts *UsageOptions) {
+		opts.ShowSources = true
+	}
+}
+
+// FooterData is available to footer templates, see WithFooter.
+type FooterData struct {
+	// App is the name of the application
+	App string
+	// Route is the route of the command, sub-command names separated by spaces. Empty for the root command.
+	Route string
+	// Command is the app name with the route, e.g. "app peer connect"
+	Command string
+	// SubCommands is true if the command lists any sub-commands
+	SubCommands bool
+}
+
+// WithFooter renders a footer at the end of the usage, for the application with the given name.
+// The footer is a text/template with FooterData, e.g. "Use '{{.Command}} <command> --help' for more information."
+func WithFooter(app string, tmpl string) UsageOption {
+	return func(opts *UsageOptions) {
+		opts.App = app
+		opts.Footer = tmpl
+	}
+}
+
+// WithoutZeroDefaults omits defaults that are the zero value of the flag type, like "0" and "false".
+func WithoutZeroDefaults() UsageOption {
+	return func(opts *UsageOptions) {
+		opts.HideZeroDefaults = true
+	}
+}
+
+// WithFullDefaults renders long defaults in full, instead of truncated to MaxDefaultLength characters.
+func WithFullDefaults() UsageOption {
+	return func(opts *UsageOptions) {
+		opts.FullDefaults = true
+	}
+}
+
+// MaxDefaultLength is the number of characters of a default that is rendered in usage, unless WithFullDefaults is used.
+const MaxDefaultLength = 80
+
+// previewDefault truncates a long default, with the number of characters that are left out, e.g. "00ff… (120 more)".
+func (opts *UsageOptions) previewDefault(v string) string {
+	if opts.FullDefaults {
+		return v
+	}
+	chars := []rune(v)
+	if len(chars) <= MaxDefaultLength {
+		return v
+	}
+	return fmt.Sprintf("%s… (%d more)", string(chars[:MaxDefaultLength]), len(chars)-MaxDefaultLength)
+}
+
+// WithFilterRegexp only includes the flags and groups with a path or help that matches the given regular expression.
+func WithFilterRegexp(re *regexp.Regexp) UsageOption {
+	return func(opts *UsageOptions) {
+		opts.Filter = re.MatchString
+	}
+}
+
+func (opts *UsageOptions) include(f *Flag, path string) bool {
+	if f.Hidden && !opts.ShowHidden {
+		return false
+	}
+	if opts.Filter == nil {
+		return true
+	}
+	p := f.Name
+	if path != "" {
+		p = path + "." + f.Name
+	}
+	return opts.Filter(p) || opts.Filter(f.Help)
+}
+
+func (opts *UsageOptions) matchGroup(g *FlagGroup, path string) bool {
+	if opts.Filter == nil {
+		return true
+	}
+	// the top-level group is not named, and its help is the command help
+	if g.GroupName == "" {
+		return false
+	}
+	return opts.Filter(path) || (g.Help != nil && opts.Filter(g.Help.Help()))
+}
+
+func (g *FlagGroup) Usage(prefix string, showHidden bool, out *strings.Builder) {
+	g.usage(prefix, &UsageOptions{ShowHidden: showHidden}, out)
+}
+
+func (g *FlagGroup) usage(prefix string, opts *UsageOptions, out *strings.Builder) {
+	path := g.path(prefix)
+	// everything in the group is included if the group itself matches the filter
+	if opts.Filter != nil && opts.matchGroup(g, path) {
+		all := *opts
+		all.Filter = nil
+		opts = &all
+	}
+	if !g.hasMatch(path, opts) {
+		return
+	}
+	if g.GroupName != "" {
+		out.WriteString("# ")
+		out.WriteString(path)
+		out.WriteString("\n")
+	}
+	if !g.Enabled() {
+		out.WriteString("(disabled, enable with --")
+		out.WriteString(g.togglePath(prefix))
+		out.WriteString(")\n\n")
+		return
+	}
+	if g.Help != nil {
+		out.WriteString(RenderHelp(g.Help.Help(), HelpText))
+		out.WriteString("\n\n")
+	}
+	for _, f := range g.Flags {
+		if !opts.include(f, path) {
+			continue
+		}
+		out.WriteString("  ")
+		indent := 2
+		if f.Shorthand != 0 {
+			out.WriteString("-")
+			out.WriteByte(f.Shorthand)
+			out.WriteString(" ")
+			// e.g. "-c "
+			indent += 1 + 1 + 1
+		}
+		if f.Name != string(f.Shorthand) {
+			var prefix, suffix string
+			if f.IsArg {
+				if f.Required {
+					prefix = "<"
+					suffix = ">"
+				} else {
+					prefix = "["
+					suffix = "]"
+				}
+			} else {
+				prefix = "--"
+			}
+			out.WriteString(prefix)
+			if path != "" {
+				out.WriteString(path)
+				out.WriteString(".")
+				indent += len(path) + 1
+			}
+			out.WriteString(f.Name)
+			out.WriteString(suffix)
+			out.WriteString(" ")
+			indent += len(prefix) + len(f.Name) + len(suffix) + 1
+		}
+		if indent < 30 {
+			out.WriteString(strings.Repeat(" ", 30-indent))
+		}
+		writeHelp(out, f.Help, 30)
+		if f.Default != "" && !f.HideDefault && !(opts.HideZeroDefaults && f.isZeroDefault()) {
+			out.WriteString(" (default: ")
+			out.WriteString(opts.previewDefault(f.usageDefault()))
+			out.WriteString(")")
+		}
+		if f.Default == "true" && f.isNegatable() && f.Name != string(f.Shorthand) {
+			out.WriteString(" (disable with --no-")
+			if path != "" {
+				out.WriteString(path)
+				out.WriteString(".")
+			}
+			out.WriteString(f.Name)
+			out.WriteString(")")
+		}
+		if tv, ok := f.Value.(TypedValue); ok {
+			typ := tv.Type()
+			if typ != "" {
+				out.WriteString(" (type: ")
+				out.WriteString(typ)
+				out.WriteString(")")
+			}
+		}
+		for _, m := range f.meta() {
+			out.WriteString(" (")
+			out.WriteString(m)
+			out.WriteString(")")
+		}
+		if opts.ShowSources && opts.source != nil && f.ReplacedBy == nil {
+			out.WriteString(" (source: ")
+			if path != "" {
+				out.WriteString(opts.source(path + "." + f.Name))
+			} else {
+				out.WriteString(opts.source(f.Name))
+			}
+			out.WriteString(")")
+		}
+		if f.Deprecated != "" {
+			out.WriteString(" DEPRECATED: ")
+			out.WriteString(f.Deprecated)
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("\n")
+	entries := g.Entries
+	if g.indexed != nil && len(entries) == 0 {
+		// describe the flags of an element, if there are no elements yet
+		if tmpl, err := g.indexed.template(); err == nil {
+			entries = []*FlagGroup{tmpl}
+		}
+	}
+	for _, e := range entries {
+		e.usage(path, opts, out)
+	}
+}
+
+// writeHelp writes the help for a terminal, with the lines after the first line indented.
+func writeHelp(out *strings.Builder, help string, indent int) {
+	out.WriteString(strings.ReplaceAll(RenderHelp(help, HelpText), "\n", "\n"+strings.Repeat(" ", indent)))
+}
+
+// hasMatch checks if the group, or any of its sub-groups, has a flag to include in the usage.
+func (g *FlagGroup) hasMatch(path string, opts *UsageOptions) bool {
+	if opts.Filter == nil || opts.matchGroup(g, path) {
+		return true
+	}
+	for _, f := range g.Flags {
+		if opts.include(f, path) {
+			return true
+		}
+	}
+	for _, e := range g.Entries {
+		if e.hasMatch(e.path(path), opts) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagCount counts the flags of the group and its sub-groups that are included in the usage, not counting args.
+func (g *FlagGroup) flagCount(prefix string, opts *UsageOptions) int {
+	path := g.path(prefix)
+	// everything in the group is included if the group itself matches the filter, like in usage
+	if opts.Filter != nil && opts.matchGroup(g, path) {
+		all := *opts
+		all.Filter = nil
+		opts = &all
+	}
+	count := 0
+	for _, f := range g.Flags {
+		if !f.IsArg && opts.include(f, path) {
+			count += 1
+		}
+	}
+	for _, e := range g.Entries {
+		count += e.flagCount(path, opts)
+	}
+	return count
+}
+
+// Usage prints the help information and the usage of all flags.
+// Options can be specified to change what is included, see UsageOption.
+func (descr *CommandDescription) Usage(showHidden bool, options ...UsageOption) string {
+	opts := &UsageOptions{ShowHidden: showHidden, source: descr.Source}
+	for _, o := range options {
+		o(opts)
+	}
+	var out strings.Builder
+	out.WriteString("(command)")
+	all := descr.All("")
+
+	for _, a := range all {
+		if a.IsArg && a.Required {
+			out.WriteString(" <")
+			out.WriteString(a.Path)
+			out.WriteString(">")
+		}
+	}
+	for _, a := range all {
+		if a.IsArg && !a.Required {
+			out.WriteString(" [")
+			out.WriteString(a.Path)
+			out.WriteString("]")
+		}
+	}
+	if flagCount := descr.FlagGroup.flagCount("", opts); flagCount > 0 {
+		out.WriteString(fmt.Sprintf(" # %d flags (see below)", flagCount))
+	}
+
+	out.WriteString("\n\n")
+
+	if len(all) > 0 || len(descr.Entries) > 0 {
+		descr.FlagGroup.usage("", opts, &out)
+		out.WriteString("\n")
+	}
+
+	subCommands := false
+	if descr.CommandRoute != nil {
+		knownRoutes, ok := descr.CommandRoute.(CommandKnownRoutes)
+		if ok {
+			subCommands = true
+			out.WriteString("Sub commands:\n")
+			routes := knownRoutes.Routes()
+			maxRouteLen := 0
+			for _, r := range routes {
+				if len(r) > maxRouteLen {
+					maxRouteLen = len(r)
+				}
+			}
+			for _, k := range routes {
+				out.WriteString("  ")
+				out.WriteString(k)
+				if len(k) < maxRouteLen {
+					out.WriteString(strings.Repeat(" ", maxRouteLen-len(k)))
+				}
+				out.WriteString("  ")
+				subCmd, err := descr.CommandRoute.Cmd(k)
+				if err != nil {
+					out.WriteString(err.Error())
+				} else if subCmd == nil {
+					out.WriteString("Command route not available")
+				} else {
+					subDescr, err := LoadWithOptions(subCmd, descr.LoadOptions)
+					if err != nil {
+						out.WriteString("[error] command is invalid\n")
+						out.WriteString(err.Error())
+					} else {
+						if subDescr.Help != nil {
+							writeHelp(&out, subDescr.Help.Help(), maxRouteLen+4)
+						}
+						// no info in no help available but valid otherwise
+					}
+				}
+				out.WriteString("\n")
+			}
+			if note := descr.routePolicyNote(); note != "" {
+				out.WriteString(note)
+				out.WriteString("\n")
+			}
+		}
+	}
+
+	if descr.Epilogue != nil {
+		if e := RenderHelp(descr.Epilogue.Epilogue(), HelpText); e != "" {
+			out.WriteString("\n")
+			out.WriteString(e)
+			out.WriteString("\n")
+		}
+	}
+
+	if opts.Footer != "" {
+		route := strings.Join(descr.Route, " ")
+		data := FooterData{App: opts.App, Route: route, Command: strings.TrimSpace(opts.App + " " + route), SubCommands: subCommands}
+		out.WriteString("\n")
+		if err := renderFooter(&out, opts.Footer, &data); err != nil {
+			out.WriteString("[error] footer is invalid: ")
+			out.WriteString(err.Error())
+		}
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
+
+func renderFooter(out *strings.Builder, footer string, data *FooterData) error {
+	tmpl, err := template.New("footer").Parse(footer)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, data)
+}
@@ -0,0 +1,58 @@
+package ask
+
+import (
+	"flag"
+	"reflect"
+)
+
+// GenericValue is a flag value of type T, parsed and formatted with functions, see Value and RegisterValue.
+type GenericValue[T any] struct {
+	dest   *T
+	parse  func(string) (T, error)
+	format func(T) string
+}
+
+func (f *GenericValue[T]) Set(s string) error {
+	v, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	*f.dest = v
+	return nil
+}
+
+func (f *GenericValue[T]) String() string {
+	if f.dest == nil {
+		return ""
+	}
+	return f.format(*f.dest)
+}
+
+func (f *GenericValue[T]) Type() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// Get returns the current value.
+func (f *GenericValue[T]) Get() T {
+	return *f.dest
+}
+
+// Value creates a flag value of type T, parsed and formatted with the given functions,
+// e.g. to declare a flag in ExtraFlags without a TypedValue implementation. The value starts as the zero value of T,
+// and is read with Get.
+func Value[T any](parse func(string) (T, error), format func(T) string) *GenericValue[T] {
+	return &GenericValue[T]{dest: new(T), parse: parse, format: format}
+}
+
+// registeredValues are the registered flag values by type, see RegisterValue.
+var registeredValues = map[reflect.Type]func(val reflect.Value) flag.Value{}
+
+// RegisterValue registers how fields of type T are parsed and formatted, so fields of that type can be flags
+// without a TypedValue implementation. A registered type takes precedence over the built-in types,
+// but not over a type that implements flag.Value itself.
+// Values are not safe to register concurrently with Load, register them during initialization.
+func RegisterValue[T any](parse func(string) (T, error), format func(T) string) {
+	registeredValues[reflect.TypeOf((*T)(nil)).Elem()] = func(val reflect.Value) flag.Value {
+		return &GenericValue[T]{dest: val.Addr().Interface().(*T), parse: parse, format: format}
+	}
+}
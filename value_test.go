@@ -0,0 +1,54 @@
+package ask
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Celsius is registered with RegisterValue, to use as flag without a flag.Value implementation.
+type Celsius float64
+
+func init() {
+	RegisterValue(func(s string) (Celsius, error) {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "C"), 64)
+		return Celsius(v), err
+	}, func(c Celsius) string {
+		return strconv.FormatFloat(float64(c), 'g', -1, 64) + "C"
+	})
+}
+
+type ValueCmd struct {
+	Max   Celsius           `ask:"--max"`
+	Min   Optional[Celsius] `ask:"--min"`
+	scale *GenericValue[int]
+}
+
+func (c *ValueCmd) ExtraFlags() []*Flag {
+	return []*Flag{{Name: "scale", Value: c.scale}}
+}
+
+func (c *ValueCmd) Run(ctx context.Context, args ...string) error {
+	return nil
+}
+
+func TestValue(t *testing.T) {
+	c := ValueCmd{Max: 30, scale: Value(strconv.Atoi, strconv.Itoa)}
+	cmd, err := Load(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf, _ := cmd.Lookup("max"); pf.Default != "30C" || pf.Value.(TypedValue).Type() != "ask.Celsius" {
+		t.Fatalf("unexpected max flag: %q %q", pf.Default, pf.Value.(TypedValue).Type())
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--max=35.5C", "--min=-5", "--scale=3"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Max != 35.5 || c.Min.Value != -5 || !c.Min.IsSet() || c.scale.Get() != 3 {
+		t.Fatalf("unexpected values: %+v (scale %d)", c, c.scale.Get())
+	}
+	if _, err := cmd.Execute(context.Background(), nil, "--max=hot"); !IsUsageErr(err) {
+		t.Fatalf("expected parse error, got: %v", err)
+	}
+}